@@ -0,0 +1,338 @@
+package xcrypto
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/subtle"
+	"errors"
+	"github.com/Aoi-hosizora/ahlib/xstring"
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+// ======
+// cipher
+// ======
+
+const (
+	AES128KeySize              = 16                          // The key size of AES-128.
+	AES192KeySize              = 24                          // The key size of AES-192.
+	AES256KeySize              = 32                          // The key size of AES-256.
+	XChaCha20Poly1305KeySize   = chacha20poly1305.KeySize    // The key size of XChaCha20-Poly1305.
+	AESGCMNonceSize            = 12                          // The nonce size of AES-GCM, which is prepended to the ciphertext.
+	XChaCha20Poly1305NonceSize = chacha20poly1305.NonceSizeX // The nonce size of XChaCha20-Poly1305, which is prepended to the ciphertext.
+)
+
+var (
+	// ErrCiphertextTooShort represents the ciphertext is too short or is not aligned to the block size.
+	ErrCiphertextTooShort = errors.New("xcrypto: ciphertext too short")
+
+	// ErrAuthenticationFailed represents the ciphertext or additional data is tampered, or the key is wrong, when decrypting with AEAD.
+	ErrAuthenticationFailed = errors.New("xcrypto: message authentication failed")
+
+	// ErrDecryptionFailed represents the decryption of unauthenticated cipher is failed, which may be caused by wrong key or tampered data.
+	// Note that the detail (such as invalid padding) is not reported, to avoid being used as a padding oracle.
+	ErrDecryptionFailed = errors.New("xcrypto: decryption failed")
+)
+
+// GenerateKey generates a random key in given size, using crypto/rand.
+func GenerateKey(size int) ([]byte, error) {
+	key := make([]byte, size)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// newAESGCM creates a cipher.AEAD using AES-GCM, the key size must be 16, 24 or 32.
+func newAESGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// newXChaCha20Poly1305 creates a cipher.AEAD using XChaCha20-Poly1305, the key size must be 32.
+func newXChaCha20Poly1305(key []byte) (cipher.AEAD, error) {
+	return chacha20poly1305.NewX(key)
+}
+
+// aeadEncrypt seals given plaintext with a random nonce, and returns the nonce prepended to the ciphertext.
+func aeadEncrypt(aead cipher.AEAD, plaintext, additionalData []byte) ([]byte, error) {
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plaintext)+aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, plaintext, additionalData), nil
+}
+
+// aeadDecrypt opens given nonce prepended ciphertext, which is generated by aeadEncrypt.
+func aeadDecrypt(aead cipher.AEAD, ciphertext, additionalData []byte) ([]byte, error) {
+	if len(ciphertext) < aead.NonceSize()+aead.Overhead() {
+		return nil, ErrCiphertextTooShort
+	}
+	nonce, ciphertext := ciphertext[:aead.NonceSize()], ciphertext[aead.NonceSize():]
+	plaintext, err := aead.Open(nil, nonce, ciphertext, additionalData)
+	if err != nil {
+		return nil, ErrAuthenticationFailed
+	}
+	return plaintext, nil
+}
+
+// =======
+// aes-gcm
+// =======
+
+// AESGCMEncrypt uses AES-GCM to encrypt and authenticate plaintext and additional data (can be nil), the key size must be 16, 24 or 32.
+// The returned ciphertext is in the form of "nonce + encrypted + tag", where the 12 bytes nonce is randomly generated.
+func AESGCMEncrypt(plaintext, key, additionalData []byte) ([]byte, error) {
+	aead, err := newAESGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	return aeadEncrypt(aead, plaintext, additionalData)
+}
+
+// AESGCMDecrypt uses AES-GCM to decrypt and authenticate ciphertext generated by AESGCMEncrypt, ErrAuthenticationFailed will be returned
+// if the authentication fails.
+func AESGCMDecrypt(ciphertext, key, additionalData []byte) ([]byte, error) {
+	aead, err := newAESGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	return aeadDecrypt(aead, ciphertext, additionalData)
+}
+
+// AESGCMEncryptToBase64 uses AES-GCM to encrypt plaintext, and encodes the ciphertext to base64 string.
+func AESGCMEncryptToBase64(plaintext, key, additionalData []byte) (string, error) {
+	return encryptToBase64(AESGCMEncrypt(plaintext, key, additionalData))
+}
+
+// AESGCMDecryptFromBase64 decodes base64 string, and uses AES-GCM to decrypt the ciphertext.
+func AESGCMDecryptFromBase64(ciphertext string, key, additionalData []byte) ([]byte, error) {
+	bs, err := Base64DecodeFromString(ciphertext)
+	if err != nil {
+		return nil, err
+	}
+	return AESGCMDecrypt(bs, key, additionalData)
+}
+
+// AESGCMEncryptString uses AES-GCM to encrypt string, and encodes the ciphertext to base64 string.
+func AESGCMEncryptString(text string, key, additionalData []byte) (string, error) {
+	return AESGCMEncryptToBase64(xstring.FastStob(text), key, additionalData)
+}
+
+// AESGCMDecryptString decodes base64 string, and uses AES-GCM to decrypt the ciphertext to string.
+func AESGCMDecryptString(ciphertext string, key, additionalData []byte) (string, error) {
+	return decryptToString(AESGCMDecryptFromBase64(ciphertext, key, additionalData))
+}
+
+// ==================
+// xchacha20-poly1305
+// ==================
+
+// XChaCha20Poly1305Encrypt uses XChaCha20-Poly1305 to encrypt and authenticate plaintext and additional data (can be nil), the key size
+// must be 32. The returned ciphertext is in the form of "nonce + encrypted + tag", where the 24 bytes nonce is randomly generated, which
+// is large enough to be generated randomly without collision concern.
+func XChaCha20Poly1305Encrypt(plaintext, key, additionalData []byte) ([]byte, error) {
+	aead, err := newXChaCha20Poly1305(key)
+	if err != nil {
+		return nil, err
+	}
+	return aeadEncrypt(aead, plaintext, additionalData)
+}
+
+// XChaCha20Poly1305Decrypt uses XChaCha20-Poly1305 to decrypt and authenticate ciphertext generated by XChaCha20Poly1305Encrypt,
+// ErrAuthenticationFailed will be returned if the authentication fails.
+func XChaCha20Poly1305Decrypt(ciphertext, key, additionalData []byte) ([]byte, error) {
+	aead, err := newXChaCha20Poly1305(key)
+	if err != nil {
+		return nil, err
+	}
+	return aeadDecrypt(aead, ciphertext, additionalData)
+}
+
+// XChaCha20Poly1305EncryptToBase64 uses XChaCha20-Poly1305 to encrypt plaintext, and encodes the ciphertext to base64 string.
+func XChaCha20Poly1305EncryptToBase64(plaintext, key, additionalData []byte) (string, error) {
+	return encryptToBase64(XChaCha20Poly1305Encrypt(plaintext, key, additionalData))
+}
+
+// XChaCha20Poly1305DecryptFromBase64 decodes base64 string, and uses XChaCha20-Poly1305 to decrypt the ciphertext.
+func XChaCha20Poly1305DecryptFromBase64(ciphertext string, key, additionalData []byte) ([]byte, error) {
+	bs, err := Base64DecodeFromString(ciphertext)
+	if err != nil {
+		return nil, err
+	}
+	return XChaCha20Poly1305Decrypt(bs, key, additionalData)
+}
+
+// XChaCha20Poly1305EncryptString uses XChaCha20-Poly1305 to encrypt string, and encodes the ciphertext to base64 string.
+func XChaCha20Poly1305EncryptString(text string, key, additionalData []byte) (string, error) {
+	return XChaCha20Poly1305EncryptToBase64(xstring.FastStob(text), key, additionalData)
+}
+
+// XChaCha20Poly1305DecryptString decodes base64 string, and uses XChaCha20-Poly1305 to decrypt the ciphertext to string.
+func XChaCha20Poly1305DecryptString(ciphertext string, key, additionalData []byte) (string, error) {
+	return decryptToString(XChaCha20Poly1305DecryptFromBase64(ciphertext, key, additionalData))
+}
+
+// =======
+// aes-cbc
+// =======
+
+// AESCBCEncrypt uses AES-CBC to encrypt plaintext padded by PKCS#7, the key size must be 16, 24 or 32. The returned ciphertext is in the
+// form of "iv + encrypted", where the 16 bytes iv is randomly generated. Note that CBC mode is not authenticated, and should only be used
+// for interoperability with legacy systems, please use AESGCMEncrypt or XChaCha20Poly1305Encrypt instead.
+func AESCBCEncrypt(plaintext, key []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	padded := PKCS5Padding(append([]byte(nil), plaintext...), aes.BlockSize)
+	ciphertext := make([]byte, aes.BlockSize+len(padded))
+	iv := ciphertext[:aes.BlockSize]
+	if _, err := rand.Read(iv); err != nil {
+		return nil, err
+	}
+	cipher.NewCBCEncrypter(block, iv).CryptBlocks(ciphertext[aes.BlockSize:], padded)
+	return ciphertext, nil
+}
+
+// AESCBCDecrypt uses AES-CBC to decrypt ciphertext generated by AESCBCEncrypt, and trims the PKCS#7 padding, ErrDecryptionFailed will be
+// returned if the padding is invalid. Note that CBC mode is not authenticated, so please never expose decryption failures of untrusted
+// ciphertext to the remote, or use AESGCMDecrypt or XChaCha20Poly1305Decrypt instead.
+func AESCBCDecrypt(ciphertext, key []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(ciphertext) < 2*aes.BlockSize || len(ciphertext)%aes.BlockSize != 0 {
+		return nil, ErrCiphertextTooShort
+	}
+	iv, ciphertext := ciphertext[:aes.BlockSize], ciphertext[aes.BlockSize:]
+	plaintext := make([]byte, len(ciphertext))
+	cipher.NewCBCDecrypter(block, iv).CryptBlocks(plaintext, ciphertext)
+	if !validPKCS7Padding(plaintext, aes.BlockSize) {
+		return nil, ErrDecryptionFailed
+	}
+	return PKCS5Trimming(plaintext), nil
+}
+
+// AESCBCEncryptToBase64 uses AES-CBC to encrypt plaintext, and encodes the ciphertext to base64 string.
+func AESCBCEncryptToBase64(plaintext, key []byte) (string, error) {
+	return encryptToBase64(AESCBCEncrypt(plaintext, key))
+}
+
+// AESCBCDecryptFromBase64 decodes base64 string, and uses AES-CBC to decrypt the ciphertext.
+func AESCBCDecryptFromBase64(ciphertext string, key []byte) ([]byte, error) {
+	bs, err := Base64DecodeFromString(ciphertext)
+	if err != nil {
+		return nil, err
+	}
+	return AESCBCDecrypt(bs, key)
+}
+
+// AESCBCEncryptString uses AES-CBC to encrypt string, and encodes the ciphertext to base64 string.
+func AESCBCEncryptString(text string, key []byte) (string, error) {
+	return AESCBCEncryptToBase64(xstring.FastStob(text), key)
+}
+
+// AESCBCDecryptString decodes base64 string, and uses AES-CBC to decrypt the ciphertext to string.
+func AESCBCDecryptString(ciphertext string, key []byte) (string, error) {
+	return decryptToString(AESCBCDecryptFromBase64(ciphertext, key))
+}
+
+// validPKCS7Padding checks whether given block aligned data has valid PKCS#7 padding, the check is done in constant time, that is, it
+// only branches on the data length, never on the data content.
+func validPKCS7Padding(data []byte, blockSize int) bool {
+	length := len(data)
+	if length == 0 || length%blockSize != 0 {
+		return false
+	}
+	padByte := data[length-1]
+	padLen := int(padByte)
+	good := subtle.ConstantTimeLessOrEq(1, padLen) & subtle.ConstantTimeLessOrEq(padLen, blockSize)
+	for i := 0; i < blockSize; i++ {
+		inPadding := subtle.ConstantTimeLessOrEq(i+1, padLen)
+		matched := subtle.ConstantTimeByteEq(data[length-1-i], padByte)
+		good &= subtle.ConstantTimeSelect(inPadding, matched, 1)
+	}
+	return good == 1
+}
+
+// =======
+// aes-ctr
+// =======
+
+// AESCTREncrypt uses AES-CTR to encrypt plaintext without padding, the key size must be 16, 24 or 32. The returned ciphertext is in the
+// form of "iv + encrypted", where the 16 bytes iv is randomly generated. Note that CTR mode is not authenticated, and should only be used
+// for interoperability with legacy systems, please use AESGCMEncrypt or XChaCha20Poly1305Encrypt instead.
+func AESCTREncrypt(plaintext, key []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	ciphertext := make([]byte, aes.BlockSize+len(plaintext))
+	iv := ciphertext[:aes.BlockSize]
+	if _, err := rand.Read(iv); err != nil {
+		return nil, err
+	}
+	cipher.NewCTR(block, iv).XORKeyStream(ciphertext[aes.BlockSize:], plaintext)
+	return ciphertext, nil
+}
+
+// AESCTRDecrypt uses AES-CTR to decrypt ciphertext generated by AESCTREncrypt.
+func AESCTRDecrypt(ciphertext, key []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(ciphertext) < aes.BlockSize {
+		return nil, ErrCiphertextTooShort
+	}
+	iv, ciphertext := ciphertext[:aes.BlockSize], ciphertext[aes.BlockSize:]
+	plaintext := make([]byte, len(ciphertext))
+	cipher.NewCTR(block, iv).XORKeyStream(plaintext, ciphertext)
+	return plaintext, nil
+}
+
+// AESCTREncryptToBase64 uses AES-CTR to encrypt plaintext, and encodes the ciphertext to base64 string.
+func AESCTREncryptToBase64(plaintext, key []byte) (string, error) {
+	return encryptToBase64(AESCTREncrypt(plaintext, key))
+}
+
+// AESCTRDecryptFromBase64 decodes base64 string, and uses AES-CTR to decrypt the ciphertext.
+func AESCTRDecryptFromBase64(ciphertext string, key []byte) ([]byte, error) {
+	bs, err := Base64DecodeFromString(ciphertext)
+	if err != nil {
+		return nil, err
+	}
+	return AESCTRDecrypt(bs, key)
+}
+
+// AESCTREncryptString uses AES-CTR to encrypt string, and encodes the ciphertext to base64 string.
+func AESCTREncryptString(text string, key []byte) (string, error) {
+	return AESCTREncryptToBase64(xstring.FastStob(text), key)
+}
+
+// AESCTRDecryptString decodes base64 string, and uses AES-CTR to decrypt the ciphertext to string.
+func AESCTRDecryptString(ciphertext string, key []byte) (string, error) {
+	return decryptToString(AESCTRDecryptFromBase64(ciphertext, key))
+}
+
+// encryptToBase64 encodes the result of encryption to base64 string.
+func encryptToBase64(ciphertext []byte, err error) (string, error) {
+	if err != nil {
+		return "", err
+	}
+	return Base64EncodeToString(ciphertext), nil
+}
+
+// decryptToString converts the result of decryption to string.
+func decryptToString(plaintext []byte, err error) (string, error) {
+	if err != nil {
+		return "", err
+	}
+	return xstring.FastBtos(plaintext), nil
+}
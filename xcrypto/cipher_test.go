@@ -0,0 +1,186 @@
+package xcrypto
+
+import (
+	"bytes"
+	"crypto/aes"
+	"github.com/Aoi-hosizora/ahlib/xtesting"
+	"strings"
+	"testing"
+)
+
+func TestAEAD(t *testing.T) {
+	aesKey, err := GenerateKey(AES256KeySize)
+	xtesting.Nil(t, err)
+	chachaKey, _ := GenerateKey(XChaCha20Poly1305KeySize)
+	otherKey, _ := GenerateKey(32)
+	ad := []byte("additional data")
+
+	for _, tc := range []struct {
+		giveKey       []byte
+		giveNonceSize int
+		encryptFn     func(plaintext, key, additionalData []byte) ([]byte, error)
+		decryptFn     func(ciphertext, key, additionalData []byte) ([]byte, error)
+		encryptB64Fn  func(plaintext, key, additionalData []byte) (string, error)
+		decryptB64Fn  func(ciphertext string, key, additionalData []byte) ([]byte, error)
+		encryptStrFn  func(text string, key, additionalData []byte) (string, error)
+		decryptStrFn  func(ciphertext string, key, additionalData []byte) (string, error)
+	}{
+		{aesKey, AESGCMNonceSize, AESGCMEncrypt, AESGCMDecrypt, AESGCMEncryptToBase64, AESGCMDecryptFromBase64, AESGCMEncryptString, AESGCMDecryptString},
+		{aesKey[:AES128KeySize], AESGCMNonceSize, AESGCMEncrypt, AESGCMDecrypt, AESGCMEncryptToBase64, AESGCMDecryptFromBase64, AESGCMEncryptString, AESGCMDecryptString},
+		{chachaKey, XChaCha20Poly1305NonceSize, XChaCha20Poly1305Encrypt, XChaCha20Poly1305Decrypt, XChaCha20Poly1305EncryptToBase64, XChaCha20Poly1305DecryptFromBase64, XChaCha20Poly1305EncryptString, XChaCha20Poly1305DecryptString},
+	} {
+		for _, text := range []string{"", "test", "hello world", "测试 テス тест", strings.Repeat("0123456789", 100)} {
+			ciphertext, err := tc.encryptFn([]byte(text), tc.giveKey, ad)
+			xtesting.Nil(t, err)
+			xtesting.Equal(t, len(ciphertext), tc.giveNonceSize+len(text)+16)
+			ciphertext2, _ := tc.encryptFn([]byte(text), tc.giveKey, ad)
+			xtesting.NotEqual(t, ciphertext, ciphertext2) // random nonce
+			plaintext, err := tc.decryptFn(ciphertext, tc.giveKey, ad)
+			xtesting.Nil(t, err)
+			xtesting.Equal(t, string(plaintext), text)
+
+			b64, err := tc.encryptB64Fn([]byte(text), tc.giveKey, nil)
+			xtesting.Nil(t, err)
+			plaintext, err = tc.decryptB64Fn(b64, tc.giveKey, nil)
+			xtesting.Nil(t, err)
+			xtesting.Equal(t, string(plaintext), text)
+			s, err := tc.encryptStrFn(text, tc.giveKey, ad)
+			xtesting.Nil(t, err)
+			s, err = tc.decryptStrFn(s, tc.giveKey, ad)
+			xtesting.Nil(t, err)
+			xtesting.Equal(t, s, text)
+
+			// authentication failures
+			tampered := append([]byte(nil), ciphertext...)
+			tampered[len(tampered)-1] ^= 1
+			for _, fn := range []func() ([]byte, error){
+				func() ([]byte, error) { return tc.decryptFn(tampered, tc.giveKey, ad) },
+				func() ([]byte, error) { return tc.decryptFn(ciphertext, otherKey[:len(tc.giveKey)], ad) },
+				func() ([]byte, error) { return tc.decryptFn(ciphertext, tc.giveKey, nil) },
+			} {
+				plaintext, err := fn()
+				xtesting.Nil(t, plaintext)
+				xtesting.Equal(t, err, ErrAuthenticationFailed)
+			}
+		}
+
+		// errors
+		_, err := tc.decryptFn(make([]byte, tc.giveNonceSize+15), tc.giveKey, nil)
+		xtesting.Equal(t, err, ErrCiphertextTooShort)
+		_, err = tc.encryptFn(nil, tc.giveKey[:15], nil)
+		xtesting.NotNil(t, err)
+		_, err = tc.decryptFn(nil, tc.giveKey[:15], nil)
+		xtesting.NotNil(t, err)
+		_, err = tc.encryptB64Fn(nil, tc.giveKey[:15], nil)
+		xtesting.NotNil(t, err)
+		_, err = tc.decryptB64Fn("!", tc.giveKey, nil)
+		xtesting.NotNil(t, err)
+		_, err = tc.decryptStrFn("!", tc.giveKey, nil)
+		xtesting.NotNil(t, err)
+	}
+}
+
+func TestAESCBCCTR(t *testing.T) {
+	key, _ := HexDecodeFromString("000102030405060708090a0b0c0d0e0f")
+	iv, _ := HexDecodeFromString("0f0e0d0c0b0a09080706050403020100")
+	otherKey, _ := GenerateKey(AES128KeySize)
+
+	// interoperate with openssl
+	// $ printf 'hello world' | openssl enc -aes-128-cbc -K 000102030405060708090a0b0c0d0e0f -iv 0f0e0d0c0b0a09080706050403020100 | xxd -p
+	cbc, _ := HexDecodeFromString("3fb51c0ccbcb533bb82a08e6817013ea")
+	plaintext, err := AESCBCDecrypt(append(append([]byte(nil), iv...), cbc...), key)
+	xtesting.Nil(t, err)
+	xtesting.Equal(t, string(plaintext), "hello world")
+	// $ printf 'hello world' | openssl enc -aes-128-ctr -K 000102030405060708090a0b0c0d0e0f -iv 0f0e0d0c0b0a09080706050403020100 | xxd -p
+	ctr, _ := HexDecodeFromString("48cc95fedb6c2c87767398")
+	plaintext, err = AESCTRDecrypt(append(append([]byte(nil), iv...), ctr...), key)
+	xtesting.Nil(t, err)
+	xtesting.Equal(t, string(plaintext), "hello world")
+
+	for _, tc := range []struct {
+		encryptFn    func(plaintext, key []byte) ([]byte, error)
+		decryptFn    func(ciphertext, key []byte) ([]byte, error)
+		encryptB64Fn func(plaintext, key []byte) (string, error)
+		decryptB64Fn func(ciphertext string, key []byte) ([]byte, error)
+		encryptStrFn func(text string, key []byte) (string, error)
+		decryptStrFn func(ciphertext string, key []byte) (string, error)
+		wantLenFn    func(int) int
+	}{
+		{AESCBCEncrypt, AESCBCDecrypt, AESCBCEncryptToBase64, AESCBCDecryptFromBase64, AESCBCEncryptString, AESCBCDecryptString,
+			func(l int) int { return aes.BlockSize + (l/aes.BlockSize+1)*aes.BlockSize }},
+		{AESCTREncrypt, AESCTRDecrypt, AESCTREncryptToBase64, AESCTRDecryptFromBase64, AESCTREncryptString, AESCTRDecryptString,
+			func(l int) int { return aes.BlockSize + l }},
+	} {
+		for _, text := range []string{"", "test", "0123456789abcdef", "测试 テス тест", strings.Repeat("0123456789", 100)} {
+			given := []byte(text)
+			ciphertext, err := tc.encryptFn(given, key)
+			xtesting.Nil(t, err)
+			xtesting.Equal(t, string(given), text) // not modified
+			xtesting.Equal(t, len(ciphertext), tc.wantLenFn(len(text)))
+			plaintext, err := tc.decryptFn(ciphertext, key)
+			xtesting.Nil(t, err)
+			xtesting.Equal(t, string(plaintext), text)
+
+			b64, err := tc.encryptB64Fn([]byte(text), key)
+			xtesting.Nil(t, err)
+			plaintext, err = tc.decryptB64Fn(b64, key)
+			xtesting.Nil(t, err)
+			xtesting.Equal(t, string(plaintext), text)
+			s, err := tc.encryptStrFn(text, key)
+			xtesting.Nil(t, err)
+			s, err = tc.decryptStrFn(s, key)
+			xtesting.Nil(t, err)
+			xtesting.Equal(t, s, text)
+		}
+
+		_, err := tc.encryptFn(nil, key[:15])
+		xtesting.NotNil(t, err)
+		_, err = tc.decryptFn(nil, key[:15])
+		xtesting.NotNil(t, err)
+		_, err = tc.decryptFn(make([]byte, aes.BlockSize-1), key)
+		xtesting.Equal(t, err, ErrCiphertextTooShort)
+		_, err = tc.encryptB64Fn(nil, key[:15])
+		xtesting.NotNil(t, err)
+		_, err = tc.decryptB64Fn("!", key)
+		xtesting.NotNil(t, err)
+		_, err = tc.decryptStrFn("!", key)
+		xtesting.NotNil(t, err)
+	}
+
+	// cbc errors
+	ciphertext, _ := AESCBCEncrypt([]byte("hello world"), key)
+	_, err = AESCBCDecrypt(ciphertext[:aes.BlockSize], key)
+	xtesting.Equal(t, err, ErrCiphertextTooShort)
+	_, err = AESCBCDecrypt(ciphertext[:len(ciphertext)-1], key)
+	xtesting.Equal(t, err, ErrCiphertextTooShort)
+	tampered := append([]byte(nil), ciphertext...)
+	tampered[aes.BlockSize-1] ^= 0x07 // last padding byte 0x05 -> 0x02
+	_, err = AESCBCDecrypt(tampered, key)
+	xtesting.Equal(t, err, ErrDecryptionFailed)
+	plaintext, err = AESCBCDecrypt(ciphertext, otherKey)
+	xtesting.False(t, err == nil && string(plaintext) == "hello world")
+}
+
+func TestValidPKCS7Padding(t *testing.T) {
+	for _, tc := range []struct {
+		give []byte
+		want bool
+	}{
+		{[]byte{}, false},
+		{[]byte{1, 2, 3}, false},
+		{[]byte{1, 2, 3, 1}, true},
+		{[]byte{1, 2, 2, 2}, true},
+		{[]byte{4, 4, 4, 4}, true},
+		{[]byte{1, 2, 3, 0}, false},
+		{[]byte{1, 2, 3, 2}, false},
+		{[]byte{1, 2, 3, 5}, false},
+		{[]byte{5, 5, 5, 5}, false},
+		{append(bytes.Repeat([]byte{0}, 4), 4, 4, 4, 4), true},
+		{[]byte{2, 3, 3, 3}, true},
+		{[]byte{3, 2, 3, 3}, false},
+		{[]byte{0, 0, 0, 255}, false},
+		{[]byte{1, 2, 3, 4, 5, 6, 7, 1}, true},
+	} {
+		xtesting.Equal(t, validPKCS7Padding(tc.give, 4), tc.want)
+	}
+}
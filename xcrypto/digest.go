@@ -0,0 +1,229 @@
+package xcrypto
+
+import (
+	"bytes"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"github.com/Aoi-hosizora/ahlib/xstring"
+	"golang.org/x/crypto/md4"
+	"golang.org/x/crypto/sha3"
+	"hash"
+	"hash/adler32"
+	"hash/crc32"
+	"hash/fnv"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// ==============
+// hash algorithm
+// ==============
+
+// HashAlgorithm represents a hash algorithm supported by xcrypto, can be used to create hash.Hash and calculate digest.
+type HashAlgorithm uint8
+
+const (
+	HashFNV32      HashAlgorithm = iota + 1 // fnv32, hash/fnv
+	HashFNV32a                              // fnv32a, hash/fnv
+	HashFNV64                               // fnv64, hash/fnv
+	HashFNV64a                              // fnv64a, hash/fnv
+	HashCRC32                               // crc32, hash/crc32
+	HashADLER32                             // adler32, hash/adler32
+	HashMD4                                 // md4, x/crypto/md4
+	HashMD5                                 // md5, crypto/md5
+	HashSHA1                                // sha-1, crypto/sha1
+	HashSHA224                              // sha2-224, crypto/sha256
+	HashSHA256                              // sha2-256, crypto/sha256
+	HashSHA384                              // sha2-384, crypto/sha512
+	HashSHA512                              // sha2-512, crypto/sha512
+	HashSHA512_224                          // sha2-512/224, crypto/sha512
+	HashSHA512_256                          // sha2-512/256, crypto/sha512
+	HashSHA3_224                            // sha3-224, x/crypto/sha3
+	HashSHA3_256                            // sha3-256, x/crypto/sha3
+	HashSHA3_384                            // sha3-384, x/crypto/sha3
+	HashSHA3_512                            // sha3-512, x/crypto/sha3
+
+	maxHashAlgorithm
+)
+
+// hashAlgorithmInfo represents the information of a HashAlgorithm.
+type hashAlgorithmInfo struct {
+	name          string
+	size          int
+	new           func() hash.Hash
+	cryptographic bool // false for checksums, which cannot be used for hmac and key derivation
+}
+
+// _hashAlgorithms is the registry of all HashAlgorithm-s.
+var _hashAlgorithms = [maxHashAlgorithm]*hashAlgorithmInfo{
+	HashFNV32:      {"fnv32", 4, func() hash.Hash { return fnv.New32() }, false},
+	HashFNV32a:     {"fnv32a", 4, func() hash.Hash { return fnv.New32a() }, false},
+	HashFNV64:      {"fnv64", 8, func() hash.Hash { return fnv.New64() }, false},
+	HashFNV64a:     {"fnv64a", 8, func() hash.Hash { return fnv.New64a() }, false},
+	HashCRC32:      {"crc32", crc32.Size, func() hash.Hash { return crc32.NewIEEE() }, false},
+	HashADLER32:    {"adler32", adler32.Size, func() hash.Hash { return adler32.New() }, false},
+	HashMD4:        {"md4", md4.Size, md4.New, true},
+	HashMD5:        {"md5", md5.Size, md5.New, true},
+	HashSHA1:       {"sha1", sha1.Size, sha1.New, true},
+	HashSHA224:     {"sha224", sha256.Size224, sha256.New224, true},
+	HashSHA256:     {"sha256", sha256.Size, sha256.New, true},
+	HashSHA384:     {"sha384", sha512.Size384, sha512.New384, true},
+	HashSHA512:     {"sha512", sha512.Size, sha512.New, true},
+	HashSHA512_224: {"sha512/224", sha512.Size224, sha512.New512_224, true},
+	HashSHA512_256: {"sha512/256", sha512.Size256, sha512.New512_256, true},
+	HashSHA3_224:   {"sha3-224", 28, sha3.New224, true},
+	HashSHA3_256:   {"sha3-256", 32, sha3.New256, true},
+	HashSHA3_384:   {"sha3-384", 48, sha3.New384, true},
+	HashSHA3_512:   {"sha3-512", 64, sha3.New512, true},
+}
+
+const (
+	panicInvalidHashAlgorithm  = "xcrypto: invalid hash algorithm"
+	panicInvalidDigestEncoding = "xcrypto: invalid digest encoding"
+	panicInvalidHMACAlgorithm  = "xcrypto: non-cryptographic hash algorithm cannot be used for hmac"
+)
+
+// HashAlgorithms returns all the HashAlgorithm-s supported by xcrypto, in the order of their values.
+func HashAlgorithms() []HashAlgorithm {
+	algorithms := make([]HashAlgorithm, 0, maxHashAlgorithm-1)
+	for a := HashAlgorithm(1); a < maxHashAlgorithm; a++ {
+		algorithms = append(algorithms, a)
+	}
+	return algorithms
+}
+
+// ParseHashAlgorithm parses given name (case-insensitive) to HashAlgorithm, such as "md5", "sha256", "sha512/256" and "sha3-256". Note
+// that the name is the same as HashAlgorithm.String() returns.
+func ParseHashAlgorithm(name string) (HashAlgorithm, bool) {
+	name = strings.ToLower(name)
+	for a := HashAlgorithm(1); a < maxHashAlgorithm; a++ {
+		if _hashAlgorithms[a].name == name {
+			return a, true
+		}
+	}
+	return 0, false
+}
+
+// Available checks whether the HashAlgorithm is a valid algorithm supported by xcrypto.
+func (h HashAlgorithm) Available() bool {
+	return h > 0 && h < maxHashAlgorithm
+}
+
+// isCryptographic checks whether the HashAlgorithm is a valid cryptographic hash algorithm rather than a checksum.
+func (h HashAlgorithm) isCryptographic() bool {
+	return h.Available() && _hashAlgorithms[h].cryptographic
+}
+
+// String returns the name of the HashAlgorithm, such as "sha256", returns "HashAlgorithm(x)" for invalid algorithm.
+func (h HashAlgorithm) String() string {
+	if !h.Available() {
+		return "HashAlgorithm(" + strconv.Itoa(int(h)) + ")"
+	}
+	return _hashAlgorithms[h].name
+}
+
+// Size returns the digest size in bytes of the HashAlgorithm, panics if the algorithm is invalid.
+func (h HashAlgorithm) Size() int {
+	if !h.Available() {
+		panic(panicInvalidHashAlgorithm)
+	}
+	return _hashAlgorithms[h].size
+}
+
+// New creates a new hash.Hash of the HashAlgorithm, panics if the algorithm is invalid.
+func (h HashAlgorithm) New() hash.Hash {
+	if !h.Available() {
+		panic(panicInvalidHashAlgorithm)
+	}
+	return _hashAlgorithms[h].new()
+}
+
+// ===============
+// digest encoding
+// ===============
+
+// DigestEncoding represents the output encoding of digest, used by Digest series functions.
+type DigestEncoding uint8
+
+const (
+	EncodingRaw       DigestEncoding = iota // raw bytes
+	EncodingHex                             // lowercase hex, the same as HexEncodeToBytes
+	EncodingUpperHex                        // uppercase hex
+	EncodingBase32                          // standard base32, the same as Base32EncodeToBytes
+	EncodingBase64                          // standard base64, the same as Base64EncodeToBytes, such as S3 Content-MD5
+	EncodingBase64URL                       // url-safe base64 with padding, the same as Base64URLEncodeToBytes
+)
+
+// Encode encodes given data using the DigestEncoding, panics if the encoding is invalid.
+func (e DigestEncoding) Encode(data []byte) []byte {
+	switch e {
+	case EncodingRaw:
+		return data
+	case EncodingHex:
+		return HexEncodeToBytes(data)
+	case EncodingUpperHex:
+		return bytes.ToUpper(HexEncodeToBytes(data))
+	case EncodingBase32:
+		return Base32EncodeToBytes(data)
+	case EncodingBase64:
+		return Base64EncodeToBytes(data)
+	case EncodingBase64URL:
+		return Base64URLEncodeToBytes(data)
+	default:
+		panic(panicInvalidDigestEncoding)
+	}
+}
+
+// Decode decodes given encoded data using the DigestEncoding, panics if the encoding is invalid. Note that both EncodingHex and
+// EncodingUpperHex accept lowercase and uppercase hex.
+func (e DigestEncoding) Decode(data []byte) ([]byte, error) {
+	switch e {
+	case EncodingRaw:
+		return data, nil
+	case EncodingHex, EncodingUpperHex:
+		return HexDecodeFromBytes(data)
+	case EncodingBase32:
+		return Base32DecodeFromBytes(data)
+	case EncodingBase64:
+		return Base64DecodeFromBytes(data)
+	case EncodingBase64URL:
+		return Base64URLDecodeFromBytes(data)
+	default:
+		panic(panicInvalidDigestEncoding)
+	}
+}
+
+// ======
+// digest
+// ======
+
+// Digest uses given HashAlgorithm to hash data, and encodes the digest using given DigestEncoding, panics if the algorithm or encoding
+// is invalid.
+// Example:
+//
+//	contentMD5 := xcrypto.Digest(xcrypto.HashMD5, data, xcrypto.EncodingBase64) // S3 Content-MD5
+//	integrity := "sha384-" + xcrypto.DigestString(xcrypto.HashSHA384, text, xcrypto.EncodingBase64) // subresource integrity
+func Digest(algorithm HashAlgorithm, data []byte, encoding DigestEncoding) []byte {
+	h := algorithm.New()
+	_, _ = h.Write(data)
+	return encoding.Encode(h.Sum(nil))
+}
+
+// DigestString uses given HashAlgorithm to hash string, and encodes the digest to string using given DigestEncoding. Note that if
+// EncodingRaw is used, the returned string contains the raw digest bytes.
+func DigestString(algorithm HashAlgorithm, text string, encoding DigestEncoding) string {
+	return xstring.FastBtos(Digest(algorithm, xstring.FastStob(text), encoding))
+}
+
+// DigestReader uses given HashAlgorithm to hash data read from io.Reader using a bounded buffer, and encodes the digest using given
+// DigestEncoding.
+func DigestReader(algorithm HashAlgorithm, r io.Reader, encoding DigestEncoding) ([]byte, error) {
+	h := algorithm.New()
+	if err := copyToHash(h, r); err != nil {
+		return nil, err
+	}
+	return encoding.Encode(h.Sum(nil)), nil
+}
@@ -0,0 +1,131 @@
+package xcrypto
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"github.com/Aoi-hosizora/ahlib/xtesting"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestHashAlgorithm(t *testing.T) {
+	algorithms := HashAlgorithms()
+	xtesting.Equal(t, len(algorithms), 19)
+	xtesting.Equal(t, algorithms[0], HashFNV32)
+	xtesting.Equal(t, algorithms[len(algorithms)-1], HashSHA3_512)
+
+	for _, tc := range []struct {
+		give              HashAlgorithm
+		wantName          string
+		wantSize          int
+		wantCryptographic bool
+	}{
+		{HashFNV32, "fnv32", 4, false},
+		{HashFNV64a, "fnv64a", 8, false},
+		{HashCRC32, "crc32", 4, false},
+		{HashADLER32, "adler32", 4, false},
+		{HashMD4, "md4", 16, true},
+		{HashMD5, "md5", 16, true},
+		{HashSHA1, "sha1", 20, true},
+		{HashSHA256, "sha256", 32, true},
+		{HashSHA512_224, "sha512/224", 28, true},
+		{HashSHA3_384, "sha3-384", 48, true},
+	} {
+		xtesting.True(t, tc.give.Available())
+		xtesting.Equal(t, tc.give.isCryptographic(), tc.wantCryptographic)
+		xtesting.Equal(t, tc.give.String(), tc.wantName)
+		xtesting.Equal(t, tc.give.Size(), tc.wantSize)
+		a, ok := ParseHashAlgorithm(strings.ToUpper(tc.wantName))
+		xtesting.True(t, ok)
+		xtesting.Equal(t, a, tc.give)
+	}
+	for _, a := range algorithms {
+		xtesting.Equal(t, a.New().Size(), a.Size())
+	}
+
+	for _, invalid := range []HashAlgorithm{0, maxHashAlgorithm, 255} {
+		xtesting.False(t, invalid.Available())
+		xtesting.False(t, invalid.isCryptographic())
+		xtesting.True(t, strings.HasPrefix(invalid.String(), "HashAlgorithm("))
+		xtesting.Panic(t, func() { invalid.New() })
+		xtesting.Panic(t, func() { _ = invalid.Size() })
+	}
+	xtesting.Equal(t, HashAlgorithm(0).String(), "HashAlgorithm(0)")
+	_, ok := ParseHashAlgorithm("sha2")
+	xtesting.False(t, ok)
+}
+
+func TestDigest(t *testing.T) {
+	// compare with existing hash functions
+	for _, text := range []string{"", "test", "hello world", "测试 テス тест"} {
+		for _, tc := range []struct {
+			giveAlgorithm HashAlgorithm
+			giveFn        func(string) string
+		}{
+			{HashMD4, MD4},
+			{HashMD5, MD5},
+			{HashSHA1, SHA1},
+			{HashSHA224, SHA224},
+			{HashSHA256, SHA256},
+			{HashSHA384, SHA384},
+			{HashSHA512, SHA512},
+			{HashSHA512_224, SHA512_224},
+			{HashSHA512_256, SHA512_256},
+			{HashSHA3_224, SHA3_224},
+			{HashSHA3_256, SHA3_256},
+			{HashSHA3_384, SHA3_384},
+			{HashSHA3_512, SHA3_512},
+		} {
+			xtesting.Equal(t, DigestString(tc.giveAlgorithm, text, EncodingHex), tc.giveFn(text))
+		}
+
+		for _, tc := range []struct {
+			giveAlgorithm HashAlgorithm
+			giveFn        func(string) uint32
+		}{
+			{HashFNV32, FNV32},
+			{HashFNV32a, FNV32a},
+			{HashCRC32, CRC32},
+			{HashADLER32, ADLER32},
+		} {
+			xtesting.Equal(t, binary.BigEndian.Uint32(Digest(tc.giveAlgorithm, []byte(text), EncodingRaw)), tc.giveFn(text))
+		}
+		xtesting.Equal(t, binary.BigEndian.Uint64(Digest(HashFNV64, []byte(text), EncodingRaw)), FNV64(text))
+		xtesting.Equal(t, binary.BigEndian.Uint64(Digest(HashFNV64a, []byte(text), EncodingRaw)), FNV64a(text))
+	}
+
+	// encodings
+	raw := sha256.Sum256([]byte("hello world"))
+	for _, tc := range []struct {
+		giveEncoding DigestEncoding
+		want         string
+	}{
+		{EncodingRaw, string(raw[:])},
+		{EncodingHex, "b94d27b9934d3e08a52e52d7da7dabfac484efe37a5380ee9088f7ace2efcde9"},
+		{EncodingUpperHex, "B94D27B9934D3E08A52E52D7DA7DABFAC484EFE37A5380EE9088F7ACE2EFCDE9"},
+		{EncodingBase32, "XFGSPOMTJU7ARJJOKLL5U7NL7LCIJ37DPJJYB3UQRD32ZYXPZXUQ===="},
+		{EncodingBase64, "uU0nuZNNPgilLlLX2n2r+sSE7+N6U4DukIj3rOLvzek="},
+		{EncodingBase64URL, "uU0nuZNNPgilLlLX2n2r-sSE7-N6U4DukIj3rOLvzek="},
+	} {
+		xtesting.Equal(t, string(Digest(HashSHA256, []byte("hello world"), tc.giveEncoding)), tc.want)
+		xtesting.Equal(t, DigestString(HashSHA256, "hello world", tc.giveEncoding), tc.want)
+		bs, err := DigestReader(HashSHA256, strings.NewReader("hello world"), tc.giveEncoding)
+		xtesting.Nil(t, err)
+		xtesting.Equal(t, string(bs), tc.want)
+		decoded, err := tc.giveEncoding.Decode([]byte(tc.want))
+		xtesting.Nil(t, err)
+		xtesting.Equal(t, decoded, raw[:])
+	}
+	xtesting.Equal(t, DigestString(HashMD5, "hello world", EncodingBase64), "XrY7u+Ae7tCTyyK7j1rNww==") // Content-MD5
+
+	// errors and panics
+	testErr := errors.New("test")
+	_, err := DigestReader(HashMD5, io.MultiReader(strings.NewReader("test"), &errorReader{testErr}), EncodingHex)
+	xtesting.Equal(t, err, testErr)
+	xtesting.Panic(t, func() { Digest(0, nil, EncodingHex) })
+	xtesting.Panic(t, func() { Digest(HashMD5, nil, 99) })
+	xtesting.Panic(t, func() { DigestEncoding(99).Encode(nil) })
+	xtesting.Panic(t, func() { _, _ = DigestEncoding(99).Decode(nil) })
+}
@@ -0,0 +1,314 @@
+package xcrypto
+
+import (
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"golang.org/x/crypto/md4"
+	"golang.org/x/crypto/sha3"
+	"hash"
+	"hash/adler32"
+	"hash/crc32"
+	"hash/fnv"
+	"io"
+	"os"
+)
+
+// ==============
+// reader hashing
+// ==============
+
+const (
+	// hashBufferSize represents the buffer size used when hashing streams, this makes the memory usage bounded no matter how large the
+	// stream is.
+	hashBufferSize = 32 * 1024
+)
+
+// FNV32Reader uses fnv32 to hash data read from io.Reader to uint32.
+func FNV32Reader(r io.Reader) (uint32, error) {
+	return Uint32ReaderHasher(fnv.New32(), r)
+}
+
+// FNV32aReader uses fnv32a to hash data read from io.Reader to uint32.
+func FNV32aReader(r io.Reader) (uint32, error) {
+	return Uint32ReaderHasher(fnv.New32a(), r)
+}
+
+// FNV64Reader uses fnv64 to hash data read from io.Reader to uint64.
+func FNV64Reader(r io.Reader) (uint64, error) {
+	return Uint64ReaderHasher(fnv.New64(), r)
+}
+
+// FNV64aReader uses fnv64a to hash data read from io.Reader to uint64.
+func FNV64aReader(r io.Reader) (uint64, error) {
+	return Uint64ReaderHasher(fnv.New64a(), r)
+}
+
+// CRC32Reader uses crc32 to hash data read from io.Reader to uint32.
+func CRC32Reader(r io.Reader) (uint32, error) {
+	return Uint32ReaderHasher(crc32.NewIEEE(), r)
+}
+
+// ADLER32Reader uses adler32 to hash data read from io.Reader to uint32.
+func ADLER32Reader(r io.Reader) (uint32, error) {
+	return Uint32ReaderHasher(adler32.New(), r)
+}
+
+// MD4Reader uses md4 to hash data read from io.Reader.
+func MD4Reader(r io.Reader) (string, error) {
+	return ReaderHasher(md4.New(), r)
+}
+
+// MD5Reader uses md5 to hash data read from io.Reader.
+func MD5Reader(r io.Reader) (string, error) {
+	return ReaderHasher(md5.New(), r)
+}
+
+// SHA1Reader uses sha-1 to hash data read from io.Reader.
+func SHA1Reader(r io.Reader) (string, error) {
+	return ReaderHasher(sha1.New(), r)
+}
+
+// SHA224Reader uses sha2-224 to hash data read from io.Reader.
+func SHA224Reader(r io.Reader) (string, error) {
+	return ReaderHasher(sha256.New224(), r)
+}
+
+// SHA256Reader uses sha2-256 to hash data read from io.Reader.
+func SHA256Reader(r io.Reader) (string, error) {
+	return ReaderHasher(sha256.New(), r)
+}
+
+// SHA384Reader uses sha2-384 to hash data read from io.Reader.
+func SHA384Reader(r io.Reader) (string, error) {
+	return ReaderHasher(sha512.New384(), r)
+}
+
+// SHA512Reader uses sha2-512 to hash data read from io.Reader.
+func SHA512Reader(r io.Reader) (string, error) {
+	return ReaderHasher(sha512.New(), r)
+}
+
+// SHA512_224Reader uses sha2-512/224 to hash data read from io.Reader.
+func SHA512_224Reader(r io.Reader) (string, error) {
+	return ReaderHasher(sha512.New512_224(), r)
+}
+
+// SHA512_256Reader uses sha2-512/256 to hash data read from io.Reader.
+func SHA512_256Reader(r io.Reader) (string, error) {
+	return ReaderHasher(sha512.New512_256(), r)
+}
+
+// SHA3_224Reader uses sha3-224 to hash data read from io.Reader.
+func SHA3_224Reader(r io.Reader) (string, error) {
+	return ReaderHasher(sha3.New224(), r)
+}
+
+// SHA3_256Reader uses sha3-256 to hash data read from io.Reader.
+func SHA3_256Reader(r io.Reader) (string, error) {
+	return ReaderHasher(sha3.New256(), r)
+}
+
+// SHA3_384Reader uses sha3-384 to hash data read from io.Reader.
+func SHA3_384Reader(r io.Reader) (string, error) {
+	return ReaderHasher(sha3.New384(), r)
+}
+
+// SHA3_512Reader uses sha3-512 to hash data read from io.Reader.
+func SHA3_512Reader(r io.Reader) (string, error) {
+	return ReaderHasher(sha3.New512(), r)
+}
+
+// Uint32ReaderHasher uses hash.Hash32 to encode data read from io.Reader to uint32.
+func Uint32ReaderHasher(algorithm hash.Hash32, r io.Reader) (uint32, error) {
+	if err := copyToHash(algorithm, r); err != nil {
+		return 0, err
+	}
+	return algorithm.Sum32(), nil
+}
+
+// Uint64ReaderHasher uses hash.Hash64 to encode data read from io.Reader to uint64.
+func Uint64ReaderHasher(algorithm hash.Hash64, r io.Reader) (uint64, error) {
+	if err := copyToHash(algorithm, r); err != nil {
+		return 0, err
+	}
+	return algorithm.Sum64(), nil
+}
+
+// ReaderHasher uses hash.Hash to encode data read from io.Reader to string. Note that data is read in chunks using a bounded buffer,
+// so this can be used to hash large stream, such as uploaded file.
+func ReaderHasher(algorithm hash.Hash, r io.Reader) (string, error) {
+	if err := copyToHash(algorithm, r); err != nil {
+		return "", err
+	}
+	return HexEncodeToString(algorithm.Sum(nil)), nil
+}
+
+// MultiReaderHasher uses multiple hash.Hash to encode data read from io.Reader to strings in one pass, the returned digests are in the
+// same order as given algorithms.
+// Example:
+// 	digests, err := xcrypto.MultiReaderHasher(r, md5.New(), sha256.New())
+// 	md5Digest, sha256Digest := digests[0], digests[1]
+func MultiReaderHasher(r io.Reader, algorithms ...hash.Hash) ([]string, error) {
+	writers := make([]io.Writer, 0, len(algorithms))
+	for _, algorithm := range algorithms {
+		writers = append(writers, algorithm)
+	}
+	if err := copyToHash(io.MultiWriter(writers...), r); err != nil {
+		return nil, err
+	}
+
+	digests := make([]string, 0, len(algorithms))
+	for _, algorithm := range algorithms {
+		digests = append(digests, HexEncodeToString(algorithm.Sum(nil)))
+	}
+	return digests, nil
+}
+
+// copyToHash copies data from io.Reader to given hash writer using a bounded buffer.
+func copyToHash(w io.Writer, r io.Reader) error {
+	buf := make([]byte, hashBufferSize)
+	_, err := io.CopyBuffer(w, struct{ io.Reader }{r}, buf) // hide io.WriterTo, to make sure the buffer is used
+	return err
+}
+
+// ============
+// file hashing
+// ============
+
+// FNV32File uses fnv32 to hash file content to uint32.
+func FNV32File(filename string) (uint32, error) {
+	return Uint32FileHasher(fnv.New32(), filename)
+}
+
+// FNV32aFile uses fnv32a to hash file content to uint32.
+func FNV32aFile(filename string) (uint32, error) {
+	return Uint32FileHasher(fnv.New32a(), filename)
+}
+
+// FNV64File uses fnv64 to hash file content to uint64.
+func FNV64File(filename string) (uint64, error) {
+	return Uint64FileHasher(fnv.New64(), filename)
+}
+
+// FNV64aFile uses fnv64a to hash file content to uint64.
+func FNV64aFile(filename string) (uint64, error) {
+	return Uint64FileHasher(fnv.New64a(), filename)
+}
+
+// CRC32File uses crc32 to hash file content to uint32.
+func CRC32File(filename string) (uint32, error) {
+	return Uint32FileHasher(crc32.NewIEEE(), filename)
+}
+
+// ADLER32File uses adler32 to hash file content to uint32.
+func ADLER32File(filename string) (uint32, error) {
+	return Uint32FileHasher(adler32.New(), filename)
+}
+
+// MD4File uses md4 to hash file content.
+func MD4File(filename string) (string, error) {
+	return FileHasher(md4.New(), filename)
+}
+
+// MD5File uses md5 to hash file content.
+func MD5File(filename string) (string, error) {
+	return FileHasher(md5.New(), filename)
+}
+
+// SHA1File uses sha-1 to hash file content.
+func SHA1File(filename string) (string, error) {
+	return FileHasher(sha1.New(), filename)
+}
+
+// SHA224File uses sha2-224 to hash file content.
+func SHA224File(filename string) (string, error) {
+	return FileHasher(sha256.New224(), filename)
+}
+
+// SHA256File uses sha2-256 to hash file content.
+func SHA256File(filename string) (string, error) {
+	return FileHasher(sha256.New(), filename)
+}
+
+// SHA384File uses sha2-384 to hash file content.
+func SHA384File(filename string) (string, error) {
+	return FileHasher(sha512.New384(), filename)
+}
+
+// SHA512File uses sha2-512 to hash file content.
+func SHA512File(filename string) (string, error) {
+	return FileHasher(sha512.New(), filename)
+}
+
+// SHA512_224File uses sha2-512/224 to hash file content.
+func SHA512_224File(filename string) (string, error) {
+	return FileHasher(sha512.New512_224(), filename)
+}
+
+// SHA512_256File uses sha2-512/256 to hash file content.
+func SHA512_256File(filename string) (string, error) {
+	return FileHasher(sha512.New512_256(), filename)
+}
+
+// SHA3_224File uses sha3-224 to hash file content.
+func SHA3_224File(filename string) (string, error) {
+	return FileHasher(sha3.New224(), filename)
+}
+
+// SHA3_256File uses sha3-256 to hash file content.
+func SHA3_256File(filename string) (string, error) {
+	return FileHasher(sha3.New256(), filename)
+}
+
+// SHA3_384File uses sha3-384 to hash file content.
+func SHA3_384File(filename string) (string, error) {
+	return FileHasher(sha3.New384(), filename)
+}
+
+// SHA3_512File uses sha3-512 to hash file content.
+func SHA3_512File(filename string) (string, error) {
+	return FileHasher(sha3.New512(), filename)
+}
+
+// Uint32FileHasher uses hash.Hash32 to encode file content to uint32.
+func Uint32FileHasher(algorithm hash.Hash32, filename string) (uint32, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	return Uint32ReaderHasher(algorithm, f)
+}
+
+// Uint64FileHasher uses hash.Hash64 to encode file content to uint64.
+func Uint64FileHasher(algorithm hash.Hash64, filename string) (uint64, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	return Uint64ReaderHasher(algorithm, f)
+}
+
+// FileHasher uses hash.Hash to encode file content to string, the file is read in chunks rather than loaded into memory.
+func FileHasher(algorithm hash.Hash, filename string) (string, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	return ReaderHasher(algorithm, f)
+}
+
+// MultiFileHasher uses multiple hash.Hash to encode file content to strings in one pass, the returned digests are in the same order as
+// given algorithms.
+func MultiFileHasher(filename string, algorithms ...hash.Hash) ([]string, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return MultiReaderHasher(f, algorithms...)
+}
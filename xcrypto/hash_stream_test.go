@@ -0,0 +1,157 @@
+package xcrypto
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"errors"
+	"github.com/Aoi-hosizora/ahlib/xtesting"
+	"hash/crc32"
+	"hash/fnv"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestReaderFileHasher(t *testing.T) {
+	dir, err := ioutil.TempDir("", "xcrypto")
+	xtesting.Nil(t, err)
+	defer os.RemoveAll(dir)
+	notExist := filepath.Join(dir, "not_exist")
+
+	for _, text := range []string{"", "test", "hello world", "测试 テス тест", strings.Repeat("0123456789", 10000)} {
+		filename := filepath.Join(dir, "test.txt")
+		xtesting.Nil(t, ioutil.WriteFile(filename, []byte(text), 0644))
+
+		for _, tc := range []struct {
+			giveFn       func(string) uint32
+			giveReaderFn func(io.Reader) (uint32, error)
+			giveFileFn   func(string) (uint32, error)
+		}{
+			{FNV32, FNV32Reader, FNV32File},
+			{FNV32a, FNV32aReader, FNV32aFile},
+			{CRC32, CRC32Reader, CRC32File},
+			{ADLER32, ADLER32Reader, ADLER32File},
+		} {
+			want := tc.giveFn(text)
+			got, err := tc.giveReaderFn(strings.NewReader(text))
+			xtesting.Nil(t, err)
+			xtesting.Equal(t, got, want)
+			got, err = tc.giveFileFn(filename)
+			xtesting.Nil(t, err)
+			xtesting.Equal(t, got, want)
+			_, err = tc.giveFileFn(notExist)
+			xtesting.True(t, os.IsNotExist(err))
+		}
+
+		for _, tc := range []struct {
+			giveFn       func(string) uint64
+			giveReaderFn func(io.Reader) (uint64, error)
+			giveFileFn   func(string) (uint64, error)
+		}{
+			{FNV64, FNV64Reader, FNV64File},
+			{FNV64a, FNV64aReader, FNV64aFile},
+		} {
+			want := tc.giveFn(text)
+			got, err := tc.giveReaderFn(strings.NewReader(text))
+			xtesting.Nil(t, err)
+			xtesting.Equal(t, got, want)
+			got, err = tc.giveFileFn(filename)
+			xtesting.Nil(t, err)
+			xtesting.Equal(t, got, want)
+			_, err = tc.giveFileFn(notExist)
+			xtesting.True(t, os.IsNotExist(err))
+		}
+
+		for _, tc := range []struct {
+			giveFn       func(string) string
+			giveReaderFn func(io.Reader) (string, error)
+			giveFileFn   func(string) (string, error)
+		}{
+			{MD4, MD4Reader, MD4File},
+			{MD5, MD5Reader, MD5File},
+			{SHA1, SHA1Reader, SHA1File},
+			{SHA224, SHA224Reader, SHA224File},
+			{SHA256, SHA256Reader, SHA256File},
+			{SHA384, SHA384Reader, SHA384File},
+			{SHA512, SHA512Reader, SHA512File},
+			{SHA512_224, SHA512_224Reader, SHA512_224File},
+			{SHA512_256, SHA512_256Reader, SHA512_256File},
+			{SHA3_224, SHA3_224Reader, SHA3_224File},
+			{SHA3_256, SHA3_256Reader, SHA3_256File},
+			{SHA3_384, SHA3_384Reader, SHA3_384File},
+			{SHA3_512, SHA3_512Reader, SHA3_512File},
+		} {
+			want := tc.giveFn(text)
+			got, err := tc.giveReaderFn(strings.NewReader(text))
+			xtesting.Nil(t, err)
+			xtesting.Equal(t, got, want)
+			got, err = tc.giveFileFn(filename)
+			xtesting.Nil(t, err)
+			xtesting.Equal(t, got, want)
+			_, err = tc.giveFileFn(notExist)
+			xtesting.True(t, os.IsNotExist(err))
+		}
+
+		digests, err := MultiFileHasher(filename, md5.New(), sha256.New())
+		xtesting.Nil(t, err)
+		xtesting.Equal(t, digests, []string{MD5(text), SHA256(text)})
+	}
+
+	// bounded buffer
+	r := &recordReader{Reader: strings.NewReader(strings.Repeat("a", 10*hashBufferSize))}
+	digest, err := SHA256Reader(r)
+	xtesting.Nil(t, err)
+	xtesting.Equal(t, digest, SHA256(strings.Repeat("a", 10*hashBufferSize)))
+	xtesting.Equal(t, r.maxRead, hashBufferSize)
+
+	// reader error
+	testErr := errors.New("test")
+	errReader := io.MultiReader(strings.NewReader("test"), &errorReader{testErr})
+	_, err = Uint32ReaderHasher(crc32.NewIEEE(), errReader)
+	xtesting.Equal(t, err, testErr)
+	_, err = Uint64ReaderHasher(fnv.New64(), errReader)
+	xtesting.Equal(t, err, testErr)
+	_, err = ReaderHasher(md5.New(), errReader)
+	xtesting.Equal(t, err, testErr)
+	_, err = MultiReaderHasher(errReader, md5.New())
+	xtesting.Equal(t, err, testErr)
+	_, err = MultiFileHasher(notExist, md5.New())
+	xtesting.True(t, os.IsNotExist(err))
+}
+
+func TestMultiReaderHasher(t *testing.T) {
+	digests, err := MultiReaderHasher(strings.NewReader("hello world"))
+	xtesting.Nil(t, err)
+	xtesting.Equal(t, len(digests), 0)
+
+	digests, err = MultiReaderHasher(strings.NewReader("hello world"), md5.New(), sha256.New(), md5.New())
+	xtesting.Nil(t, err)
+	xtesting.Equal(t, digests, []string{
+		"5eb63bbbe01eeed093cb22bb8f5acdc3",
+		"b94d27b9934d3e08a52e52d7da7dabfac484efe37a5380ee9088f7ace2efcde9",
+		"5eb63bbbe01eeed093cb22bb8f5acdc3",
+	})
+}
+
+type recordReader struct {
+	io.Reader
+	maxRead int
+}
+
+func (r *recordReader) Read(p []byte) (int, error) {
+	if len(p) > r.maxRead {
+		r.maxRead = len(p)
+	}
+	return r.Reader.Read(p)
+}
+
+type errorReader struct {
+	err error
+}
+
+func (e *errorReader) Read([]byte) (int, error) {
+	return 0, e.err
+}
@@ -0,0 +1,144 @@
+package xcrypto
+
+import (
+	"crypto/hmac"
+	"crypto/subtle"
+	"github.com/Aoi-hosizora/ahlib/xstring"
+	"hash"
+	"io"
+)
+
+// ====
+// hmac
+// ====
+
+// HMACMD5 uses hmac-md5 to hash string with given key, and returns lowercase hex string.
+func HMACMD5(key []byte, text string) string {
+	return HMACString(HashMD5, key, text, EncodingHex)
+}
+
+// HMACSHA1 uses hmac-sha-1 to hash string with given key, and returns lowercase hex string.
+func HMACSHA1(key []byte, text string) string {
+	return HMACString(HashSHA1, key, text, EncodingHex)
+}
+
+// HMACSHA224 uses hmac-sha2-224 to hash string with given key, and returns lowercase hex string.
+func HMACSHA224(key []byte, text string) string {
+	return HMACString(HashSHA224, key, text, EncodingHex)
+}
+
+// HMACSHA256 uses hmac-sha2-256 to hash string with given key, and returns lowercase hex string.
+func HMACSHA256(key []byte, text string) string {
+	return HMACString(HashSHA256, key, text, EncodingHex)
+}
+
+// HMACSHA384 uses hmac-sha2-384 to hash string with given key, and returns lowercase hex string.
+func HMACSHA384(key []byte, text string) string {
+	return HMACString(HashSHA384, key, text, EncodingHex)
+}
+
+// HMACSHA512 uses hmac-sha2-512 to hash string with given key, and returns lowercase hex string.
+func HMACSHA512(key []byte, text string) string {
+	return HMACString(HashSHA512, key, text, EncodingHex)
+}
+
+// HMACSHA512_224 uses hmac-sha2-512/224 to hash string with given key, and returns lowercase hex string.
+func HMACSHA512_224(key []byte, text string) string {
+	return HMACString(HashSHA512_224, key, text, EncodingHex)
+}
+
+// HMACSHA512_256 uses hmac-sha2-512/256 to hash string with given key, and returns lowercase hex string.
+func HMACSHA512_256(key []byte, text string) string {
+	return HMACString(HashSHA512_256, key, text, EncodingHex)
+}
+
+// HMACSHA3_224 uses hmac-sha3-224 to hash string with given key, and returns lowercase hex string.
+func HMACSHA3_224(key []byte, text string) string {
+	return HMACString(HashSHA3_224, key, text, EncodingHex)
+}
+
+// HMACSHA3_256 uses hmac-sha3-256 to hash string with given key, and returns lowercase hex string.
+func HMACSHA3_256(key []byte, text string) string {
+	return HMACString(HashSHA3_256, key, text, EncodingHex)
+}
+
+// HMACSHA3_384 uses hmac-sha3-384 to hash string with given key, and returns lowercase hex string.
+func HMACSHA3_384(key []byte, text string) string {
+	return HMACString(HashSHA3_384, key, text, EncodingHex)
+}
+
+// HMACSHA3_512 uses hmac-sha3-512 to hash string with given key, and returns lowercase hex string.
+func HMACSHA3_512(key []byte, text string) string {
+	return HMACString(HashSHA3_512, key, text, EncodingHex)
+}
+
+// NewHMAC creates a new hmac hash.Hash using given HashAlgorithm and key, panics if the algorithm is invalid, or is a non-cryptographic
+// checksum (HashFNV32, HashFNV32a, HashFNV64, HashFNV64a, HashCRC32 and HashADLER32). Note that all the HMAC and VerifyHMAC functions
+// share this restriction.
+func NewHMAC(algorithm HashAlgorithm, key []byte) hash.Hash {
+	if !algorithm.Available() {
+		panic(panicInvalidHashAlgorithm)
+	}
+	if !algorithm.isCryptographic() {
+		panic(panicInvalidHMACAlgorithm)
+	}
+	return hmac.New(algorithm.New, key)
+}
+
+// HMAC uses given HashAlgorithm and key to calculate the hmac of data, and encodes the mac using given DigestEncoding, panics if the
+// algorithm or encoding is invalid.
+func HMAC(algorithm HashAlgorithm, key, data []byte, encoding DigestEncoding) []byte {
+	h := NewHMAC(algorithm, key)
+	_, _ = h.Write(data)
+	return encoding.Encode(h.Sum(nil))
+}
+
+// HMACString uses given HashAlgorithm and key to calculate the hmac of string, and encodes the mac to string using given DigestEncoding.
+func HMACString(algorithm HashAlgorithm, key []byte, text string, encoding DigestEncoding) string {
+	return xstring.FastBtos(HMAC(algorithm, key, xstring.FastStob(text), encoding))
+}
+
+// HMACReader uses given HashAlgorithm and key to calculate the hmac of data read from io.Reader using a bounded buffer, and encodes the
+// mac using given DigestEncoding.
+func HMACReader(algorithm HashAlgorithm, key []byte, r io.Reader, encoding DigestEncoding) ([]byte, error) {
+	h := NewHMAC(algorithm, key)
+	if err := copyToHash(h, r); err != nil {
+		return nil, err
+	}
+	return encoding.Encode(h.Sum(nil)), nil
+}
+
+// VerifyHMAC checks whether given mac, which is encoded using given DigestEncoding, is the hmac of data, the comparison is done in
+// constant time. Note that malformed mac is treated as mismatched.
+// Example:
+// 	// verify webhook signature, such as "X-Hub-Signature-256: sha256=xxx"
+// 	signature := strings.TrimPrefix(r.Header.Get("X-Hub-Signature-256"), "sha256=")
+// 	ok := xcrypto.VerifyHMAC(xcrypto.HashSHA256, secret, body, []byte(signature), xcrypto.EncodingHex)
+func VerifyHMAC(algorithm HashAlgorithm, key, data, mac []byte, encoding DigestEncoding) bool {
+	return compareMAC(HMAC(algorithm, key, data, EncodingRaw), mac, encoding)
+}
+
+// VerifyHMACString checks whether given mac string, which is encoded using given DigestEncoding, is the hmac of text, the comparison is
+// done in constant time.
+func VerifyHMACString(algorithm HashAlgorithm, key []byte, text, mac string, encoding DigestEncoding) bool {
+	return VerifyHMAC(algorithm, key, xstring.FastStob(text), xstring.FastStob(mac), encoding)
+}
+
+// VerifyHMACReader checks whether given mac, which is encoded using given DigestEncoding, is the hmac of data read from io.Reader, the
+// comparison is done in constant time.
+func VerifyHMACReader(algorithm HashAlgorithm, key []byte, r io.Reader, mac []byte, encoding DigestEncoding) (bool, error) {
+	expected, err := HMACReader(algorithm, key, r, EncodingRaw)
+	if err != nil {
+		return false, err
+	}
+	return compareMAC(expected, mac, encoding), nil
+}
+
+// compareMAC decodes given encoded mac, and compares it with expected raw mac in constant time.
+func compareMAC(expected, mac []byte, encoding DigestEncoding) bool {
+	decoded, err := encoding.Decode(mac)
+	if err != nil {
+		return false
+	}
+	return subtle.ConstantTimeCompare(expected, decoded) == 1
+}
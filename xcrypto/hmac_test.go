@@ -0,0 +1,99 @@
+package xcrypto
+
+import (
+	"errors"
+	"github.com/Aoi-hosizora/ahlib/xtesting"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestHMAC(t *testing.T) {
+	// https://datatracker.ietf.org/doc/html/rfc4231#section-4.3
+	key := []byte("Jefe")
+	text := "what do ya want for nothing?"
+
+	for _, tc := range []struct {
+		giveFn        func([]byte, string) string
+		giveAlgorithm HashAlgorithm
+		want          string
+	}{
+		{HMACMD5, HashMD5, "750c783e6ab0b503eaa86e310a5db738"},
+		{HMACSHA1, HashSHA1, "effcdf6ae5eb2fa2d27416d5f184df9c259a7c79"},
+		{HMACSHA224, HashSHA224, "a30e01098bc6dbbf45690f3a7e9e6d0f8bbea2a39e6148008fd05e44"},
+		{HMACSHA256, HashSHA256, "5bdcc146bf60754e6a042426089575c75a003f089d2739839dec58b964ec3843"},
+		{HMACSHA384, HashSHA384, "af45d2e376484031617f78d2b58a6b1b9c7ef464f5a01b47e42ec3736322445e8e2240ca5e69e2c78b3239ecfab21649"},
+		{HMACSHA512, HashSHA512, "164b7a7bfcf819e2e395fbe73b56e0a387bd64222e831fd610270cd7ea2505549758bf75c05a994a6d034f65f8f0e6fdcaeab1a34d4a6b4b636e070a38bce737"},
+		{HMACSHA512_224, HashSHA512_224, "4a530b31a79ebcce36916546317c45f247d83241dfb818fd37254bde"},
+		{HMACSHA512_256, HashSHA512_256, "6df7b24630d5ccb2ee335407081a87188c221489768fa2020513b2d593359456"},
+		{HMACSHA3_224, HashSHA3_224, "7fdb8dd88bd2f60d1b798634ad386811c2cfc85bfaf5d52bbace5e66"},
+		{HMACSHA3_256, HashSHA3_256, "c7d4072e788877ae3596bbb0da73b887c9171f93095b294ae857fbe2645e1ba5"},
+		{HMACSHA3_384, HashSHA3_384, "f1101f8cbf9766fd6764d2ed61903f21ca9b18f57cf3e1a23ca13508a93243ce48c045dc007f26a21b3f5e0e9df4c20a"},
+		{HMACSHA3_512, HashSHA3_512, "5a4bfeab6166427c7a3647b747292b8384537cdb89afb3bf5665e4c5e709350b287baec921fd7ca0ee7a0c31d022a95e1fc92ba9d77df883960275beb4e62024"},
+	} {
+		xtesting.Equal(t, tc.giveFn(key, text), tc.want)
+		xtesting.Equal(t, HMACString(tc.giveAlgorithm, key, text, EncodingHex), tc.want)
+		xtesting.Equal(t, string(HMAC(tc.giveAlgorithm, key, []byte(text), EncodingHex)), tc.want)
+		mac, err := HMACReader(tc.giveAlgorithm, key, strings.NewReader(text), EncodingHex)
+		xtesting.Nil(t, err)
+		xtesting.Equal(t, string(mac), tc.want)
+
+		xtesting.True(t, VerifyHMACString(tc.giveAlgorithm, key, text, tc.want, EncodingHex))
+		xtesting.True(t, VerifyHMACString(tc.giveAlgorithm, key, text, strings.ToUpper(tc.want), EncodingUpperHex))
+		xtesting.False(t, VerifyHMACString(tc.giveAlgorithm, key, text+".", tc.want, EncodingHex))
+		xtesting.False(t, VerifyHMACString(tc.giveAlgorithm, []byte("Jeff"), text, tc.want, EncodingHex))
+	}
+
+	// encodings
+	for _, tc := range []struct {
+		giveEncoding DigestEncoding
+		want         string
+	}{
+		{EncodingHex, "5bdcc146bf60754e6a042426089575c75a003f089d2739839dec58b964ec3843"},
+		{EncodingUpperHex, "5BDCC146BF60754E6A042426089575C75A003F089D2739839DEC58B964EC3843"},
+		{EncodingBase32, "LPOMCRV7MB2U42QEEQTARFLVY5NAAPYITUTTTA455RMLSZHMHBBQ===="},
+		{EncodingBase64, "W9zBRr9gdU5qBCQmCJV1x1oAPwidJzmDnexYuWTsOEM="},
+		{EncodingBase64URL, "W9zBRr9gdU5qBCQmCJV1x1oAPwidJzmDnexYuWTsOEM="},
+	} {
+		mac := HMACString(HashSHA256, key, text, tc.giveEncoding)
+		xtesting.Equal(t, mac, tc.want)
+		xtesting.True(t, VerifyHMAC(HashSHA256, key, []byte(text), []byte(mac), tc.giveEncoding))
+		ok, err := VerifyHMACReader(HashSHA256, key, strings.NewReader(text), []byte(mac), tc.giveEncoding)
+		xtesting.Nil(t, err)
+		xtesting.True(t, ok)
+	}
+	raw := HMAC(HashSHA256, key, []byte(text), EncodingRaw)
+	xtesting.Equal(t, len(raw), 32)
+	xtesting.True(t, VerifyHMAC(HashSHA256, key, []byte(text), raw, EncodingRaw))
+	xtesting.False(t, VerifyHMAC(HashSHA256, key, []byte(text), raw[:31], EncodingRaw))
+	xtesting.False(t, VerifyHMAC(HashSHA256, key, []byte(text), []byte("not hex"), EncodingHex))
+	xtesting.False(t, VerifyHMAC(HashSHA256, key, []byte(text), []byte("!"), EncodingBase64))
+
+	// hash.Hash
+	h := NewHMAC(HashSHA256, key)
+	_, _ = h.Write([]byte(text))
+	xtesting.Equal(t, h.Sum(nil), raw)
+
+	// errors and panics
+	testErr := errors.New("test")
+	_, err := HMACReader(HashSHA256, key, io.MultiReader(strings.NewReader("test"), &errorReader{testErr}), EncodingHex)
+	xtesting.Equal(t, err, testErr)
+	ok, err := VerifyHMACReader(HashSHA256, key, &errorReader{testErr}, nil, EncodingHex)
+	xtesting.False(t, ok)
+	xtesting.Equal(t, err, testErr)
+	xtesting.Panic(t, func() { NewHMAC(0, key) })
+	xtesting.Panic(t, func() { HMAC(HashSHA256, key, nil, 99) })
+	xtesting.Panic(t, func() { VerifyHMAC(HashSHA256, key, nil, nil, 99) })
+
+	// non-cryptographic checksums
+	for _, algorithm := range []HashAlgorithm{HashFNV32, HashFNV32a, HashFNV64, HashFNV64a, HashCRC32, HashADLER32} {
+		xtesting.PanicWithValue(t, panicInvalidHMACAlgorithm, func() { NewHMAC(algorithm, key) })
+		xtesting.PanicWithValue(t, panicInvalidHMACAlgorithm, func() { HMAC(algorithm, key, nil, EncodingHex) })
+		xtesting.PanicWithValue(t, panicInvalidHMACAlgorithm, func() { HMACString(algorithm, key, text, EncodingHex) })
+		xtesting.PanicWithValue(t, panicInvalidHMACAlgorithm, func() { _, _ = HMACReader(algorithm, key, strings.NewReader(text), EncodingHex) })
+		xtesting.PanicWithValue(t, panicInvalidHMACAlgorithm, func() { VerifyHMAC(algorithm, key, nil, nil, EncodingHex) })
+		xtesting.PanicWithValue(t, panicInvalidHMACAlgorithm, func() { VerifyHMACString(algorithm, key, text, "", EncodingHex) })
+		xtesting.PanicWithValue(t, panicInvalidHMACAlgorithm, func() { _, _ = VerifyHMACReader(algorithm, key, strings.NewReader(text), nil, EncodingHex) })
+	}
+	xtesting.NotPanic(t, func() { NewHMAC(HashMD4, key) })
+}
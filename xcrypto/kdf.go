@@ -0,0 +1,366 @@
+package xcrypto
+
+import (
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/hkdf"
+	"golang.org/x/crypto/pbkdf2"
+	"golang.org/x/crypto/scrypt"
+	"io"
+	"math/bits"
+	"strings"
+)
+
+// =========================
+// password & key derivation
+// =========================
+
+var (
+	// ErrInvalidEncodedHash represents the encoded hash is not in the expected PHC string format, or the algorithm or version is not
+	// supported.
+	ErrInvalidEncodedHash = errors.New("xcrypto: invalid encoded hash")
+
+	// ErrInvalidKDFParams represents the parameters of key derivation function are invalid.
+	ErrInvalidKDFParams = errors.New("xcrypto: invalid key derivation parameters")
+)
+
+// _phcEncoding is the base64 encoding used by PHC string format, which is standard base64 without padding.
+var _phcEncoding = base64.RawStdEncoding
+
+// generateSalt generates a random salt in given size.
+func generateSalt(size uint32) ([]byte, error) {
+	if size == 0 {
+		return nil, ErrInvalidKDFParams
+	}
+	return GenerateKey(int(size))
+}
+
+// splitPHC splits given PHC string to n parts, and decodes the last two parts as salt and hash.
+func splitPHC(encoded string, n int) (parts []string, salt, hash []byte, err error) {
+	parts = strings.Split(encoded, "$")
+	if len(parts) != n || parts[0] != "" {
+		return nil, nil, nil, ErrInvalidEncodedHash
+	}
+	salt, err1 := _phcEncoding.DecodeString(parts[n-2])
+	hash, err2 := _phcEncoding.DecodeString(parts[n-1])
+	if err1 != nil || err2 != nil || len(hash) == 0 {
+		return nil, nil, nil, ErrInvalidEncodedHash
+	}
+	return parts, salt, hash, nil
+}
+
+// ========
+// argon2id
+// ========
+
+// Argon2idParams represents the parameters of Argon2id.
+type Argon2idParams struct {
+	Memory      uint32 // memory size in KiB
+	Iterations  uint32 // number of passes over the memory
+	Parallelism uint8  // number of threads
+	SaltLength  uint32 // salt length in bytes, used when hashing password
+	KeyLength   uint32 // key length in bytes
+}
+
+// DefaultArgon2idParams is the default Argon2idParams, which is the second recommended option in RFC 9106 (64 MiB memory, 3 iterations,
+// 4 threads, 16 bytes salt and 32 bytes key).
+var DefaultArgon2idParams = Argon2idParams{Memory: 64 * 1024, Iterations: 3, Parallelism: 4, SaltLength: 16, KeyLength: 32}
+
+// MaxArgon2idParams is the maximum Argon2idParams accepted by Argon2idCompare (1 GiB memory, 64 iterations, 64 threads and 1024 bytes
+// key), encoded hash exceeding any of them will be rejected with ErrInvalidEncodedHash, to prevent denial of service by crafted hash.
+// Note that SaltLength is ignored.
+var MaxArgon2idParams = Argon2idParams{Memory: 1024 * 1024, Iterations: 64, Parallelism: 64, KeyLength: 1024}
+
+// validate checks whether the Argon2idParams is valid.
+func (p *Argon2idParams) validate() error {
+	if p.Iterations < 1 || p.Parallelism < 1 || p.KeyLength < 1 || p.Memory < 8*uint32(p.Parallelism) {
+		return ErrInvalidKDFParams
+	}
+	return nil
+}
+
+// exceeds checks whether the Argon2idParams exceeds given maximum parameters.
+func (p *Argon2idParams) exceeds(max Argon2idParams) bool {
+	return p.Memory > max.Memory || p.Iterations > max.Iterations || p.Parallelism > max.Parallelism || p.KeyLength > max.KeyLength
+}
+
+// Argon2idKey uses Argon2id to derive a key from password and salt, which can be used as encryption key.
+func Argon2idKey(password, salt []byte, params Argon2idParams) ([]byte, error) {
+	if err := params.validate(); err != nil {
+		return nil, err
+	}
+	return argon2.IDKey(password, salt, params.Iterations, params.Memory, params.Parallelism, params.KeyLength), nil
+}
+
+// Argon2idHash uses Argon2id to hash password with a random salt, and returns the encoded hash in PHC string format, such as
+// "$argon2id$v=19$m=65536,t=3,p=4$<salt>$<hash>".
+func Argon2idHash(password []byte, params Argon2idParams) (string, error) {
+	if err := params.validate(); err != nil {
+		return "", err
+	}
+	salt, err := generateSalt(params.SaltLength)
+	if err != nil {
+		return "", err
+	}
+	hash := argon2.IDKey(password, salt, params.Iterations, params.Memory, params.Parallelism, params.KeyLength)
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s", argon2.Version, params.Memory, params.Iterations, params.Parallelism,
+		_phcEncoding.EncodeToString(salt), _phcEncoding.EncodeToString(hash)), nil
+}
+
+// Argon2idHashWithDefaultParams uses Argon2id to hash password using DefaultArgon2idParams.
+func Argon2idHashWithDefaultParams(password []byte) (string, error) {
+	return Argon2idHash(password, DefaultArgon2idParams)
+}
+
+// Argon2idCompare compares encoded hash generated by Argon2idHash and given password in constant time, ErrInvalidEncodedHash will be
+// returned if the encoded hash is malformed or exceeds MaxArgon2idParams.
+func Argon2idCompare(password []byte, encoded string) (ok bool, err error) {
+	parts, salt, hash, err := splitPHC(encoded, 6)
+	if err != nil {
+		return false, err
+	}
+	var version int
+	params := Argon2idParams{KeyLength: uint32(len(hash))}
+	if parts[1] != "argon2id" || !scanPHC(parts[2], "v=%d", &version) || version != argon2.Version ||
+		!scanPHC(parts[3], "m=%d,t=%d,p=%d", &params.Memory, &params.Iterations, &params.Parallelism) {
+		return false, ErrInvalidEncodedHash
+	}
+	if params.validate() != nil || params.exceeds(MaxArgon2idParams) {
+		return false, ErrInvalidEncodedHash
+	}
+	other := argon2.IDKey(password, salt, params.Iterations, params.Memory, params.Parallelism, params.KeyLength)
+	return subtle.ConstantTimeCompare(hash, other) == 1, nil
+}
+
+// scanPHC scans the PHC parameters part using given format, and checks the whole part is consumed.
+func scanPHC(part, format string, a ...interface{}) bool {
+	n, err := fmt.Sscanf(part, format, a...)
+	return err == nil && n == len(a) && fmt.Sprintf(format, derefAll(a)...) == part
+}
+
+// derefAll dereferences given pointers of scanned values.
+func derefAll(a []interface{}) []interface{} {
+	out := make([]interface{}, 0, len(a))
+	for _, v := range a {
+		switch p := v.(type) {
+		case *int:
+			out = append(out, *p)
+		case *uint32:
+			out = append(out, *p)
+		case *uint8:
+			out = append(out, *p)
+		}
+	}
+	return out
+}
+
+// ======
+// scrypt
+// ======
+
+// ScryptParams represents the parameters of scrypt.
+type ScryptParams struct {
+	N          int    // CPU/memory cost, must be a power of two greater than 1
+	R          int    // block size
+	P          int    // parallelization
+	SaltLength uint32 // salt length in bytes, used when hashing password
+	KeyLength  uint32 // key length in bytes
+}
+
+// DefaultScryptParams is the default ScryptParams, which is the recommended option for interactive logins (N=32768, r=8, p=1, 16 bytes
+// salt and 32 bytes key).
+var DefaultScryptParams = ScryptParams{N: 32768, R: 8, P: 1, SaltLength: 16, KeyLength: 32}
+
+// MaxScryptParams is the maximum ScryptParams accepted by ScryptCompare (N=1048576, r=16, p=16 and 1024 bytes key), encoded hash
+// exceeding any of them will be rejected with ErrInvalidEncodedHash, to prevent denial of service by crafted hash. Note that SaltLength
+// is ignored.
+var MaxScryptParams = ScryptParams{N: 1 << 20, R: 16, P: 16, KeyLength: 1024}
+
+// validate checks whether the ScryptParams is valid.
+func (p *ScryptParams) validate() error {
+	if p.N <= 1 || p.N&(p.N-1) != 0 || p.R < 1 || p.P < 1 || p.KeyLength < 1 || uint64(p.R)*uint64(p.P) >= 1<<30 {
+		return ErrInvalidKDFParams
+	}
+	return nil
+}
+
+// exceeds checks whether the ScryptParams exceeds given maximum parameters.
+func (p *ScryptParams) exceeds(max ScryptParams) bool {
+	return p.N > max.N || p.R > max.R || p.P > max.P || p.KeyLength > max.KeyLength
+}
+
+// ScryptKey uses scrypt to derive a key from password and salt, which can be used as encryption key.
+func ScryptKey(password, salt []byte, params ScryptParams) ([]byte, error) {
+	if err := params.validate(); err != nil {
+		return nil, err
+	}
+	return scrypt.Key(password, salt, params.N, params.R, params.P, int(params.KeyLength))
+}
+
+// ScryptHash uses scrypt to hash password with a random salt, and returns the encoded hash in PHC string format, such as
+// "$scrypt$ln=15,r=8,p=1$<salt>$<hash>", where ln is log2(N).
+func ScryptHash(password []byte, params ScryptParams) (string, error) {
+	if err := params.validate(); err != nil {
+		return "", err
+	}
+	salt, err := generateSalt(params.SaltLength)
+	if err != nil {
+		return "", err
+	}
+	hash, err := scrypt.Key(password, salt, params.N, params.R, params.P, int(params.KeyLength))
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("$scrypt$ln=%d,r=%d,p=%d$%s$%s", bits.TrailingZeros(uint(params.N)), params.R, params.P,
+		_phcEncoding.EncodeToString(salt), _phcEncoding.EncodeToString(hash)), nil
+}
+
+// ScryptHashWithDefaultParams uses scrypt to hash password using DefaultScryptParams.
+func ScryptHashWithDefaultParams(password []byte) (string, error) {
+	return ScryptHash(password, DefaultScryptParams)
+}
+
+// ScryptCompare compares encoded hash generated by ScryptHash and given password in constant time, ErrInvalidEncodedHash will be returned
+// if the encoded hash is malformed or exceeds MaxScryptParams.
+func ScryptCompare(password []byte, encoded string) (ok bool, err error) {
+	parts, salt, hash, err := splitPHC(encoded, 5)
+	if err != nil {
+		return false, err
+	}
+	var ln int
+	params := ScryptParams{KeyLength: uint32(len(hash))}
+	if parts[1] != "scrypt" || !scanPHC(parts[2], "ln=%d,r=%d,p=%d", &ln, &params.R, &params.P) || ln < 1 || ln > 62 {
+		return false, ErrInvalidEncodedHash
+	}
+	params.N = 1 << uint(ln)
+	if params.validate() != nil || params.exceeds(MaxScryptParams) {
+		return false, ErrInvalidEncodedHash
+	}
+	other, err := scrypt.Key(password, salt, params.N, params.R, params.P, int(params.KeyLength))
+	if err != nil {
+		return false, ErrInvalidEncodedHash
+	}
+	return subtle.ConstantTimeCompare(hash, other) == 1, nil
+}
+
+// ======
+// pbkdf2
+// ======
+
+// PBKDF2Params represents the parameters of PBKDF2.
+type PBKDF2Params struct {
+	Hash       HashAlgorithm // the pseudorandom function used by hmac, must be a SHA-2 or SHA-3 hash algorithm
+	Iterations int           // number of iterations
+	SaltLength uint32        // salt length in bytes, used when hashing password
+	KeyLength  uint32        // key length in bytes
+}
+
+// DefaultPBKDF2Params is the default PBKDF2Params, which is the OWASP recommended option for PBKDF2-HMAC-SHA256 (600000 iterations, 16
+// bytes salt and 32 bytes key).
+var DefaultPBKDF2Params = PBKDF2Params{Hash: HashSHA256, Iterations: 600000, SaltLength: 16, KeyLength: 32}
+
+// MaxPBKDF2Params is the maximum PBKDF2Params accepted by PBKDF2Compare (10000000 iterations and 1024 bytes key), encoded hash exceeding
+// any of them will be rejected with ErrInvalidEncodedHash, to prevent denial of service by crafted hash. Note that Hash and SaltLength
+// are ignored.
+var MaxPBKDF2Params = PBKDF2Params{Iterations: 10000000, KeyLength: 1024}
+
+// validate checks whether the PBKDF2Params is valid.
+func (p *PBKDF2Params) validate() error {
+	if !p.Hash.isCryptographic() || !isSHA2OrSHA3(p.Hash) || p.Iterations < 1 || p.KeyLength < 1 {
+		return ErrInvalidKDFParams
+	}
+	return nil
+}
+
+// exceeds checks whether the PBKDF2Params exceeds given maximum parameters.
+func (p *PBKDF2Params) exceeds(max PBKDF2Params) bool {
+	return p.Iterations > max.Iterations || p.KeyLength > max.KeyLength
+}
+
+// isSHA2OrSHA3 checks whether given HashAlgorithm belongs to SHA-2 or SHA-3 family, MD4, MD5 and SHA-1 are not allowed to be used in
+// PBKDF2.
+func isSHA2OrSHA3(algorithm HashAlgorithm) bool {
+	switch algorithm {
+	case HashSHA224, HashSHA256, HashSHA384, HashSHA512, HashSHA512_224, HashSHA512_256,
+		HashSHA3_224, HashSHA3_256, HashSHA3_384, HashSHA3_512:
+		return true
+	}
+	return false
+}
+
+// pbkdf2ID returns the PHC identifier of PBKDF2 with given HashAlgorithm, such as "pbkdf2-sha256" and "pbkdf2-sha512-256".
+func pbkdf2ID(algorithm HashAlgorithm) string {
+	return "pbkdf2-" + strings.ReplaceAll(algorithm.String(), "/", "-")
+}
+
+// PBKDF2Key uses PBKDF2 to derive a key from password and salt, which can be used as encryption key.
+func PBKDF2Key(password, salt []byte, params PBKDF2Params) ([]byte, error) {
+	if err := params.validate(); err != nil {
+		return nil, err
+	}
+	return pbkdf2.Key(password, salt, params.Iterations, int(params.KeyLength), params.Hash.New), nil
+}
+
+// PBKDF2Hash uses PBKDF2 to hash password with a random salt, and returns the encoded hash in PHC string format, such as
+// "$pbkdf2-sha256$i=600000$<salt>$<hash>".
+func PBKDF2Hash(password []byte, params PBKDF2Params) (string, error) {
+	if err := params.validate(); err != nil {
+		return "", err
+	}
+	salt, err := generateSalt(params.SaltLength)
+	if err != nil {
+		return "", err
+	}
+	hash := pbkdf2.Key(password, salt, params.Iterations, int(params.KeyLength), params.Hash.New)
+	return fmt.Sprintf("$%s$i=%d$%s$%s", pbkdf2ID(params.Hash), params.Iterations,
+		_phcEncoding.EncodeToString(salt), _phcEncoding.EncodeToString(hash)), nil
+}
+
+// PBKDF2HashWithDefaultParams uses PBKDF2 to hash password using DefaultPBKDF2Params.
+func PBKDF2HashWithDefaultParams(password []byte) (string, error) {
+	return PBKDF2Hash(password, DefaultPBKDF2Params)
+}
+
+// PBKDF2Compare compares encoded hash generated by PBKDF2Hash and given password in constant time, ErrInvalidEncodedHash will be returned
+// if the encoded hash is malformed or exceeds MaxPBKDF2Params.
+func PBKDF2Compare(password []byte, encoded string) (ok bool, err error) {
+	parts, salt, hash, err := splitPHC(encoded, 5)
+	if err != nil {
+		return false, err
+	}
+	params := PBKDF2Params{KeyLength: uint32(len(hash))}
+	for _, algorithm := range HashAlgorithms() {
+		if pbkdf2ID(algorithm) == parts[1] {
+			params.Hash = algorithm
+			break
+		}
+	}
+	if !scanPHC(parts[2], "i=%d", &params.Iterations) || params.validate() != nil || params.exceeds(MaxPBKDF2Params) {
+		return false, ErrInvalidEncodedHash
+	}
+	other := pbkdf2.Key(password, salt, params.Iterations, int(params.KeyLength), params.Hash.New)
+	return subtle.ConstantTimeCompare(hash, other) == 1, nil
+}
+
+// ====
+// hkdf
+// ====
+
+// HKDF uses HKDF (HMAC-based Extract-and-Expand Key Derivation Function) with given HashAlgorithm to derive a subkey in given length from
+// secret, salt (can be nil) and info (can be nil). Note that HKDF is not suitable for password, use Argon2idKey or ScryptKey instead.
+// Example:
+// 	encKey, _ := xcrypto.HKDF(xcrypto.HashSHA256, masterKey, salt, []byte("encryption"), 32)
+// 	macKey, _ := xcrypto.HKDF(xcrypto.HashSHA256, masterKey, salt, []byte("authentication"), 32)
+func HKDF(algorithm HashAlgorithm, secret, salt, info []byte, length int) ([]byte, error) {
+	if !algorithm.isCryptographic() || length < 1 {
+		return nil, ErrInvalidKDFParams
+	}
+	key := make([]byte, length)
+	if _, err := io.ReadFull(hkdf.New(algorithm.New, secret, salt, info), key); err != nil {
+		return nil, err // too large length
+	}
+	return key, nil
+}
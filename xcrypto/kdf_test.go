@@ -0,0 +1,247 @@
+package xcrypto
+
+import (
+	"github.com/Aoi-hosizora/ahlib/xtesting"
+	"strings"
+	"testing"
+)
+
+func TestArgon2id(t *testing.T) {
+	password := []byte("password")
+	params := Argon2idParams{Memory: 64, Iterations: 1, Parallelism: 1, SaltLength: 8, KeyLength: 24}
+
+	// https://github.com/golang/crypto/blob/master/argon2/argon2_test.go
+	key, err := Argon2idKey(password, []byte("somesalt"), params)
+	xtesting.Nil(t, err)
+	xtesting.Equal(t, HexEncodeToString(key), "655ad15eac652dc59f7170a7332bf49b8469be1fdb9c28bb")
+	encoded := "$argon2id$v=19$m=64,t=1,p=1$c29tZXNhbHQ$ZVrRXqxlLcWfcXCnMyv0m4Rpvh/bnCi7"
+	ok, err := Argon2idCompare(password, encoded)
+	xtesting.Nil(t, err)
+	xtesting.True(t, ok)
+	ok, err = Argon2idCompare([]byte("Password"), encoded)
+	xtesting.Nil(t, err)
+	xtesting.False(t, ok)
+
+	for _, pass := range []string{"", "test", "hello world", "测试 テス тест"} {
+		encoded, err := Argon2idHash([]byte(pass), params)
+		xtesting.Nil(t, err)
+		xtesting.True(t, strings.HasPrefix(encoded, "$argon2id$v=19$m=64,t=1,p=1$"))
+		encoded2, _ := Argon2idHash([]byte(pass), params)
+		xtesting.NotEqual(t, encoded, encoded2) // random salt
+		ok, err := Argon2idCompare([]byte(pass), encoded)
+		xtesting.Nil(t, err)
+		xtesting.True(t, ok)
+		ok, err = Argon2idCompare([]byte("fake password"), encoded)
+		xtesting.Nil(t, err)
+		xtesting.False(t, ok)
+	}
+	xtesting.Equal(t, DefaultArgon2idParams.Memory, uint32(64*1024))
+	encoded, err = Argon2idHashWithDefaultParams(password)
+	xtesting.Nil(t, err)
+	xtesting.True(t, strings.HasPrefix(encoded, "$argon2id$v=19$m=65536,t=3,p=4$"))
+
+	// errors
+	for _, p := range []Argon2idParams{
+		{Memory: 64, Iterations: 0, Parallelism: 1, SaltLength: 8, KeyLength: 24},
+		{Memory: 64, Iterations: 1, Parallelism: 0, SaltLength: 8, KeyLength: 24},
+		{Memory: 7, Iterations: 1, Parallelism: 1, SaltLength: 8, KeyLength: 24},
+		{Memory: 64, Iterations: 1, Parallelism: 1, SaltLength: 8, KeyLength: 0},
+		{Memory: 64, Iterations: 1, Parallelism: 1, SaltLength: 0, KeyLength: 24},
+	} {
+		_, err := Argon2idHash(password, p)
+		xtesting.Equal(t, err, ErrInvalidKDFParams)
+	}
+	_, err = Argon2idKey(password, nil, Argon2idParams{})
+	xtesting.Equal(t, err, ErrInvalidKDFParams)
+	for _, invalid := range []string{
+		"",
+		"$argon2id$v=19$m=64,t=1,p=1$c29tZXNhbHQ",
+		"argon2id$v=19$m=64,t=1,p=1$c29tZXNhbHQ$ZVrRXqxlLcWfcXCnMyv0m4Rpvh/bnCi7$",
+		"$argon2i$v=19$m=64,t=1,p=1$c29tZXNhbHQ$ZVrRXqxlLcWfcXCnMyv0m4Rpvh/bnCi7",
+		"$argon2id$v=16$m=64,t=1,p=1$c29tZXNhbHQ$ZVrRXqxlLcWfcXCnMyv0m4Rpvh/bnCi7",
+		"$argon2id$v=19$m=64,t=1$c29tZXNhbHQ$ZVrRXqxlLcWfcXCnMyv0m4Rpvh/bnCi7",
+		"$argon2id$v=19$m=64,t=1,p=1,x=1$c29tZXNhbHQ$ZVrRXqxlLcWfcXCnMyv0m4Rpvh/bnCi7",
+		"$argon2id$v=19$m=64,t=0,p=1$c29tZXNhbHQ$ZVrRXqxlLcWfcXCnMyv0m4Rpvh/bnCi7",
+		"$argon2id$v=19$m=64,t=1,p=1$c29tZXNhbHQ=$ZVrRXqxlLcWfcXCnMyv0m4Rpvh/bnCi7",
+		"$argon2id$v=19$m=64,t=1,p=1$c29tZXNhbHQ$!",
+		"$argon2id$v=19$m=64,t=1,p=1$c29tZXNhbHQ$",
+		"$argon2id$v=19$m=4294967295,t=1,p=1$c29tZXNhbHQ$ZVrRXqxlLcWfcXCnMyv0m4Rpvh/bnCi7",
+		"$argon2id$v=19$m=64,t=4294967295,p=1$c29tZXNhbHQ$ZVrRXqxlLcWfcXCnMyv0m4Rpvh/bnCi7",
+		"$argon2id$v=19$m=2048,t=1,p=255$c29tZXNhbHQ$ZVrRXqxlLcWfcXCnMyv0m4Rpvh/bnCi7",
+		"$argon2id$v=19$m=64,t=1,p=1$c29tZXNhbHQ$" + strings.Repeat("A", 1368),
+	} {
+		ok, err := Argon2idCompare(password, invalid)
+		xtesting.False(t, ok)
+		xtesting.Equal(t, err, ErrInvalidEncodedHash)
+	}
+}
+
+func TestScrypt(t *testing.T) {
+	password := []byte("password")
+	params := ScryptParams{N: 1024, R: 8, P: 1, SaltLength: 8, KeyLength: 32}
+
+	// python3 -c "import hashlib; print(hashlib.scrypt(b'password', salt=b'somesalt', n=1024, r=8, p=1, dklen=32).hex())"
+	key, err := ScryptKey(password, []byte("somesalt"), params)
+	xtesting.Nil(t, err)
+	xtesting.Equal(t, Base64EncodeToString(key), "wdXoWEig5T693O7BJbufEPRk+qarG40BYOh1xe9tMAc=")
+	encoded := "$scrypt$ln=10,r=8,p=1$c29tZXNhbHQ$wdXoWEig5T693O7BJbufEPRk+qarG40BYOh1xe9tMAc"
+	ok, err := ScryptCompare(password, encoded)
+	xtesting.Nil(t, err)
+	xtesting.True(t, ok)
+	ok, err = ScryptCompare([]byte("Password"), encoded)
+	xtesting.Nil(t, err)
+	xtesting.False(t, ok)
+
+	for _, pass := range []string{"", "test", "hello world", "测试 テス тест"} {
+		encoded, err := ScryptHash([]byte(pass), params)
+		xtesting.Nil(t, err)
+		xtesting.True(t, strings.HasPrefix(encoded, "$scrypt$ln=10,r=8,p=1$"))
+		ok, err := ScryptCompare([]byte(pass), encoded)
+		xtesting.Nil(t, err)
+		xtesting.True(t, ok)
+		ok, err = ScryptCompare([]byte("fake password"), encoded)
+		xtesting.Nil(t, err)
+		xtesting.False(t, ok)
+	}
+	encoded, err = ScryptHashWithDefaultParams(password)
+	xtesting.Nil(t, err)
+	xtesting.True(t, strings.HasPrefix(encoded, "$scrypt$ln=15,r=8,p=1$"))
+
+	// errors
+	for _, p := range []ScryptParams{
+		{N: 1, R: 8, P: 1, SaltLength: 8, KeyLength: 32},
+		{N: 1000, R: 8, P: 1, SaltLength: 8, KeyLength: 32},
+		{N: 1024, R: 0, P: 1, SaltLength: 8, KeyLength: 32},
+		{N: 1024, R: 8, P: 0, SaltLength: 8, KeyLength: 32},
+		{N: 1024, R: 1 << 15, P: 1 << 15, SaltLength: 8, KeyLength: 32},
+		{N: 1024, R: 8, P: 1, SaltLength: 8, KeyLength: 0},
+		{N: 1024, R: 8, P: 1, SaltLength: 0, KeyLength: 32},
+	} {
+		_, err := ScryptHash(password, p)
+		xtesting.Equal(t, err, ErrInvalidKDFParams)
+	}
+	_, err = ScryptKey(password, nil, ScryptParams{})
+	xtesting.Equal(t, err, ErrInvalidKDFParams)
+	for _, invalid := range []string{
+		"",
+		"$scrypt$ln=10,r=8,p=1$c29tZXNhbHQ",
+		"$scrypt2$ln=10,r=8,p=1$c29tZXNhbHQ$wdXoWEig5T693O7BJbufEPRk+qarG40BYOh1xe9tMAc",
+		"$scrypt$n=1024,r=8,p=1$c29tZXNhbHQ$wdXoWEig5T693O7BJbufEPRk+qarG40BYOh1xe9tMAc",
+		"$scrypt$ln=0,r=8,p=1$c29tZXNhbHQ$wdXoWEig5T693O7BJbufEPRk+qarG40BYOh1xe9tMAc",
+		"$scrypt$ln=63,r=8,p=1$c29tZXNhbHQ$wdXoWEig5T693O7BJbufEPRk+qarG40BYOh1xe9tMAc",
+		"$scrypt$ln=10,r=0,p=1$c29tZXNhbHQ$wdXoWEig5T693O7BJbufEPRk+qarG40BYOh1xe9tMAc",
+		"$scrypt$ln=10,r=8,p=1$!$wdXoWEig5T693O7BJbufEPRk+qarG40BYOh1xe9tMAc",
+		"$scrypt$ln=50,r=8,p=1$c29tZXNhbHQ$wdXoWEig5T693O7BJbufEPRk+qarG40BYOh1xe9tMAc",
+		"$scrypt$ln=10,r=1024,p=1$c29tZXNhbHQ$wdXoWEig5T693O7BJbufEPRk+qarG40BYOh1xe9tMAc",
+		"$scrypt$ln=10,r=8,p=1024$c29tZXNhbHQ$wdXoWEig5T693O7BJbufEPRk+qarG40BYOh1xe9tMAc",
+	} {
+		ok, err := ScryptCompare(password, invalid)
+		xtesting.False(t, ok)
+		xtesting.Equal(t, err, ErrInvalidEncodedHash)
+	}
+}
+
+func TestPBKDF2(t *testing.T) {
+	password := []byte("password")
+	params := PBKDF2Params{Hash: HashSHA256, Iterations: 1000, SaltLength: 8, KeyLength: 32}
+
+	// python3 -c "import hashlib; print(hashlib.pbkdf2_hmac('sha256', b'password', b'somesalt', 1000, 32).hex())"
+	key, err := PBKDF2Key(password, []byte("somesalt"), params)
+	xtesting.Nil(t, err)
+	xtesting.Equal(t, Base64EncodeToString(key), "j4Aa14inUtOh7Sg/D7hH54ohymuHNQD4+ccfhepGWAY=")
+	for _, tc := range []struct {
+		give string
+		want bool
+	}{
+		{"$pbkdf2-sha256$i=1000$c29tZXNhbHQ$j4Aa14inUtOh7Sg/D7hH54ohymuHNQD4+ccfhepGWAY", true},
+		{"$pbkdf2-sha512$i=1000$c29tZXNhbHQ$pArTsT8AahzxmI5OZcxKNw2o4l9qiKwc5zbWR8bo8900Q7MYRcodIEijxiztL4hDlWTfVLTSRiLheMi39WU5Yw", true},
+		{"$pbkdf2-sha512$i=1001$c29tZXNhbHQ$pArTsT8AahzxmI5OZcxKNw2o4l9qiKwc5zbWR8bo8900Q7MYRcodIEijxiztL4hDlWTfVLTSRiLheMi39WU5Yw", false},
+	} {
+		ok, err := PBKDF2Compare(password, tc.give)
+		xtesting.Nil(t, err)
+		xtesting.Equal(t, ok, tc.want)
+	}
+
+	for _, algorithm := range []HashAlgorithm{HashSHA224, HashSHA256, HashSHA512, HashSHA512_256, HashSHA3_256} {
+		params.Hash = algorithm
+		encoded, err := PBKDF2Hash(password, params)
+		xtesting.Nil(t, err)
+		ok, err := PBKDF2Compare(password, encoded)
+		xtesting.Nil(t, err)
+		xtesting.True(t, ok)
+		ok, err = PBKDF2Compare([]byte("fake password"), encoded)
+		xtesting.Nil(t, err)
+		xtesting.False(t, ok)
+	}
+	xtesting.Equal(t, pbkdf2ID(HashSHA512_256), "pbkdf2-sha512-256")
+	xtesting.Equal(t, pbkdf2ID(HashSHA3_256), "pbkdf2-sha3-256")
+	xtesting.Equal(t, DefaultPBKDF2Params.Iterations, 600000)
+	encoded, err := PBKDF2HashWithDefaultParams(password)
+	xtesting.Nil(t, err)
+	xtesting.True(t, strings.HasPrefix(encoded, "$pbkdf2-sha256$i=600000$"))
+
+	// errors
+	for _, p := range []PBKDF2Params{
+		{Hash: 0, Iterations: 1000, SaltLength: 8, KeyLength: 32},
+		{Hash: HashCRC32, Iterations: 1000, SaltLength: 8, KeyLength: 32},
+		{Hash: HashMD4, Iterations: 1000, SaltLength: 8, KeyLength: 32},
+		{Hash: HashMD5, Iterations: 1000, SaltLength: 8, KeyLength: 32},
+		{Hash: HashSHA1, Iterations: 1000, SaltLength: 8, KeyLength: 32},
+		{Hash: HashSHA256, Iterations: 0, SaltLength: 8, KeyLength: 32},
+		{Hash: HashSHA256, Iterations: 1000, SaltLength: 8, KeyLength: 0},
+		{Hash: HashSHA256, Iterations: 1000, SaltLength: 0, KeyLength: 32},
+	} {
+		_, err := PBKDF2Hash(password, p)
+		xtesting.Equal(t, err, ErrInvalidKDFParams)
+	}
+	_, err = PBKDF2Key(password, nil, PBKDF2Params{})
+	xtesting.Equal(t, err, ErrInvalidKDFParams)
+	for _, invalid := range []string{
+		"",
+		"$pbkdf2-sha256$i=1000$c29tZXNhbHQ",
+		"$pbkdf2-sha255$i=1000$c29tZXNhbHQ$j4Aa14inUtOh7Sg/D7hH54ohymuHNQD4+ccfhepGWAY",
+		"$pbkdf2-crc32$i=1000$c29tZXNhbHQ$j4Aa14inUtOh7Sg/D7hH54ohymuHNQD4+ccfhepGWAY",
+		"$pbkdf2-sha256$i=0$c29tZXNhbHQ$j4Aa14inUtOh7Sg/D7hH54ohymuHNQD4+ccfhepGWAY",
+		"$pbkdf2-sha256$rounds=1000$c29tZXNhbHQ$j4Aa14inUtOh7Sg/D7hH54ohymuHNQD4+ccfhepGWAY",
+		"$pbkdf2-sha256$i=1000$c29tZXNhbHQ$j4Aa14inUtOh7Sg/D7hH54ohymuHNQD4+ccfhepGWAY=",
+		"$pbkdf2-md5$i=1000$c29tZXNhbHQ$j4Aa14inUtOh7Sg/D7hH54ohymuHNQD4+ccfhepGWAY",
+		"$pbkdf2-sha1$i=1000$c29tZXNhbHQ$j4Aa14inUtOh7Sg/D7hH54ohymuHNQD4+ccfhepGWAY",
+		"$pbkdf2-sha256$i=2147483647$c29tZXNhbHQ$j4Aa14inUtOh7Sg/D7hH54ohymuHNQD4+ccfhepGWAY",
+	} {
+		ok, err := PBKDF2Compare(password, invalid)
+		xtesting.False(t, ok)
+		xtesting.Equal(t, err, ErrInvalidEncodedHash)
+	}
+}
+
+func TestHKDF(t *testing.T) {
+	// https://datatracker.ietf.org/doc/html/rfc5869#appendix-A.1
+	ikm, _ := HexDecodeFromString("0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b")
+	salt, _ := HexDecodeFromString("000102030405060708090a0b0c")
+	info, _ := HexDecodeFromString("f0f1f2f3f4f5f6f7f8f9")
+	key, err := HKDF(HashSHA256, ikm, salt, info, 42)
+	xtesting.Nil(t, err)
+	xtesting.Equal(t, HexEncodeToString(key), "3cb25f25faacd57a90434f64d0362f2a2d2d0a90cf1a5a4c5db02d56ecc4c5bf34007208d5b887185865")
+
+	// https://datatracker.ietf.org/doc/html/rfc5869#appendix-A.3
+	key, err = HKDF(HashSHA256, ikm, nil, nil, 42)
+	xtesting.Nil(t, err)
+	xtesting.Equal(t, HexEncodeToString(key), "8da4e775a563c18f715f802a063c5a31b8a11f5c5ee1879ec3454e5f3c738d2d9d201395faa4b61a96c8")
+
+	key1, _ := HKDF(HashSHA512, ikm, salt, []byte("encryption"), 32)
+	key2, _ := HKDF(HashSHA512, ikm, salt, []byte("authentication"), 32)
+	xtesting.NotEqual(t, key1, key2)
+
+	for _, tc := range []struct {
+		giveAlgorithm HashAlgorithm
+		giveLength    int
+	}{
+		{0, 32},
+		{HashFNV32, 32},
+		{HashSHA256, 0},
+		{HashSHA256, 255*32 + 1},
+	} {
+		_, err := HKDF(tc.giveAlgorithm, ikm, salt, info, tc.giveLength)
+		xtesting.NotNil(t, err)
+	}
+}
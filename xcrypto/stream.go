@@ -0,0 +1,366 @@
+package xcrypto
+
+import (
+	"bytes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"golang.org/x/crypto/hkdf"
+	"io"
+	"math"
+)
+
+// ====================
+// streaming encryption
+// ====================
+
+// StreamAlgorithm represents the AEAD algorithm used by EncryptWriter and DecryptReader.
+type StreamAlgorithm uint8
+
+const (
+	StreamAESGCM            StreamAlgorithm = iota + 1 // AES-GCM, the key size must be 16, 24 or 32
+	StreamXChaCha20Poly1305                            // XChaCha20-Poly1305, the key size must be 32
+)
+
+const (
+	DefaultStreamChunkSize = 64 * 1024        // The default plaintext size of each chunk.
+	MaxStreamChunkSize     = 16 * 1024 * 1024 // The maximum plaintext size of each chunk.
+	StreamVersion          = 1                // The current version of stream format, which is written in the header.
+)
+
+var (
+	// ErrInvalidStreamHeader represents the stream header is malformed, or the version or algorithm is not supported.
+	ErrInvalidStreamHeader = errors.New("xcrypto: invalid stream header")
+
+	// ErrStreamTruncated represents the stream ends before the final chunk, which means the stream is truncated.
+	ErrStreamTruncated = errors.New("xcrypto: stream truncated")
+
+	// ErrStreamClosed represents the EncryptWriter has been closed.
+	ErrStreamClosed = errors.New("xcrypto: write to closed stream")
+)
+
+var (
+	errInvalidStreamAlgorithm = errors.New("xcrypto: invalid stream algorithm")
+	errInvalidChunkSize       = errors.New("xcrypto: invalid chunk size")
+	errStreamTooLarge         = errors.New("xcrypto: stream too large")
+)
+
+const (
+	streamSaltSize  = 32
+	streamFixedSize = 10 // magic (4) + version (1) + algorithm (1) + chunk size (4)
+)
+
+// _streamMagic is the magic bytes at the beginning of the stream header.
+var _streamMagic = []byte("XCST")
+
+// The stream format is based on STREAM construction, described in https://eprint.iacr.org/2015/189.pdf, which is in the form of
+// "header + chunk_0 + chunk_1 + ... + chunk_n".
+//
+// 1. The header is "magic + version + algorithm + chunk size (big endian uint32) + salt + nonce prefix", and it is authenticated as the
+// additional data of each chunk.
+// 2. The key used by AEAD is derived from given key and random salt using HKDF-SHA256, so the same key can be safely reused across streams.
+// 3. The nonce of each chunk is "nonce prefix + counter (big endian uint32) + final flag (0 or 1)", so reordering, dropping or appending
+// chunks, and truncating at chunk boundary can be detected.
+
+// newStreamAEAD derives a subkey from given key and salt, and creates the cipher.AEAD for given algorithm.
+func newStreamAEAD(algorithm StreamAlgorithm, key, salt, info []byte) (cipher.AEAD, error) {
+	subkey := make([]byte, len(key))
+	if _, err := io.ReadFull(hkdf.New(sha256.New, key, salt, info), subkey); err != nil {
+		return nil, err
+	}
+	switch algorithm {
+	case StreamAESGCM:
+		return newAESGCM(subkey)
+	case StreamXChaCha20Poly1305:
+		return newXChaCha20Poly1305(subkey)
+	default:
+		return nil, errInvalidStreamAlgorithm
+	}
+}
+
+// streamNonceSize returns the nonce size of given algorithm, returns 0 for invalid algorithm.
+func streamNonceSize(algorithm StreamAlgorithm) int {
+	switch algorithm {
+	case StreamAESGCM:
+		return AESGCMNonceSize
+	case StreamXChaCha20Poly1305:
+		return XChaCha20Poly1305NonceSize
+	default:
+		return 0
+	}
+}
+
+// streamState represents the shared state of EncryptWriter and DecryptReader.
+type streamState struct {
+	aead    cipher.AEAD
+	header  []byte
+	nonce   []byte
+	counter uint32
+}
+
+// chunkNonce fills and returns the nonce of current chunk.
+func (s *streamState) chunkNonce(final bool) []byte {
+	n := len(s.nonce)
+	binary.BigEndian.PutUint32(s.nonce[n-5:n-1], s.counter)
+	s.nonce[n-1] = 0
+	if final {
+		s.nonce[n-1] = 1
+	}
+	return s.nonce
+}
+
+// EncryptWriter is an io.WriteCloser which encrypts data written to it in chunks, and writes the encrypted stream to the underlying
+// io.Writer. Note that Close must be called to write the final chunk, otherwise the stream will be treated as truncated when decrypting.
+type EncryptWriter struct {
+	w         io.Writer
+	state     *streamState
+	chunkSize int
+	buf       []byte
+	out       []byte
+	closed    bool
+	err       error
+}
+
+// NewEncryptWriter creates an EncryptWriter using given StreamAlgorithm, key and plaintext chunk size, and writes the stream header to
+// given io.Writer immediately. Zero or negative chunkSize means DefaultStreamChunkSize, and it must not be larger than MaxStreamChunkSize.
+// Example:
+// 	w, err := xcrypto.NewEncryptWriter(dst, xcrypto.StreamXChaCha20Poly1305, key, 0)
+// 	_, err = io.Copy(w, src)
+// 	err = w.Close() // write the final chunk
+func NewEncryptWriter(w io.Writer, algorithm StreamAlgorithm, key []byte, chunkSize int) (*EncryptWriter, error) {
+	if chunkSize <= 0 {
+		chunkSize = DefaultStreamChunkSize
+	}
+	if chunkSize > MaxStreamChunkSize {
+		return nil, errInvalidChunkSize
+	}
+	nonceSize := streamNonceSize(algorithm)
+	if nonceSize == 0 {
+		return nil, errInvalidStreamAlgorithm
+	}
+
+	header := make([]byte, streamFixedSize+streamSaltSize+nonceSize-5)
+	copy(header, _streamMagic)
+	header[4], header[5] = StreamVersion, byte(algorithm)
+	binary.BigEndian.PutUint32(header[6:10], uint32(chunkSize))
+	if _, err := rand.Read(header[streamFixedSize:]); err != nil {
+		return nil, err
+	}
+	salt := header[streamFixedSize : streamFixedSize+streamSaltSize]
+	aead, err := newStreamAEAD(algorithm, key, salt, header[:streamFixedSize])
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(header); err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, nonceSize)
+	copy(nonce, header[streamFixedSize+streamSaltSize:])
+	return &EncryptWriter{
+		w:         w,
+		state:     &streamState{aead: aead, header: header, nonce: nonce},
+		chunkSize: chunkSize,
+		buf:       make([]byte, 0, chunkSize),
+	}, nil
+}
+
+// Write implements io.Writer, the data is buffered and encrypted chunk by chunk.
+func (e *EncryptWriter) Write(p []byte) (int, error) {
+	if e.closed {
+		return 0, ErrStreamClosed
+	}
+	if e.err != nil {
+		return 0, e.err
+	}
+	total := 0
+	for len(p) > 0 {
+		if len(e.buf) == e.chunkSize {
+			// only flush a full chunk when there is more data, because the last chunk must be marked as final
+			if e.err = e.flush(false); e.err != nil {
+				return total, e.err
+			}
+		}
+		n := e.chunkSize - len(e.buf)
+		if n > len(p) {
+			n = len(p)
+		}
+		e.buf = append(e.buf, p[:n]...)
+		p = p[n:]
+		total += n
+	}
+	return total, nil
+}
+
+// Close encrypts and writes the final chunk, note that the underlying io.Writer will not be closed.
+func (e *EncryptWriter) Close() error {
+	if e.closed {
+		return nil
+	}
+	e.closed = true
+	if e.err != nil {
+		return e.err
+	}
+	e.err = e.flush(true)
+	return e.err
+}
+
+// flush encrypts buffered data as a chunk and writes it to the underlying io.Writer.
+func (e *EncryptWriter) flush(final bool) error {
+	s := e.state
+	if !final && s.counter == math.MaxUint32 {
+		return errStreamTooLarge
+	}
+	e.out = s.aead.Seal(e.out[:0], s.chunkNonce(final), e.buf, s.header)
+	if _, err := e.w.Write(e.out); err != nil {
+		return err
+	}
+	s.counter++
+	e.buf = e.buf[:0]
+	return nil
+}
+
+// DecryptReader is an io.Reader which decrypts and authenticates the stream generated by EncryptWriter chunk by chunk. Note that the
+// data returned by Read is authenticated, but the stream may still be truncated or tampered later, so the data should not be treated as
+// complete until io.EOF is returned.
+type DecryptReader struct {
+	r       io.Reader
+	state   *streamState
+	buf     []byte // encrypted chunk, with one more byte to detect the final chunk
+	pending int
+	plain   []byte
+	unread  []byte
+	done    bool
+	err     error
+}
+
+// NewDecryptReader creates a DecryptReader using given key, and reads the stream header from given io.Reader immediately. The algorithm
+// and chunk size are read from the header, ErrInvalidStreamHeader will be returned if the header is invalid.
+// Example:
+// 	r, err := xcrypto.NewDecryptReader(src, key)
+// 	_, err = io.Copy(dst, r) // ErrAuthenticationFailed or ErrStreamTruncated may be returned
+func NewDecryptReader(r io.Reader, key []byte) (*DecryptReader, error) {
+	fixed := make([]byte, streamFixedSize)
+	if _, err := io.ReadFull(r, fixed); err != nil {
+		return nil, headerError(err)
+	}
+	if !bytes.Equal(fixed[:4], _streamMagic) || fixed[4] != StreamVersion {
+		return nil, ErrInvalidStreamHeader
+	}
+	algorithm := StreamAlgorithm(fixed[5])
+	chunkSize := int(binary.BigEndian.Uint32(fixed[6:10]))
+	nonceSize := streamNonceSize(algorithm)
+	if nonceSize == 0 || chunkSize <= 0 || chunkSize > MaxStreamChunkSize {
+		return nil, ErrInvalidStreamHeader
+	}
+
+	header := make([]byte, streamFixedSize+streamSaltSize+nonceSize-5)
+	copy(header, fixed)
+	if _, err := io.ReadFull(r, header[streamFixedSize:]); err != nil {
+		return nil, headerError(err)
+	}
+	salt := header[streamFixedSize : streamFixedSize+streamSaltSize]
+	aead, err := newStreamAEAD(algorithm, key, salt, fixed)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, nonceSize)
+	copy(nonce, header[streamFixedSize+streamSaltSize:])
+	return &DecryptReader{
+		r:     r,
+		state: &streamState{aead: aead, header: header, nonce: nonce},
+		buf:   make([]byte, chunkSize+aead.Overhead()+1),
+		plain: make([]byte, 0, chunkSize),
+	}, nil
+}
+
+// headerError converts the error when reading stream header.
+func headerError(err error) error {
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return ErrInvalidStreamHeader
+	}
+	return err
+}
+
+// Read implements io.Reader, io.EOF will be returned only after the final chunk is decrypted and authenticated.
+func (d *DecryptReader) Read(p []byte) (int, error) {
+	for len(d.unread) == 0 {
+		if d.err != nil {
+			return 0, d.err
+		}
+		if d.done {
+			return 0, io.EOF
+		}
+		d.err = d.readChunk()
+	}
+	n := copy(p, d.unread)
+	d.unread = d.unread[n:]
+	return n, nil
+}
+
+// readChunk reads, decrypts and authenticates the next chunk.
+func (d *DecryptReader) readChunk() error {
+	s := d.state
+	n, err := io.ReadFull(d.r, d.buf[d.pending:])
+	n += d.pending
+	final := false
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		final = true
+	} else if err != nil {
+		return err
+	}
+	if !final {
+		n = len(d.buf) - 1
+	} else if n == 0 {
+		return ErrStreamTruncated
+	}
+
+	chunk := d.buf[:n]
+	plain, err := s.aead.Open(d.plain[:0], s.chunkNonce(final), chunk, s.header)
+	if err != nil {
+		if final {
+			if _, err := s.aead.Open(nil, s.chunkNonce(false), chunk, s.header); err == nil {
+				return ErrStreamTruncated // a non-final chunk is the last one
+			}
+		}
+		return ErrAuthenticationFailed
+	}
+	if final {
+		d.done = true
+	} else {
+		if s.counter == math.MaxUint32 {
+			return errStreamTooLarge
+		}
+		s.counter++
+		d.buf[0] = d.buf[len(d.buf)-1]
+		d.pending = 1
+	}
+	d.unread = plain
+	return nil
+}
+
+// EncryptStream reads all data from src, encrypts it using EncryptWriter, and writes the encrypted stream to dst.
+func EncryptStream(dst io.Writer, src io.Reader, algorithm StreamAlgorithm, key []byte, chunkSize int) error {
+	w, err := NewEncryptWriter(dst, algorithm, key, chunkSize)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(w, src); err != nil {
+		return err
+	}
+	return w.Close()
+}
+
+// DecryptStream reads the encrypted stream from src, decrypts it using DecryptReader, and writes the plaintext to dst. Note that the
+// plaintext written to dst before error returned should be discarded.
+func DecryptStream(dst io.Writer, src io.Reader, key []byte) error {
+	r, err := NewDecryptReader(src, key)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(dst, r)
+	return err
+}
@@ -0,0 +1,214 @@
+package xcrypto
+
+import (
+	"bytes"
+	"errors"
+	"github.com/Aoi-hosizora/ahlib/xtesting"
+	"io"
+	"io/ioutil"
+	"math"
+	"testing"
+	"testing/iotest"
+)
+
+func TestStream(t *testing.T) {
+	aesKey, _ := GenerateKey(AES128KeySize)
+	chachaKey, _ := GenerateKey(XChaCha20Poly1305KeySize)
+	data, _ := GenerateKey(1000)
+
+	for _, tc := range []struct {
+		giveAlgorithm StreamAlgorithm
+		giveKey       []byte
+		wantHeader    int
+	}{
+		{StreamAESGCM, aesKey, 10 + 32 + 7},
+		{StreamXChaCha20Poly1305, chachaKey, 10 + 32 + 19},
+	} {
+		for _, chunkSize := range []int{1, 16, 100, 999, 1000, 1001} {
+			for _, size := range []int{0, 1, 15, 16, 17, 100, 999, 1000} {
+				plaintext := data[:size]
+				chunks := (size + chunkSize - 1) / chunkSize
+				if chunks == 0 {
+					chunks = 1 // the final chunk is empty
+				}
+
+				// one write
+				buf := &bytes.Buffer{}
+				xtesting.Nil(t, EncryptStream(buf, bytes.NewReader(plaintext), tc.giveAlgorithm, tc.giveKey, chunkSize))
+				xtesting.Equal(t, buf.Len(), tc.wantHeader+size+chunks*16)
+				encrypted := buf.Bytes()
+				out := &bytes.Buffer{}
+				xtesting.Nil(t, DecryptStream(out, bytes.NewReader(encrypted), tc.giveKey))
+				xtesting.Equal(t, out.Bytes(), plaintext)
+
+				// byte by byte
+				buf = &bytes.Buffer{}
+				w, err := NewEncryptWriter(buf, tc.giveAlgorithm, tc.giveKey, chunkSize)
+				xtesting.Nil(t, err)
+				for i := 0; i < size; i++ {
+					n, err := w.Write(plaintext[i : i+1])
+					xtesting.Nil(t, err)
+					xtesting.Equal(t, n, 1)
+				}
+				xtesting.Nil(t, w.Close())
+				xtesting.Nil(t, w.Close())
+				xtesting.NotEqual(t, buf.Bytes(), encrypted) // random salt and nonce
+				r, err := NewDecryptReader(iotest.OneByteReader(buf), tc.giveKey)
+				xtesting.Nil(t, err)
+				decrypted, err := ioutil.ReadAll(iotest.OneByteReader(r))
+				xtesting.Nil(t, err)
+				xtesting.Equal(t, decrypted, plaintext)
+
+				// truncated at chunk boundary
+				if chunks > 1 {
+					truncated := encrypted[:len(encrypted)-(size-(chunks-1)*chunkSize)-16] // drop the final chunk
+					err := DecryptStream(ioutil.Discard, bytes.NewReader(truncated), tc.giveKey)
+					xtesting.Equal(t, err, ErrStreamTruncated)
+				}
+			}
+		}
+
+		// default chunk size
+		buf := &bytes.Buffer{}
+		w, err := NewEncryptWriter(buf, tc.giveAlgorithm, tc.giveKey, 0)
+		xtesting.Nil(t, err)
+		big := bytes.Repeat(data, 200) // 200000 bytes
+		n, err := w.Write(big)
+		xtesting.Nil(t, err)
+		xtesting.Equal(t, n, len(big))
+		xtesting.Nil(t, w.Close())
+		xtesting.Equal(t, buf.Len(), tc.wantHeader+len(big)+4*16)
+		out := &bytes.Buffer{}
+		xtesting.Nil(t, DecryptStream(out, buf, tc.giveKey))
+		xtesting.Equal(t, out.Bytes(), big)
+	}
+}
+
+func TestStreamTampered(t *testing.T) {
+	key, _ := GenerateKey(AES256KeySize)
+	otherKey, _ := GenerateKey(AES256KeySize)
+	data, _ := GenerateKey(100)
+	buf := &bytes.Buffer{}
+	xtesting.Nil(t, EncryptStream(buf, bytes.NewReader(data), StreamAESGCM, key, 16))
+	encrypted := buf.Bytes()
+	headerSize, chunk := 10+32+7, 16+16
+	clone := func() []byte { return append([]byte(nil), encrypted...) }
+
+	for _, tc := range []struct {
+		name    string
+		give    []byte
+		giveKey []byte
+		wantErr error
+	}{
+		{"wrong key", encrypted, otherKey, ErrAuthenticationFailed},
+		{"tamper chunk", func() []byte { bs := clone(); bs[headerSize+chunk+1] ^= 1; return bs }(), key, ErrAuthenticationFailed},
+		{"tamper salt", func() []byte { bs := clone(); bs[10] ^= 1; return bs }(), key, ErrAuthenticationFailed},
+		{"tamper nonce prefix", func() []byte { bs := clone(); bs[headerSize-1] ^= 1; return bs }(), key, ErrAuthenticationFailed},
+		{"tamper chunk size", func() []byte { bs := clone(); bs[9] = 17; return bs }(), key, ErrAuthenticationFailed},
+		{"swap chunks", func() []byte {
+			bs := clone()
+			c0, c1 := append([]byte(nil), bs[headerSize:headerSize+chunk]...), bs[headerSize+chunk:headerSize+2*chunk]
+			copy(bs[headerSize:], c1)
+			copy(bs[headerSize+chunk:], c0)
+			return bs
+		}(), key, ErrAuthenticationFailed},
+		{"drop chunk", append(clone()[:headerSize], encrypted[headerSize+chunk:]...), key, ErrAuthenticationFailed},
+		{"append data", append(clone(), 0), key, ErrAuthenticationFailed},
+		{"truncate final", encrypted[:len(encrypted)-1], key, ErrAuthenticationFailed},
+		{"truncate boundary", encrypted[:headerSize+2*chunk], key, ErrStreamTruncated},
+		{"header only", encrypted[:headerSize], key, ErrStreamTruncated},
+		{"short header", encrypted[:headerSize-1], key, ErrInvalidStreamHeader},
+		{"short fixed header", encrypted[:5], key, ErrInvalidStreamHeader},
+		{"empty", nil, key, ErrInvalidStreamHeader},
+		{"bad magic", func() []byte { bs := clone(); bs[0] = 'x'; return bs }(), key, ErrInvalidStreamHeader},
+		{"bad version", func() []byte { bs := clone(); bs[4] = 2; return bs }(), key, ErrInvalidStreamHeader},
+		{"bad algorithm", func() []byte { bs := clone(); bs[5] = 3; return bs }(), key, ErrInvalidStreamHeader},
+		{"zero chunk size", func() []byte { bs := clone(); copy(bs[6:10], []byte{0, 0, 0, 0}); return bs }(), key, ErrInvalidStreamHeader},
+		{"huge chunk size", func() []byte { bs := clone(); copy(bs[6:10], []byte{0xff, 0, 0, 0}); return bs }(), key, ErrInvalidStreamHeader},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			out := &bytes.Buffer{}
+			err := DecryptStream(out, bytes.NewReader(tc.give), tc.giveKey)
+			xtesting.Equal(t, err, tc.wantErr)
+			xtesting.True(t, bytes.HasPrefix(data, out.Bytes()))
+		})
+	}
+
+	// sticky error
+	r, err := NewDecryptReader(bytes.NewReader(encrypted), otherKey)
+	xtesting.Nil(t, err)
+	_, err = r.Read(make([]byte, 10))
+	xtesting.Equal(t, err, ErrAuthenticationFailed)
+	_, err = r.Read(make([]byte, 10))
+	xtesting.Equal(t, err, ErrAuthenticationFailed)
+	_, err = NewDecryptReader(bytes.NewReader(encrypted), key[:31])
+	xtesting.NotNil(t, err)
+}
+
+func TestStreamErrors(t *testing.T) {
+	key, _ := GenerateKey(AES256KeySize)
+	testErr := errors.New("test")
+
+	// writer
+	_, err := NewEncryptWriter(ioutil.Discard, 0, key, 0)
+	xtesting.Equal(t, err, errInvalidStreamAlgorithm)
+	_, err = NewEncryptWriter(ioutil.Discard, StreamAESGCM, key, MaxStreamChunkSize+1)
+	xtesting.Equal(t, err, errInvalidChunkSize)
+	_, err = NewEncryptWriter(ioutil.Discard, StreamXChaCha20Poly1305, key[:16], 0)
+	xtesting.NotNil(t, err)
+	_, err = NewEncryptWriter(&errorWriter{0, testErr}, StreamAESGCM, key, 0)
+	xtesting.Equal(t, err, testErr)
+	_, err = newStreamAEAD(0, key, nil, nil)
+	xtesting.Equal(t, err, errInvalidStreamAlgorithm)
+
+	w, _ := NewEncryptWriter(&errorWriter{1, testErr}, StreamAESGCM, key, 4)
+	n, err := w.Write([]byte("0123456789"))
+	xtesting.Equal(t, n, 4)
+	xtesting.Equal(t, err, testErr)
+	_, err = w.Write([]byte("0"))
+	xtesting.Equal(t, err, testErr)
+	xtesting.Equal(t, w.Close(), testErr)
+	_, err = w.Write([]byte("0"))
+	xtesting.Equal(t, err, ErrStreamClosed)
+
+	w, _ = NewEncryptWriter(&errorWriter{1, testErr}, StreamAESGCM, key, 4)
+	xtesting.Equal(t, w.Close(), testErr)
+
+	w, _ = NewEncryptWriter(ioutil.Discard, StreamAESGCM, key, 4)
+	w.state.counter = math.MaxUint32
+	_, err = w.Write([]byte("0123456789"))
+	xtesting.Equal(t, err, errStreamTooLarge)
+
+	// reader
+	buf := &bytes.Buffer{}
+	xtesting.Nil(t, EncryptStream(buf, bytes.NewReader([]byte("0123456789")), StreamAESGCM, key, 4))
+	_, err = NewDecryptReader(io.MultiReader(bytes.NewReader(buf.Bytes()[:5]), &errorReader{testErr}), key)
+	xtesting.Equal(t, err, testErr)
+	_, err = NewDecryptReader(io.MultiReader(bytes.NewReader(buf.Bytes()[:12]), &errorReader{testErr}), key)
+	xtesting.Equal(t, err, testErr)
+	r, _ := NewDecryptReader(io.MultiReader(bytes.NewReader(buf.Bytes()[:60]), &errorReader{testErr}), key)
+	_, err = ioutil.ReadAll(r)
+	xtesting.Equal(t, err, testErr)
+	r, _ = NewDecryptReader(bytes.NewReader(buf.Bytes()), key)
+	r.state.counter = math.MaxUint32
+	_, err = ioutil.ReadAll(r)
+	xtesting.Equal(t, err, ErrAuthenticationFailed)
+
+	// helpers
+	xtesting.Equal(t, EncryptStream(ioutil.Discard, bytes.NewReader(nil), 0, key, 0), errInvalidStreamAlgorithm)
+	xtesting.Equal(t, EncryptStream(ioutil.Discard, &errorReader{testErr}, StreamAESGCM, key, 0), testErr)
+	xtesting.Equal(t, DecryptStream(ioutil.Discard, bytes.NewReader(nil), key), ErrInvalidStreamHeader)
+}
+
+type errorWriter struct {
+	okCount int
+	err     error
+}
+
+func (e *errorWriter) Write(p []byte) (int, error) {
+	if e.okCount > 0 {
+		e.okCount--
+		return len(p), nil
+	}
+	return 0, e.err
+}
@@ -238,6 +238,32 @@ func Base64DecodeFromString(data string) ([]byte, error) {
 	return Base64DecodeFromBytes(xstring.FastStob(data))
 }
 
+// Base64URLEncodeToBytes encodes bytes to url-safe base64 bytes.
+func Base64URLEncodeToBytes(data []byte) []byte {
+	enc := base64.URLEncoding // encoding/base64
+	buf := make([]byte, enc.EncodedLen(len(data)))
+	enc.Encode(buf, data)
+	return buf
+}
+
+// Base64URLEncodeToString encodes bytes to url-safe base64 string.
+func Base64URLEncodeToString(data []byte) string {
+	return xstring.FastBtos(Base64URLEncodeToBytes(data))
+}
+
+// Base64URLDecodeFromBytes decodes bytes from url-safe base64 bytes.
+func Base64URLDecodeFromBytes(data []byte) ([]byte, error) {
+	enc := base64.URLEncoding // encoding/base64
+	buf := make([]byte, enc.DecodedLen(len(data)))
+	n, err := enc.Decode(buf, data)
+	return buf[:n], err
+}
+
+// Base64URLDecodeFromString decodes bytes from url-safe base64 string.
+func Base64URLDecodeFromString(data string) ([]byte, error) {
+	return Base64URLDecodeFromBytes(xstring.FastStob(data))
+}
+
 // ====
 // pkcs
 // ====
@@ -125,6 +125,10 @@ func TestEncodeDecode(t *testing.T) {
 		{Base64EncodeToBytes, Base64EncodeToString, testBs, "dGVzdA=="},
 		{Base64EncodeToBytes, Base64EncodeToString, helloWorldBs, "aGVsbG8gd29ybGQ="},
 		{Base64EncodeToBytes, Base64EncodeToString, test2Bs, "5rWL6K+VIOODhuOCuSDRgtC10YHRgg=="},
+		{Base64URLEncodeToBytes, Base64URLEncodeToString, nil, ""},
+		{Base64URLEncodeToBytes, Base64URLEncodeToString, testBs, "dGVzdA=="},
+		{Base64URLEncodeToBytes, Base64URLEncodeToString, helloWorldBs, "aGVsbG8gd29ybGQ="},
+		{Base64URLEncodeToBytes, Base64URLEncodeToString, test2Bs, "5rWL6K-VIOODhuOCuSDRgtC10YHRgg=="},
 	} {
 		xtesting.Equal(t, string(tc.giveFn1(tc.give)), tc.want)
 		xtesting.Equal(t, tc.giveFn2(tc.give), tc.want)
@@ -148,6 +152,10 @@ func TestEncodeDecode(t *testing.T) {
 		{Base64DecodeFromBytes, Base64DecodeFromString, "dGVzdA==", testStr},
 		{Base64DecodeFromBytes, Base64DecodeFromString, "aGVsbG8gd29ybGQ=", helloWorldStr},
 		{Base64DecodeFromBytes, Base64DecodeFromString, "5rWL6K+VIOODhuOCuSDRgtC10YHRgg==", test2Str},
+		{Base64URLDecodeFromBytes, Base64URLDecodeFromString, "", ""},
+		{Base64URLDecodeFromBytes, Base64URLDecodeFromString, "dGVzdA==", testStr},
+		{Base64URLDecodeFromBytes, Base64URLDecodeFromString, "aGVsbG8gd29ybGQ=", helloWorldStr},
+		{Base64URLDecodeFromBytes, Base64URLDecodeFromString, "5rWL6K-VIOODhuOCuSDRgtC10YHRgg==", test2Str},
 	} {
 		bs1, err := tc.giveFn1([]byte(tc.give))
 		xtesting.Nil(t, err)
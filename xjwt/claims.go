@@ -0,0 +1,202 @@
+package xjwt
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/golang-jwt/jwt/v4"
+	"strings"
+)
+
+// checkRegisteredClaims validates the registered claims and the custom jwt.Claims if any claims validation option is specified, and checks
+// the revocation if WithRevocationStore is specified.
+func checkRegisteredClaims(claims *jwt.RegisteredClaims, custom jwt.Claims, opt *parseOptions) error {
+	if opt.validateClaims {
+		if err := mergeValidationErrors(validateCustomClaims(custom), validateClaims(claims, opt)); err != nil {
+			return err
+		}
+	}
+	if opt.revocationStore != nil {
+		if err := checkRevocation(opt.ctx, claims, opt.revocationStore); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateClaims validates the registered claims of a verified token using parseOptions, the failures are reported as
+// jwt.ValidationError with corresponding flags, which will be converted to ValidationError by newValidationError.
+func validateClaims(claims *jwt.RegisteredClaims, opt *parseOptions) error {
+	now := opt.now()
+	leeway := opt.leeway
+	vErr := &jwt.ValidationError{}
+	addError := func(flag uint32, format string, a ...interface{}) {
+		if vErr.Inner == nil {
+			vErr.Inner = fmt.Errorf(format, a...)
+		}
+		vErr.Errors |= flag
+	}
+
+	// time based claims
+	if claims.ExpiresAt != nil && !now.Before(claims.ExpiresAt.Add(leeway)) { // not accepted on or after exp, see RFC 7519 4.1.4
+		addError(jwt.ValidationErrorExpired, "token is expired by %v", now.Sub(claims.ExpiresAt.Time))
+	}
+	if claims.NotBefore != nil && now.Add(leeway).Before(claims.NotBefore.Time) {
+		addError(jwt.ValidationErrorNotValidYet, "token is not valid yet")
+	}
+	if claims.IssuedAt != nil && now.Add(leeway).Before(claims.IssuedAt.Time) {
+		addError(jwt.ValidationErrorIssuedAt, "token used before issued")
+	}
+	if opt.maxAge > 0 {
+		if claims.IssuedAt == nil {
+			addError(jwt.ValidationErrorIssuedAt, "token has no iat claim")
+		} else if now.After(claims.IssuedAt.Add(opt.maxAge + leeway)) {
+			addError(jwt.ValidationErrorIssuedAt, "token is older than %v", opt.maxAge)
+		}
+	}
+
+	// string claims
+	if opt.issuer != "" && claims.Issuer != opt.issuer {
+		addError(jwt.ValidationErrorIssuer, "token has invalid issuer %q", claims.Issuer)
+	}
+	if len(opt.audiences) > 0 && !containsAnyString(claims.Audience, opt.audiences) {
+		addError(jwt.ValidationErrorAudience, "token has invalid audience %q", []string(claims.Audience))
+	}
+	if opt.subject != "" && claims.Subject != opt.subject {
+		addError(ValidationErrorSubject, "token has invalid subject %q", claims.Subject)
+	}
+
+	if vErr.Errors == 0 {
+		return nil
+	}
+	return vErr
+}
+
+// CustomClaimsValidator is an optional interface of jwt.Claims, which only validates the custom (non-time based) claims. If any claims
+// validation option is specified, ValidateCustom will be invoked instead of jwt.Claims' Valid method, because the time based claims are
+// validated by xjwt with leeway and clock. Returned error will be reported as ErrTokenInvalidClaims, unless it is jwt.ValidationError.
+// Example:
+// 	func (c *UserClaims) Valid() error {
+// 		if err := c.RegisteredClaims.Valid(); err != nil {
+// 			return err
+// 		}
+// 		return c.ValidateCustom()
+// 	}
+// 	func (c *UserClaims) ValidateCustom() error {
+// 		if c.Role == "" {
+// 			return errors.New("missing role")
+// 		}
+// 		return nil
+// 	}
+type CustomClaimsValidator interface {
+	ValidateCustom() error
+}
+
+// timeValidationFlags represents the flags of time based claims, which are validated by validateClaims using leeway and clock options.
+const timeValidationFlags = jwt.ValidationErrorExpired | jwt.ValidationErrorNotValidYet | jwt.ValidationErrorIssuedAt
+
+// validateCustomClaims calls CustomClaimsValidator's ValidateCustom method, or jwt.Claims' Valid method which is skipped by jwt.Parser when
+// any claims validation option is specified. The time based failures are ignored, because they are validated by validateClaims with
+// leeway and clock, and other errors are reported as jwt.ValidationError in the same way as jwt.Parser.
+func validateCustomClaims(claims jwt.Claims) error {
+	if claims == nil {
+		return nil
+	}
+	var err error
+	if v, ok := claims.(CustomClaimsValidator); ok {
+		err = v.ValidateCustom()
+	} else {
+		err = claims.Valid() // maybe returned early by time based failures, see ParseOption
+	}
+	if err == nil {
+		return nil
+	}
+	vErr, ok := err.(*jwt.ValidationError)
+	if !ok {
+		return &jwt.ValidationError{Inner: err, Errors: jwt.ValidationErrorClaimsInvalid}
+	}
+	if vErr.Errors&^timeValidationFlags == 0 {
+		return nil
+	}
+	return &jwt.ValidationError{Inner: vErr.Inner, Errors: vErr.Errors &^ timeValidationFlags}
+}
+
+// mergeValidationErrors merges the flags of given jwt.ValidationError-s (can be nil), and the first non-nil inner error is kept.
+func mergeValidationErrors(errs ...error) error {
+	merged := &jwt.ValidationError{}
+	for _, err := range errs {
+		if err == nil {
+			continue
+		}
+		vErr, ok := err.(*jwt.ValidationError)
+		if !ok {
+			return err
+		}
+		if merged.Inner == nil {
+			merged.Inner = vErr.Inner
+		}
+		merged.Errors |= vErr.Errors
+	}
+	if merged.Errors == 0 {
+		return nil
+	}
+	return merged
+}
+
+// decodeRegisteredClaims decodes the registered claims from the payload of given jwt token string, no matter what the type of claims
+// is used when parsing.
+func decodeRegisteredClaims(signedToken string) (*jwt.RegisteredClaims, error) {
+	parts := strings.Split(signedToken, ".")
+	if len(parts) != 3 {
+		return nil, jwt.NewValidationError("token contains an invalid number of segments", jwt.ValidationErrorMalformed)
+	}
+	payload, err := jwt.DecodeSegment(parts[1])
+	if err != nil {
+		return nil, &jwt.ValidationError{Inner: err, Errors: jwt.ValidationErrorMalformed}
+	}
+	return decodeRegisteredClaimsFromPayload(payload)
+}
+
+// decodeRegisteredClaimsFromPayload decodes the registered claims from given JSON payload.
+func decodeRegisteredClaimsFromPayload(payload []byte) (*jwt.RegisteredClaims, error) {
+	claims := &jwt.RegisteredClaims{}
+	if err := json.Unmarshal(payload, claims); err != nil {
+		return nil, &jwt.ValidationError{Inner: err, Errors: jwt.ValidationErrorMalformed}
+	}
+	return claims, nil
+}
+
+// claimsWithTokenID wraps jwt.Claims and adds a random "jti" claim when marshalling if the claims does not contain one.
+type claimsWithTokenID struct {
+	jwt.Claims
+}
+
+// MarshalJSON marshals the wrapped claims, and adds a random "jti" claim if it is absent or empty.
+func (c *claimsWithTokenID) MarshalJSON() ([]byte, error) {
+	bs, err := json.Marshal(c.Claims)
+	if err != nil {
+		return nil, err
+	}
+	m := make(map[string]json.RawMessage)
+	if err = json.Unmarshal(bs, &m); err != nil || m == nil {
+		return bs, nil // not an object, keep it as it is
+	}
+	if jti, ok := m["jti"]; ok && string(jti) != `""` && string(jti) != "null" {
+		return bs, nil
+	}
+	id, err := newTokenID()
+	if err != nil {
+		return nil, err
+	}
+	m["jti"], _ = json.Marshal(id)
+	return json.Marshal(m)
+}
+
+// containsAnyString checks whether given string slice contains any of given strings.
+func containsAnyString(slice []string, ss []string) bool {
+	for _, s := range ss {
+		if containsString(slice, s) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,196 @@
+package xjwt
+
+import (
+	"errors"
+	"github.com/Aoi-hosizora/ahlib/xtesting"
+	"github.com/golang-jwt/jwt/v4"
+	"testing"
+	"time"
+)
+
+func TestClaimsValidation(t *testing.T) {
+	secret := []byte("secret")
+	now := time.Unix(1600000000, 0)
+	timeFunc := WithTimeFunc(func() time.Time { return now })
+	at := func(d time.Duration) *jwt.NumericDate { return jwt.NewNumericDate(now.Add(d)) }
+	claims := &jwt.RegisteredClaims{Issuer: "iss", Subject: "sub", Audience: jwt.ClaimStrings{"a1", "a2"}, IssuedAt: at(-time.Minute), ExpiresAt: at(time.Hour)}
+	token, err := GenerateTokenWithHS256(claims, secret)
+	xtesting.Nil(t, err)
+
+	// success
+	for _, opts := range [][]ParseOption{
+		{timeFunc},
+		{timeFunc, WithIssuer("iss"), WithAudience("a0", "a2"), WithSubject("sub"), WithMaxAge(time.Hour)},
+		{timeFunc, WithIssuer(""), WithAudience(), WithSubject(""), WithMaxAge(0), WithLeeway(0)},
+		{WithTimeFunc(func() time.Time { return now.Add(time.Hour + time.Minute - time.Second) }), WithLeeway(time.Minute)},
+		{WithTimeFunc(func() time.Time { return now.Add(-2 * time.Minute) }), WithLeeway(time.Minute)},
+	} {
+		parsed, err := ParseToken(token, secret, &jwt.RegisteredClaims{}, opts...)
+		xtesting.Nil(t, err)
+		xtesting.True(t, parsed.Valid)
+		xtesting.Equal(t, parsed.Claims.(*jwt.RegisteredClaims).Subject, "sub")
+	}
+
+	// failure
+	for _, tc := range []struct {
+		giveClaims jwt.Claims
+		giveOpts   []ParseOption
+		wantFn     func(error) bool
+	}{
+		{claims, []ParseOption{WithTimeFunc(func() time.Time { return now.Add(2 * time.Hour) })}, IsExpiredError},
+		{claims, []ParseOption{WithTimeFunc(func() time.Time { return now.Add(time.Hour + 2*time.Minute) }), WithLeeway(time.Minute)}, IsExpiredError},
+		{claims, []ParseOption{WithTimeFunc(func() time.Time { return now.Add(time.Hour) })}, IsExpiredError},                                        // exactly at exp
+		{claims, []ParseOption{WithTimeFunc(func() time.Time { return now.Add(time.Hour + time.Minute) }), WithLeeway(time.Minute)}, IsExpiredError}, // exactly at exp + leeway
+		{claims, []ParseOption{WithTimeFunc(func() time.Time { return now.Add(-2 * time.Minute) })}, IsIssuedAtError},
+		{&jwt.RegisteredClaims{NotBefore: at(time.Minute)}, []ParseOption{timeFunc}, IsNotValidYetError},
+		{&jwt.RegisteredClaims{NotBefore: at(2 * time.Minute)}, []ParseOption{timeFunc, WithLeeway(time.Minute)}, IsNotValidYetError},
+		{claims, []ParseOption{timeFunc, WithMaxAge(30 * time.Second)}, IsIssuedAtError},
+		{&jwt.RegisteredClaims{}, []ParseOption{timeFunc, WithMaxAge(time.Hour)}, IsIssuedAtError},
+		{claims, []ParseOption{timeFunc, WithIssuer("other")}, IsIssuerError},
+		{&jwt.RegisteredClaims{}, []ParseOption{timeFunc, WithIssuer("iss")}, IsIssuerError},
+		{claims, []ParseOption{timeFunc, WithAudience("a3")}, IsAudienceError},
+		{&jwt.RegisteredClaims{}, []ParseOption{timeFunc, WithAudience("a1")}, IsAudienceError},
+		{claims, []ParseOption{timeFunc, WithSubject("other")}, IsSubjectError},
+	} {
+		token, err := GenerateTokenWithHS256(tc.giveClaims, secret)
+		xtesting.Nil(t, err)
+		_, err = ParseToken(token, secret, &jwt.RegisteredClaims{}, tc.giveOpts...)
+		xtesting.NotNil(t, err)
+		xtesting.True(t, tc.wantFn(err))
+		xtesting.False(t, IsTokenInvalidError(err))
+	}
+
+	// multiple failures
+	_, err = ParseToken(token, secret, &jwt.MapClaims{}, WithTimeFunc(func() time.Time { return now.Add(2 * time.Hour) }), WithIssuer("x"), WithAudience("x"))
+	xtesting.True(t, IsExpiredError(err))
+	xtesting.True(t, IsIssuerError(err))
+	xtesting.True(t, IsAudienceError(err))
+
+	// signature is checked before claims
+	_, err = ParseToken(token, []byte("other"), &jwt.RegisteredClaims{}, WithIssuer("x"))
+	xtesting.True(t, IsTokenInvalidError(err))
+	xtesting.False(t, IsIssuerError(err))
+
+	// time based checks of custom Valid are replaced, default behavior is unchanged
+	expired, err := GenerateTokenWithHS256(&jwt.StandardClaims{ExpiresAt: now.Unix() + 1}, secret)
+	xtesting.Nil(t, err)
+	_, err = ParseToken(expired, secret, &jwt.StandardClaims{})
+	xtesting.True(t, IsExpiredError(err))
+	_, err = ParseToken(expired, secret, &jwt.StandardClaims{}, timeFunc)
+	xtesting.Nil(t, err)
+
+	// exp boundary
+	for _, tc := range []struct {
+		giveNow    time.Time
+		giveLeeway time.Duration
+		wantErr    bool
+	}{
+		{now.Add(time.Hour - time.Second), 0, false},
+		{now.Add(time.Hour), 0, true},
+		{now.Add(time.Hour + time.Minute - time.Second), time.Minute, false},
+		{now.Add(time.Hour + time.Minute), time.Minute, true},
+	} {
+		giveNow := tc.giveNow
+		_, err := ParseToken(token, secret, &jwt.RegisteredClaims{}, WithTimeFunc(func() time.Time { return giveNow }), WithLeeway(tc.giveLeeway))
+		xtesting.Equal(t, err != nil, tc.wantErr)
+		xtesting.Equal(t, IsExpiredError(err), tc.wantErr)
+	}
+}
+
+type testRoleClaims struct {
+	jwt.RegisteredClaims
+	Role string `json:"role"`
+}
+
+var errTestInvalidRole = errors.New("invalid role")
+
+func (c *testRoleClaims) Valid() error {
+	if err := c.RegisteredClaims.Valid(); err != nil {
+		return err
+	}
+	if c.Role != "admin" {
+		return errTestInvalidRole
+	}
+	return nil
+}
+
+func TestCustomClaimsValidation(t *testing.T) {
+	secret := []byte("secret")
+	now := time.Now()
+	claims := &testRoleClaims{RegisteredClaims: jwt.RegisteredClaims{Issuer: "iss", ExpiresAt: jwt.NewNumericDate(now.Add(time.Hour))}, Role: "user"}
+	token, err := GenerateTokenWithHS256(claims, secret)
+	xtesting.Nil(t, err)
+
+	// custom Valid is not skipped by claims options
+	for _, opts := range [][]ParseOption{
+		nil,
+		{WithIssuer("iss")},
+		{WithLeeway(time.Minute), WithTimeFunc(time.Now)},
+	} {
+		_, err := ParseToken(token, secret, &testRoleClaims{}, opts...)
+		xtesting.True(t, IsClaimsInvalidError(err))
+		xtesting.True(t, errors.Is(err, errTestInvalidRole))
+		xtesting.True(t, errors.Is(err, ErrTokenInvalidClaims))
+		xtesting.Equal(t, err.Error(), "invalid role")
+	}
+
+	// merged with registered claims failures
+	_, err = ParseToken(token, secret, &testRoleClaims{}, WithIssuer("other"))
+	xtesting.True(t, IsClaimsInvalidError(err))
+	xtesting.True(t, IsIssuerError(err))
+	xtesting.True(t, errors.Is(err, errTestInvalidRole))
+
+	// encrypted token
+	key := []byte("0123456789abcdef0123456789abcdef")
+	encrypted, err := EncryptToken([]byte(`{"iss":"iss","role":"user"}`), "dir", "A256GCM", key)
+	xtesting.Nil(t, err)
+	_, err = ParseEncryptedToken(encrypted, key, &testRoleClaims{}, WithIssuer("iss"))
+	xtesting.True(t, errors.Is(err, errTestInvalidRole))
+
+	// success
+	claims.Role = "admin"
+	token, err = GenerateTokenWithHS256(claims, secret)
+	xtesting.Nil(t, err)
+	_, err = ParseToken(token, secret, &testRoleClaims{}, WithIssuer("iss"))
+	xtesting.Nil(t, err)
+}
+
+type testValidatorClaims struct {
+	testRoleClaims
+}
+
+func (c *testValidatorClaims) ValidateCustom() error {
+	if c.Role != "admin" {
+		return errTestInvalidRole
+	}
+	return nil
+}
+
+func TestCustomClaimsValidator(t *testing.T) {
+	secret := []byte("secret")
+	now := time.Now()
+	claims := &testRoleClaims{RegisteredClaims: jwt.RegisteredClaims{ExpiresAt: jwt.NewNumericDate(now.Add(-time.Second))}, Role: "user"}
+	token, err := GenerateTokenWithHS256(claims, secret)
+	xtesting.Nil(t, err)
+
+	// Valid returns early on expiration, and the role check is skipped within leeway
+	_, err = ParseToken(token, secret, &testRoleClaims{}, WithLeeway(time.Minute))
+	xtesting.Nil(t, err)
+
+	// ValidateCustom is invoked instead of Valid
+	_, err = ParseToken(token, secret, &testValidatorClaims{}, WithLeeway(time.Minute))
+	xtesting.True(t, IsClaimsInvalidError(err))
+	xtesting.True(t, errors.Is(err, errTestInvalidRole))
+	xtesting.False(t, IsExpiredError(err))
+	_, err = ParseToken(token, secret, &testValidatorClaims{}, WithTimeFunc(func() time.Time { return now.Add(-time.Minute) }))
+	xtesting.True(t, errors.Is(err, errTestInvalidRole))
+	_, err = ParseToken(token, secret, &testValidatorClaims{})
+	xtesting.True(t, IsExpiredError(err))
+
+	// success
+	claims.Role = "admin"
+	token, err = GenerateTokenWithHS256(claims, secret)
+	xtesting.Nil(t, err)
+	_, err = ParseToken(token, secret, &testValidatorClaims{}, WithLeeway(time.Minute))
+	xtesting.Nil(t, err)
+}
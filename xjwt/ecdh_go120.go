@@ -0,0 +1,25 @@
+//go:build go1.20
+// +build go1.20
+
+package xjwt
+
+import (
+	"crypto/ecdsa"
+)
+
+// ecdhSharedSecret computes the ECDH shared secret Z using crypto/ecdh, which is the x-coordinate of the shared point, padded to the
+// curve's byte size. The public key which is not on the curve will be rejected.
+func ecdhSharedSecret(pub *ecdsa.PublicKey, priv *ecdsa.PrivateKey) ([]byte, error) {
+	if pub.Curve != priv.Curve || !pub.Curve.IsOnCurve(pub.X, pub.Y) {
+		return nil, errInvalidJWEKey
+	}
+	ecdhPub, err := pub.ECDH()
+	if err != nil {
+		return nil, errInvalidJWEKey
+	}
+	ecdhPriv, err := priv.ECDH()
+	if err != nil {
+		return nil, errInvalidJWEKey
+	}
+	return ecdhPriv.ECDH(ecdhPub)
+}
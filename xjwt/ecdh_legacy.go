@@ -0,0 +1,20 @@
+//go:build !go1.20
+// +build !go1.20
+
+package xjwt
+
+import (
+	"crypto/ecdsa"
+)
+
+// Note: crypto/ecdh is not available before go1.20, so elliptic.Curve's ScalarMult is still used in the old toolchains.
+
+// ecdhSharedSecret computes the ECDH shared secret Z, which is the x-coordinate of the shared point, padded to the curve's byte size.
+// The public key which is not on the curve will be rejected.
+func ecdhSharedSecret(pub *ecdsa.PublicKey, priv *ecdsa.PrivateKey) ([]byte, error) {
+	if pub.Curve != priv.Curve || !pub.Curve.IsOnCurve(pub.X, pub.Y) {
+		return nil, errInvalidJWEKey
+	}
+	x, _ := pub.Curve.ScalarMult(pub.X, pub.Y, priv.D.Bytes())
+	return padBytes(x.Bytes(), (pub.Curve.Params().BitSize+7)/8), nil
+}
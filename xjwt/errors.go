@@ -0,0 +1,243 @@
+package xjwt
+
+import (
+	"errors"
+	"fmt"
+	"github.com/golang-jwt/jwt/v4"
+	"strings"
+	"time"
+)
+
+// ValidationErrorSubject is the flag of SUB (Subject) validation error, which is not defined in jwt package. It can be used with
+// CheckValidationError, see IsSubjectError.
+const ValidationErrorSubject uint32 = 1 << 16
+
+// Sentinel errors of token validation checks, which can be used with errors.Is to check ValidationError.
+var (
+	ErrTokenMalformed        = errors.New("xjwt: token is malformed")
+	ErrTokenUnverifiable     = errors.New("xjwt: token is unverifiable")
+	ErrTokenSignatureInvalid = errors.New("xjwt: token signature is invalid")
+	ErrTokenExpired          = errors.New("xjwt: token is expired")
+	ErrTokenNotValidYet      = errors.New("xjwt: token is not valid yet")
+	ErrTokenInvalidIssuedAt  = errors.New("xjwt: token has invalid issued at")
+	ErrTokenInvalidAudience  = errors.New("xjwt: token has invalid audience")
+	ErrTokenInvalidIssuer    = errors.New("xjwt: token has invalid issuer")
+	ErrTokenInvalidSubject   = errors.New("xjwt: token has invalid subject")
+	ErrTokenInvalidID        = errors.New("xjwt: token has invalid id")
+	ErrTokenInvalidClaims    = errors.New("xjwt: token has invalid claims")
+)
+
+// validationChecks is the ordered list of validation flags and their sentinel errors.
+var validationChecks = []struct {
+	flag uint32
+	err  error
+}{
+	{jwt.ValidationErrorMalformed, ErrTokenMalformed},
+	{jwt.ValidationErrorUnverifiable, ErrTokenUnverifiable},
+	{jwt.ValidationErrorSignatureInvalid, ErrTokenSignatureInvalid},
+	{jwt.ValidationErrorExpired, ErrTokenExpired},
+	{jwt.ValidationErrorNotValidYet, ErrTokenNotValidYet},
+	{jwt.ValidationErrorIssuedAt, ErrTokenInvalidIssuedAt},
+	{jwt.ValidationErrorAudience, ErrTokenInvalidAudience},
+	{jwt.ValidationErrorIssuer, ErrTokenInvalidIssuer},
+	{ValidationErrorSubject, ErrTokenInvalidSubject},
+	{jwt.ValidationErrorId, ErrTokenInvalidID},
+	{jwt.ValidationErrorClaimsInvalid, ErrTokenInvalidClaims},
+}
+
+// ValidationFailure represents a failed check of token validation.
+type ValidationFailure struct {
+	// Err represents the sentinel error of the check, such as ErrTokenExpired.
+	Err error
+
+	// Message represents the detailed message of the failure.
+	Message string
+
+	// Value represents the offending claim value, such as "iss" for ErrTokenInvalidIssuer, and "aud" for ErrTokenInvalidAudience.
+	Value interface{}
+
+	// Expected represents the expected value if exists, such as the required issuer for ErrTokenInvalidIssuer.
+	Expected interface{}
+
+	// Time represents the offending timestamp for time based checks, that is "exp", "nbf" and "iat".
+	Time time.Time
+
+	// Now represents the current time used in time based checks.
+	Now time.Time
+}
+
+// Error returns the detailed message of the failure.
+func (f *ValidationFailure) Error() string {
+	return f.Message
+}
+
+// Unwrap returns the sentinel error of the failure.
+func (f *ValidationFailure) Unwrap() error {
+	return f.Err
+}
+
+// ValidationError represents an error of token validation, which lists every failed check. It can be checked by errors.Is with sentinel
+// errors such as ErrTokenExpired, and unwrapped to the underlying cause such as ErrKeyNotFound and *AlgorithmError. For compatibility, it
+// can also be converted to *jwt.ValidationError by errors.As.
+//
+// Note that all the ParseToken series functions return *ValidationError rather than *jwt.ValidationError, so the direct type assertion
+// `err.(*jwt.ValidationError)` no longer works, please use errors.As or assert to *ValidationError instead, which has the same Errors
+// bit-field.
+type ValidationError struct {
+	// Failures represents all the failed checks.
+	Failures []*ValidationFailure
+
+	// Errors represents the bit-field of jwt.ValidationErrorXXX and ValidationErrorSubject flags.
+	Errors uint32
+
+	// Inner represents the underlying cause, can be nil.
+	Inner error
+}
+
+// Error returns the distinct messages of all failures joined by "; ".
+func (v *ValidationError) Error() string {
+	if len(v.Failures) == 0 {
+		if v.Inner != nil {
+			return v.Inner.Error()
+		}
+		return "token is invalid"
+	}
+	messages := make([]string, 0, len(v.Failures))
+	for _, f := range v.Failures {
+		if !containsString(messages, f.Message) {
+			messages = append(messages, f.Message)
+		}
+	}
+	return strings.Join(messages, "; ")
+}
+
+// Unwrap returns the underlying cause.
+func (v *ValidationError) Unwrap() error {
+	return v.Inner
+}
+
+// Is checks whether given target is the sentinel error of any failure, this is used by errors.Is.
+func (v *ValidationError) Is(target error) bool {
+	for _, f := range v.Failures {
+		if f.Err == target {
+			return true
+		}
+	}
+	return false
+}
+
+// As converts ValidationError to *jwt.ValidationError with the same flags and inner error, this is used by errors.As.
+func (v *ValidationError) As(target interface{}) bool {
+	if t, ok := target.(**jwt.ValidationError); ok {
+		*t = &jwt.ValidationError{Inner: v.Inner, Errors: v.Errors}
+		return true
+	}
+	return false
+}
+
+// Failure returns the failure whose sentinel error is the given one.
+func (v *ValidationError) Failure(err error) (*ValidationFailure, bool) {
+	for _, f := range v.Failures {
+		if f.Err == err {
+			return f, true
+		}
+	}
+	return nil, false
+}
+
+// newValidationError converts given error (usually *jwt.ValidationError) to *ValidationError, the registered claims (can be nil) and
+// current time are used to fill in the offending values. Other errors will be returned as they are.
+func newValidationError(err error, claims *jwt.RegisteredClaims, opt *parseOptions, now time.Time) error {
+	if err == nil {
+		return nil
+	}
+	if _, ok := err.(*ValidationError); ok {
+		return err
+	}
+	jve, ok := err.(*jwt.ValidationError)
+	if !ok {
+		return err
+	}
+	if claims == nil {
+		claims = &jwt.RegisteredClaims{}
+	}
+	if opt == nil {
+		opt = &parseOptions{}
+	}
+
+	ve := &ValidationError{Errors: jve.Errors, Inner: jve.Inner}
+	if ve.Inner == nil && jve.Errors&(jwt.ValidationErrorMalformed|jwt.ValidationErrorUnverifiable|jwt.ValidationErrorSignatureInvalid) != 0 {
+		ve.Inner = errors.New(jve.Error()) // created by jwt.NewValidationError
+	}
+	withInner := func(message string) string {
+		if ve.Inner == nil {
+			return message
+		}
+		return ve.Inner.Error() // failures caused by the same inner error share the same message
+	}
+
+	for _, check := range validationChecks {
+		if jve.Errors&check.flag == 0 {
+			continue
+		}
+		f := &ValidationFailure{Err: check.err}
+		switch check.err {
+		case ErrTokenMalformed:
+			f.Message = withInner("token is malformed")
+		case ErrTokenUnverifiable:
+			f.Message = withInner("token could not be verified")
+		case ErrTokenSignatureInvalid:
+			f.Message = withInner("token signature is invalid")
+		case ErrTokenExpired:
+			f.Now = now
+			f.Message = "token is expired"
+			if claims.ExpiresAt != nil {
+				f.Time = claims.ExpiresAt.Time
+				f.Message = fmt.Sprintf("token is expired by %v", now.Sub(f.Time))
+			}
+		case ErrTokenNotValidYet:
+			f.Now = now
+			f.Message = "token is not valid yet"
+			if claims.NotBefore != nil {
+				f.Time = claims.NotBefore.Time
+				f.Message = fmt.Sprintf("token is not valid until %s", f.Time.UTC().Format(time.RFC3339))
+			}
+		case ErrTokenInvalidIssuedAt:
+			f.Now = now
+			switch {
+			case claims.IssuedAt == nil:
+				f.Message = "token has no iat claim"
+			case now.Add(opt.leeway).Before(claims.IssuedAt.Time):
+				f.Time = claims.IssuedAt.Time
+				f.Message = fmt.Sprintf("token is used before issued at %s", f.Time.UTC().Format(time.RFC3339))
+			default:
+				f.Time = claims.IssuedAt.Time
+				f.Expected = opt.maxAge
+				f.Message = fmt.Sprintf("token issued at %s is older than %v", f.Time.UTC().Format(time.RFC3339), opt.maxAge)
+			}
+		case ErrTokenInvalidAudience:
+			f.Value, f.Expected = []string(claims.Audience), opt.audiences
+			f.Message = fmt.Sprintf("token audience %q is not accepted", []string(claims.Audience))
+		case ErrTokenInvalidIssuer:
+			f.Value, f.Expected = claims.Issuer, opt.issuer
+			f.Message = fmt.Sprintf("token issuer %q is not accepted", claims.Issuer)
+		case ErrTokenInvalidSubject:
+			f.Value, f.Expected = claims.Subject, opt.subject
+			f.Message = fmt.Sprintf("token subject %q is not accepted", claims.Subject)
+		case ErrTokenInvalidID:
+			f.Value = claims.ID
+			switch {
+			case jve.Inner == ErrTokenRevoked:
+				f.Message = fmt.Sprintf("token %q has been revoked", claims.ID)
+			case claims.ID == "":
+				f.Message = "token has no jti claim"
+			default:
+				f.Message = fmt.Sprintf("token id %q is invalid", claims.ID)
+			}
+		case ErrTokenInvalidClaims:
+			f.Message = withInner("token claims are invalid")
+		}
+		ve.Failures = append(ve.Failures, f)
+	}
+	return ve
+}
@@ -0,0 +1,160 @@
+package xjwt
+
+import (
+	"context"
+	"errors"
+	"github.com/Aoi-hosizora/ahlib/xtesting"
+	"github.com/golang-jwt/jwt/v4"
+	"testing"
+	"time"
+)
+
+func TestValidationErrorType(t *testing.T) {
+	secret := []byte("secret")
+	now := time.Unix(1600000000, 0)
+	at := func(d time.Duration) *jwt.NumericDate { return jwt.NewNumericDate(now.Add(d)) }
+	claims := &jwt.RegisteredClaims{ID: "id", Issuer: "iss", Subject: "sub", Audience: jwt.ClaimStrings{"a1"}, IssuedAt: at(0), NotBefore: at(0), ExpiresAt: at(time.Hour)}
+	token, err := GenerateTokenWithHS256(claims, secret)
+	xtesting.Nil(t, err)
+
+	t.Run("claims failures", func(t *testing.T) {
+		later := now.Add(2 * time.Hour)
+		_, err := ParseToken(token, secret, &jwt.RegisteredClaims{}, WithTimeFunc(func() time.Time { return later }),
+			WithIssuer("other"), WithAudience("a2", "a3"), WithSubject("user"), WithMaxAge(time.Hour))
+		xtesting.NotNil(t, err)
+
+		var ve *ValidationError
+		xtesting.True(t, errors.As(err, &ve))
+		xtesting.Equal(t, len(ve.Failures), 5)
+		xtesting.Equal(t, ve.Errors, jwt.ValidationErrorExpired|jwt.ValidationErrorIssuedAt|jwt.ValidationErrorIssuer|jwt.ValidationErrorAudience|ValidationErrorSubject)
+		for _, target := range []error{ErrTokenExpired, ErrTokenInvalidIssuedAt, ErrTokenInvalidIssuer, ErrTokenInvalidAudience, ErrTokenInvalidSubject} {
+			xtesting.True(t, errors.Is(err, target))
+		}
+		for _, target := range []error{ErrTokenMalformed, ErrTokenSignatureInvalid, ErrTokenNotValidYet, ErrTokenInvalidID} {
+			xtesting.False(t, errors.Is(err, target))
+		}
+		for _, fn := range []func(error) bool{IsExpiredError, IsIssuedAtError, IsIssuerError, IsAudienceError, IsSubjectError} {
+			xtesting.True(t, fn(err))
+		}
+		xtesting.False(t, IsClaimsInvalidError(err))
+		xtesting.False(t, IsTokenInvalidError(err))
+
+		f, ok := ve.Failure(ErrTokenExpired)
+		xtesting.True(t, ok)
+		xtesting.True(t, f.Time.Equal(now.Add(time.Hour)))
+		xtesting.True(t, f.Now.Equal(later))
+		xtesting.Equal(t, f.Error(), "token is expired by 1h0m0s")
+		xtesting.True(t, errors.Is(f, ErrTokenExpired))
+		f, _ = ve.Failure(ErrTokenInvalidIssuedAt)
+		xtesting.True(t, f.Time.Equal(now))
+		xtesting.Equal(t, f.Expected, time.Hour)
+		f, _ = ve.Failure(ErrTokenInvalidIssuer)
+		xtesting.Equal(t, f.Value, "iss")
+		xtesting.Equal(t, f.Expected, "other")
+		f, _ = ve.Failure(ErrTokenInvalidAudience)
+		xtesting.Equal(t, f.Value, []string{"a1"})
+		xtesting.Equal(t, f.Expected, []string{"a2", "a3"})
+		f, _ = ve.Failure(ErrTokenInvalidSubject)
+		xtesting.Equal(t, f.Value, "sub")
+		xtesting.Equal(t, f.Expected, "user")
+		f, ok = ve.Failure(ErrTokenNotValidYet)
+		xtesting.False(t, ok)
+		xtesting.Nil(t, f)
+		xtesting.Equal(t, err.Error(), `token is expired by 1h0m0s; token issued at 2020-09-13T12:26:40Z is older than 1h0m0s; `+
+			`token audience ["a1"] is not accepted; token issuer "iss" is not accepted; token subject "sub" is not accepted`)
+
+		// compatible with jwt.ValidationError
+		var jve *jwt.ValidationError
+		xtesting.True(t, errors.As(err, &jve))
+		xtesting.Equal(t, jve.Errors, ve.Errors)
+	})
+
+	t.Run("not valid yet", func(t *testing.T) {
+		_, err := ParseToken(token, secret, &jwt.RegisteredClaims{}, WithTimeFunc(func() time.Time { return now.Add(-time.Minute) }))
+		xtesting.True(t, errors.Is(err, ErrTokenNotValidYet))
+		xtesting.True(t, errors.Is(err, ErrTokenInvalidIssuedAt))
+		f, ok := err.(*ValidationError).Failure(ErrTokenNotValidYet)
+		xtesting.True(t, ok)
+		xtesting.True(t, f.Time.Equal(now))
+		xtesting.True(t, f.Now.Equal(now.Add(-time.Minute)))
+	})
+
+	t.Run("signature and malformed", func(t *testing.T) {
+		_, err := ParseToken(token, []byte("other"), &jwt.RegisteredClaims{}, WithTimeFunc(func() time.Time { return now }))
+		xtesting.True(t, errors.Is(err, ErrTokenSignatureInvalid))
+		xtesting.False(t, errors.Is(err, ErrTokenExpired))
+		xtesting.True(t, errors.Is(err, jwt.ErrSignatureInvalid))
+		xtesting.Equal(t, err.Error(), jwt.ErrSignatureInvalid.Error())
+
+		_, err = ParseToken("a.b", secret, &jwt.RegisteredClaims{})
+		xtesting.True(t, errors.Is(err, ErrTokenMalformed))
+		xtesting.True(t, IsTokenInvalidError(err))
+		xtesting.Equal(t, err.Error(), "token contains an invalid number of segments")
+
+		_, err = ParseToken(token, secret, &jwt.RegisteredClaims{}, WithAllowedAlgorithms("HS512"))
+		xtesting.True(t, errors.Is(err, ErrTokenUnverifiable))
+		xtesting.True(t, errors.Is(err, ErrTokenSignatureInvalid))
+		var ae *AlgorithmError
+		xtesting.True(t, errors.As(err, &ae))
+		xtesting.Equal(t, ae.Algorithm, "HS256")
+	})
+
+	t.Run("revoked", func(t *testing.T) {
+		store := NewMemoryRevocationStore()
+		xtesting.Nil(t, store.Revoke(context.Background(), "id", time.Time{}))
+		_, err := ParseToken(token, secret, &jwt.RegisteredClaims{}, WithTimeFunc(func() time.Time { return now }), WithRevocationStore(store))
+		xtesting.True(t, errors.Is(err, ErrTokenInvalidID))
+		xtesting.True(t, errors.Is(err, ErrTokenRevoked))
+		xtesting.True(t, IsRevokedError(err))
+		f, _ := err.(*ValidationError).Failure(ErrTokenInvalidID)
+		xtesting.Equal(t, f.Value, "id")
+		xtesting.Equal(t, f.Error(), `token "id" has been revoked`)
+	})
+
+	t.Run("type assertion", func(t *testing.T) {
+		later := now.Add(2 * time.Hour)
+		_, err := ParseToken(token, secret, &jwt.RegisteredClaims{}, WithTimeFunc(func() time.Time { return later }))
+
+		// the concrete type is changed, the old assertion pattern no longer works
+		_, ok := err.(*jwt.ValidationError)
+		xtesting.False(t, ok)
+		ve, ok := err.(*ValidationError)
+		xtesting.True(t, ok)
+		xtesting.True(t, ve.Errors&jwt.ValidationErrorExpired != 0)
+		var jve *jwt.ValidationError
+		xtesting.True(t, errors.As(err, &jve))
+		xtesting.True(t, jve.Errors&jwt.ValidationErrorExpired != 0)
+		xtesting.True(t, CheckValidationError(err, jwt.ValidationErrorExpired))
+
+		// all the parse entry points share the same type
+		ks := NewKeySet(time.Hour)
+		for _, fn := range []func() error{
+			func() error { _, err := ParseTokenWithKey(token, secret, &jwt.RegisteredClaims{}); return err },
+			func() error { _, err := ParseTokenWithKeyfunc(token, nil, &jwt.RegisteredClaims{}); return err },
+			func() error { _, err := ParseTokenWithKeySet(token, nil, &jwt.RegisteredClaims{}); return err },
+			func() error { _, err := ParseTokenWithKeySet(token, ks, &jwt.RegisteredClaims{}); return err },
+			func() error { _, err := ParseTokenClaims("a.b", secret, &jwt.RegisteredClaims{}); return err },
+		} {
+			err := fn()
+			xtesting.NotNil(t, err)
+			_, ok := err.(*ValidationError)
+			xtesting.True(t, ok)
+			xtesting.True(t, errors.As(err, &ve))
+		}
+	})
+
+	t.Run("conversion", func(t *testing.T) {
+		xtesting.Nil(t, newValidationError(nil, nil, nil, now))
+		other := errors.New("other")
+		xtesting.Equal(t, newValidationError(other, nil, nil, now), other)
+		ve := &ValidationError{}
+		xtesting.Equal(t, newValidationError(ve, nil, nil, now), ve)
+		xtesting.Equal(t, ve.Error(), "token is invalid")
+		xtesting.Equal(t, (&ValidationError{Inner: other}).Error(), "other")
+
+		err := newValidationError(jwt.NewValidationError("token is expired", jwt.ValidationErrorExpired|jwt.ValidationErrorClaimsInvalid), nil, nil, now)
+		xtesting.True(t, errors.Is(err, ErrTokenExpired))
+		xtesting.True(t, errors.Is(err, ErrTokenInvalidClaims))
+		xtesting.Equal(t, err.Error(), "token is expired; token claims are invalid")
+	})
+}
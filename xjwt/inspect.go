@@ -0,0 +1,244 @@
+package xjwt
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/golang-jwt/jwt/v4"
+	"strings"
+	"time"
+)
+
+// Inspection represents the inspection result of a jwt token, which is decoded without verification, see Inspect.
+type Inspection struct {
+	// Header represents the decoded header of the token.
+	Header map[string]interface{}
+
+	// Claims represents the decoded raw claims of the token, numbers are stored as json.Number.
+	Claims map[string]interface{}
+
+	// Algorithm represents the "alg" header, that is the signing algorithm.
+	Algorithm string
+
+	// KeyID represents the "kid" header, empty if absent.
+	KeyID string
+
+	// IssuedAt represents the "iat" claim, nil if absent.
+	IssuedAt *time.Time
+
+	// NotBefore represents the "nbf" claim, nil if absent.
+	NotBefore *time.Time
+
+	// ExpiresAt represents the "exp" claim, nil if absent.
+	ExpiresAt *time.Time
+
+	// Now represents the current time used in the validation report.
+	Now time.Time
+
+	// Checks represents the dry-run validation report, see InspectionCheck.
+	Checks []*InspectionCheck
+}
+
+// InspectionCheck represents a check in the dry-run validation report of Inspection.
+type InspectionCheck struct {
+	// Name represents the check name, that is "signature", "claims", "exp", "nbf", "iat", "iss", "aud", "sub" or "jti".
+	Name string
+
+	// Passed represents whether the check passes.
+	Passed bool
+
+	// Skipped represents whether the check is skipped, such as "signature" without jwt.Keyfunc.
+	Skipped bool
+
+	// Message represents the failure message or the reason of skipping, empty if passed.
+	Message string
+}
+
+// Inspect decodes the header and claims of given jwt token string without verification, and reports which checks would pass or fail when
+// parsing it by ParseTokenWithKeyfunc with given keyFunc and ParseOption-s. This is intended for support tooling and debugging, so please
+// never trust the returned claims. The keyFunc can be KeySet.Keyfunc or JWKSFetcher.Keyfunc, and nil keyFunc will skip signature check.
+// The returned error is ValidationError only if the token could not be decoded.
+// Example:
+// 	inspection, _ := Inspect(token, keySet.Keyfunc, WithIssuer("auth"))
+// 	fmt.Println(inspection.Valid()) // false
+// 	fmt.Println(inspection)
+// 	// Algorithm: RS256
+// 	// Key ID: k1
+// 	// Issued at: 2021-10-01T00:00:00Z (2h0m0s ago)
+// 	// Expires at: 2021-10-01T01:00:00Z (1h0m0s ago)
+// 	// ...
+// 	// [PASS] signature
+// 	// [FAIL] exp: token is expired by 1h0m0s
+// 	// [PASS] iat
+// 	// [FAIL] iss: token issuer "other" is not accepted
+func Inspect(signedToken string, keyFunc jwt.Keyfunc, options ...ParseOption) (*Inspection, error) {
+	opt := newParseOptions(options)
+	parts := strings.Split(signedToken, ".")
+	if len(parts) != 3 {
+		return nil, newValidationError(jwt.NewValidationError("token contains an invalid number of segments", jwt.ValidationErrorMalformed), nil, opt, time.Time{})
+	}
+	i := &Inspection{Now: opt.now()}
+	if err := decodeInspectionSegment(parts[0], &i.Header); err != nil {
+		return nil, newValidationError(err, nil, opt, i.Now)
+	}
+	if err := decodeInspectionSegment(parts[1], &i.Claims); err != nil {
+		return nil, newValidationError(err, nil, opt, i.Now)
+	}
+	i.Algorithm, _ = i.Header["alg"].(string)
+	i.KeyID, _ = i.Header["kid"].(string)
+
+	i.Checks = append(i.Checks, inspectSignature(signedToken, keyFunc, opt))
+	payload, _ := jwt.DecodeSegment(parts[1]) // already decoded
+	registered, err := decodeRegisteredClaimsFromPayload(payload)
+	if err != nil {
+		i.Checks = append(i.Checks, &InspectionCheck{Name: "claims", Message: newValidationError(err, nil, opt, i.Now).Error()})
+		return i, nil
+	}
+	if registered.IssuedAt != nil {
+		i.IssuedAt = &registered.IssuedAt.Time
+	}
+	if registered.NotBefore != nil {
+		i.NotBefore = &registered.NotBefore.Time
+	}
+	if registered.ExpiresAt != nil {
+		i.ExpiresAt = &registered.ExpiresAt.Time
+	}
+	i.Checks = append(i.Checks, inspectClaims(registered, opt, i.Now)...)
+	return i, nil
+}
+
+// decodeInspectionSegment decodes given base64url encoded JSON object segment.
+func decodeInspectionSegment(segment string, v *map[string]interface{}) error {
+	bs, err := jwt.DecodeSegment(segment)
+	if err != nil {
+		return &jwt.ValidationError{Inner: err, Errors: jwt.ValidationErrorMalformed}
+	}
+	decoder := json.NewDecoder(bytes.NewReader(bs))
+	decoder.UseNumber()
+	if err = decoder.Decode(v); err != nil {
+		return &jwt.ValidationError{Inner: err, Errors: jwt.ValidationErrorMalformed}
+	}
+	return nil
+}
+
+// inspectSignature checks the signature of given jwt token string in the same way as ParseTokenWithKeyfunc.
+func inspectSignature(signedToken string, keyFunc jwt.Keyfunc, opt *parseOptions) *InspectionCheck {
+	check := &InspectionCheck{Name: "signature"}
+	if keyFunc == nil {
+		check.Skipped, check.Message = true, "no keyfunc was provided"
+		return check
+	}
+	parser := &jwt.Parser{SkipClaimsValidation: true}
+	if _, err := parser.Parse(signedToken, verifyingKeyfunc(keyFunc, opt)); err != nil {
+		check.Message = newValidationError(err, nil, opt, time.Time{}).Error()
+		return check
+	}
+	check.Passed = true
+	return check
+}
+
+// inspectClaims checks the registered claims in the same way as checkRegisteredClaims, and reports each applicable check.
+func inspectClaims(registered *jwt.RegisteredClaims, opt *parseOptions, now time.Time) []*InspectionCheck {
+	ve := &ValidationError{}
+	if err, ok := newValidationError(validateClaims(registered, opt), registered, opt, now).(*ValidationError); ok {
+		ve = err
+	}
+	var checks []*InspectionCheck
+	for _, c := range []struct {
+		name       string
+		applicable bool
+		err        error
+	}{
+		{"exp", registered.ExpiresAt != nil, ErrTokenExpired},
+		{"nbf", registered.NotBefore != nil, ErrTokenNotValidYet},
+		{"iat", registered.IssuedAt != nil || opt.maxAge > 0, ErrTokenInvalidIssuedAt},
+		{"iss", opt.issuer != "", ErrTokenInvalidIssuer},
+		{"aud", len(opt.audiences) > 0, ErrTokenInvalidAudience},
+		{"sub", opt.subject != "", ErrTokenInvalidSubject},
+	} {
+		if !c.applicable {
+			continue
+		}
+		check := &InspectionCheck{Name: c.name, Passed: true}
+		if f, ok := ve.Failure(c.err); ok {
+			check.Passed, check.Message = false, f.Message
+		}
+		checks = append(checks, check)
+	}
+	if opt.revocationStore != nil {
+		check := &InspectionCheck{Name: "jti", Passed: true}
+		if err := checkRevocation(opt.ctx, registered, opt.revocationStore); err != nil {
+			check.Passed, check.Message = false, newValidationError(err, registered, opt, now).Error()
+		}
+		checks = append(checks, check)
+	}
+	return checks
+}
+
+// Valid returns true if all the checks which are not skipped pass.
+func (i *Inspection) Valid() bool {
+	for _, check := range i.Checks {
+		if !check.Passed && !check.Skipped {
+			return false
+		}
+	}
+	return true
+}
+
+// Failures returns the checks which fail.
+func (i *Inspection) Failures() []*InspectionCheck {
+	var failures []*InspectionCheck
+	for _, check := range i.Checks {
+		if !check.Passed && !check.Skipped {
+			failures = append(failures, check)
+		}
+	}
+	return failures
+}
+
+// String returns the human-readable inspection result, including header, claims, time based claims and the validation report.
+func (i *Inspection) String() string {
+	sb := strings.Builder{}
+	sb.WriteString(fmt.Sprintf("Algorithm: %s\n", i.Algorithm))
+	if i.KeyID != "" {
+		sb.WriteString(fmt.Sprintf("Key ID: %s\n", i.KeyID))
+	}
+	for _, t := range []struct {
+		name string
+		time *time.Time
+	}{{"Issued at", i.IssuedAt}, {"Not before", i.NotBefore}, {"Expires at", i.ExpiresAt}} {
+		if t.time != nil {
+			sb.WriteString(fmt.Sprintf("%s: %s\n", t.name, humanizeTime(*t.time, i.Now)))
+		}
+	}
+	header, _ := json.Marshal(i.Header) // keys are sorted
+	claims, _ := json.Marshal(i.Claims)
+	sb.WriteString(fmt.Sprintf("Header: %s\nClaims: %s\n", header, claims))
+	for _, check := range i.Checks {
+		status := "PASS"
+		if check.Skipped {
+			status = "SKIP"
+		} else if !check.Passed {
+			status = "FAIL"
+		}
+		sb.WriteString(fmt.Sprintf("[%s] %s", status, check.Name))
+		if check.Message != "" {
+			sb.WriteString(": " + check.Message)
+		}
+		sb.WriteString("\n")
+	}
+	return strings.TrimSuffix(sb.String(), "\n")
+}
+
+// humanizeTime formats given time in RFC3339 with the relative duration to now, such as "2021-10-01T00:00:00Z (1h0m0s ago)".
+func humanizeTime(t, now time.Time) string {
+	formatted := t.UTC().Format(time.RFC3339)
+	d := t.Sub(now).Round(time.Second)
+	switch {
+	case d > 0:
+		return fmt.Sprintf("%s (in %v)", formatted, d)
+	case d < 0:
+		return fmt.Sprintf("%s (%v ago)", formatted, -d)
+	}
+	return formatted + " (now)"
+}
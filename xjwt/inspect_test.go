@@ -0,0 +1,90 @@
+package xjwt
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/Aoi-hosizora/ahlib/xtesting"
+	"github.com/golang-jwt/jwt/v4"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestInspect(t *testing.T) {
+	testKeys()
+	now := time.Unix(1600000000, 0)
+	timeFunc := WithTimeFunc(func() time.Time { return now })
+	ks := NewKeySet(time.Hour)
+	xtesting.Nil(t, ks.Rotate(&Key{ID: "k1", Method: jwt.SigningMethodRS256, Key: testRSAKey}))
+	claims := &jwt.RegisteredClaims{ID: "id", Issuer: "iss", Subject: "sub", IssuedAt: jwt.NewNumericDate(now.Add(-2 * time.Hour)), ExpiresAt: jwt.NewNumericDate(now.Add(-time.Hour))}
+	token, err := ks.GenerateToken(claims)
+	xtesting.Nil(t, err)
+
+	// decoding
+	for _, invalid := range []string{"", "a.b", "a.b.c", "e30.a.b", "W10.e30.", "e30.W10."} {
+		_, err := Inspect(invalid, nil)
+		xtesting.True(t, IsTokenInvalidError(err))
+	}
+
+	// report
+	i, err := Inspect(token, ks.Keyfunc, timeFunc, WithIssuer("other"))
+	xtesting.Nil(t, err)
+	xtesting.Equal(t, i.Algorithm, "RS256")
+	xtesting.Equal(t, i.KeyID, "k1")
+	xtesting.Equal(t, i.Header["typ"], "JWT")
+	xtesting.Equal(t, i.Claims["sub"], "sub")
+	xtesting.Equal(t, i.Claims["exp"], json.Number("1599996400"))
+	xtesting.True(t, i.IssuedAt.Equal(now.Add(-2*time.Hour)))
+	xtesting.True(t, i.ExpiresAt.Equal(now.Add(-time.Hour)))
+	xtesting.Nil(t, i.NotBefore)
+	xtesting.False(t, i.Valid())
+	xtesting.Equal(t, len(i.Checks), 4)
+	xtesting.Equal(t, len(i.Failures()), 2)
+	xtesting.Equal(t, *i.Checks[0], InspectionCheck{Name: "signature", Passed: true})
+	xtesting.Equal(t, *i.Checks[1], InspectionCheck{Name: "exp", Message: "token is expired by 1h0m0s"})
+	xtesting.Equal(t, *i.Checks[2], InspectionCheck{Name: "iat", Passed: true})
+	xtesting.Equal(t, *i.Checks[3], InspectionCheck{Name: "iss", Message: `token issuer "iss" is not accepted`})
+	lines := strings.Split(i.String(), "\n")
+	xtesting.Equal(t, lines[:4], []string{"Algorithm: RS256", "Key ID: k1", "Issued at: 2020-09-13T10:26:40Z (2h0m0s ago)", "Expires at: 2020-09-13T11:26:40Z (1h0m0s ago)"})
+	xtesting.Equal(t, lines[4], `Header: {"alg":"RS256","kid":"k1","typ":"JWT"}`)
+	xtesting.Equal(t, lines[5], `Claims: {"exp":1599996400,"iat":1599992800,"iss":"iss","jti":"id","sub":"sub"}`)
+	xtesting.Equal(t, lines[6:], []string{"[PASS] signature", "[FAIL] exp: token is expired by 1h0m0s", "[PASS] iat", `[FAIL] iss: token issuer "iss" is not accepted`})
+
+	// all passed
+	store := NewMemoryRevocationStore()
+	i, err = Inspect(token, ks.Keyfunc, WithTimeFunc(func() time.Time { return now.Add(-90 * time.Minute) }), WithIssuer("iss"),
+		WithSubject("sub"), WithAudience(), WithMaxAge(time.Hour), WithRevocationStore(store))
+	xtesting.Nil(t, err)
+	xtesting.True(t, i.Valid())
+	xtesting.Equal(t, len(i.Failures()), 0)
+	xtesting.Equal(t, len(i.Checks), 6)
+	xtesting.True(t, strings.Contains(i.String(), "Expires at: 2020-09-13T11:26:40Z (in 30m0s)"))
+
+	// signature failures and skipping
+	xtesting.Nil(t, store.Revoke(context.Background(), "id", time.Time{}))
+	for _, tc := range []struct {
+		giveKeyfunc jwt.Keyfunc
+		giveOpts    []ParseOption
+		wantCheck   InspectionCheck
+	}{
+		{nil, nil, InspectionCheck{Name: "signature", Skipped: true, Message: "no keyfunc was provided"}},
+		{NewKeySet(0).Keyfunc, nil, InspectionCheck{Name: "signature", Message: ErrKeyNotFound.Error()}},
+		{ks.Keyfunc, []ParseOption{WithAllowedAlgorithms("RS512")}, InspectionCheck{Name: "signature", Message: "signing method RS256 is invalid, allowed: RS512"}},
+	} {
+		i, err := Inspect(token, tc.giveKeyfunc, append(tc.giveOpts, timeFunc, WithRevocationStore(store))...)
+		xtesting.Nil(t, err)
+		xtesting.Equal(t, *i.Checks[0], tc.wantCheck)
+		xtesting.Equal(t, *i.Checks[len(i.Checks)-1], InspectionCheck{Name: "jti", Message: `token "id" has been revoked`})
+	}
+
+	// invalid registered claims
+	i, err = Inspect("e30.eyJhdWQiOjF9.", nil)
+	xtesting.Nil(t, err)
+	xtesting.Equal(t, i.Claims["aud"], json.Number("1"))
+	xtesting.Equal(t, i.Checks[1].Name, "claims")
+	xtesting.False(t, i.Valid())
+
+	// time
+	xtesting.Equal(t, humanizeTime(now, now), "2020-09-13T12:26:40Z (now)")
+	xtesting.Equal(t, humanizeTime(now.Add(1500*time.Millisecond), now), "2020-09-13T12:26:41Z (in 2s)")
+}
@@ -0,0 +1,389 @@
+package xjwt
+
+import (
+	"crypto"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"github.com/golang-jwt/jwt/v4"
+	"strings"
+	"time"
+)
+
+// Key management algorithms and content encryption algorithms of JWE, see RFC 7518.
+const (
+	// JWEAlgDirect represents "dir" key management algorithm, which uses the shared symmetric key as the content encryption key directly.
+	JWEAlgDirect = "dir"
+
+	// JWEAlgRSAOAEP256 represents "RSA-OAEP-256" key management algorithm, which encrypts the random content encryption key using
+	// RSAES-OAEP with SHA-256.
+	JWEAlgRSAOAEP256 = "RSA-OAEP-256"
+
+	// JWEAlgECDHES represents "ECDH-ES" key management algorithm, which derives the content encryption key by Elliptic Curve
+	// Diffie-Hellman Ephemeral Static key agreement and Concat KDF, P-256, P-384 and P-521 curves are supported.
+	JWEAlgECDHES = "ECDH-ES"
+
+	// JWEEncA128GCM represents "A128GCM" content encryption algorithm, which uses AES-GCM with 128-bit key.
+	JWEEncA128GCM = "A128GCM"
+
+	// JWEEncA192GCM represents "A192GCM" content encryption algorithm, which uses AES-GCM with 192-bit key.
+	JWEEncA192GCM = "A192GCM"
+
+	// JWEEncA256GCM represents "A256GCM" content encryption algorithm, which uses AES-GCM with 256-bit key.
+	JWEEncA256GCM = "A256GCM"
+)
+
+var (
+	errUnsupportedJWEAlg = errors.New("xjwt: unsupported jwe key management algorithm")
+	errUnsupportedJWEEnc = errors.New("xjwt: unsupported jwe content encryption algorithm")
+	errInvalidJWEKey     = errors.New("xjwt: invalid key for jwe algorithm")
+	errInvalidJWE        = errors.New("xjwt: invalid jwe")
+	errJWEDecryption     = errors.New("xjwt: jwe decryption failed")
+	errNotNestedJWT      = errors.New("xjwt: jwe payload is not a nested jwt")
+)
+
+// jweHeader represents the JOSE header of JWE used when decrypting.
+type jweHeader struct {
+	Alg  string   `json:"alg"`
+	Enc  string   `json:"enc"`
+	Cty  string   `json:"cty,omitempty"`
+	Zip  string   `json:"zip,omitempty"`
+	Crit []string `json:"crit,omitempty"`
+	EPK  *JWK     `json:"epk,omitempty"`
+	APU  string   `json:"apu,omitempty"`
+	APV  string   `json:"apv,omitempty"`
+}
+
+// jweKeySize returns the content encryption key size in bytes of given content encryption algorithm.
+func jweKeySize(enc string) (int, error) {
+	switch enc {
+	case JWEEncA128GCM:
+		return 16, nil
+	case JWEEncA192GCM:
+		return 24, nil
+	case JWEEncA256GCM:
+		return 32, nil
+	}
+	return 0, errUnsupportedJWEEnc
+}
+
+// EncryptToken encrypts given payload to a JWE compact serialization string, using given key management algorithm, content encryption
+// algorithm and key. The key must be []byte whose length matches the content encryption algorithm for JWEAlgDirect, *rsa.PublicKey for
+// JWEAlgRSAOAEP256 and *ecdsa.PublicKey for JWEAlgECDHES, and private keys will be converted to their public keys. GenerateOption-s are
+// used to set JWE headers, such as WithKeyID, note that "alg", "enc" and "epk" headers cannot be overwritten.
+func EncryptToken(payload []byte, alg, enc string, key interface{}, options ...GenerateOption) (string, error) {
+	keySize, err := jweKeySize(enc)
+	if err != nil {
+		return "", err
+	}
+	header := make(map[string]interface{})
+	for k, v := range newGenerateOptions(options).headers {
+		header[k] = v
+	}
+	header["alg"] = alg
+	header["enc"] = enc
+
+	var cek, encryptedKey []byte
+	switch alg {
+	case JWEAlgDirect:
+		secret, ok := key.([]byte)
+		if !ok || len(secret) != keySize {
+			return "", errInvalidJWEKey
+		}
+		cek = secret
+	case JWEAlgRSAOAEP256:
+		pub, ok := verificationKey(key).(*rsa.PublicKey)
+		if !ok {
+			return "", errInvalidJWEKey
+		}
+		cek = make([]byte, keySize)
+		if _, err = rand.Read(cek); err != nil {
+			return "", err
+		}
+		if encryptedKey, err = rsa.EncryptOAEP(sha256.New(), rand.Reader, pub, cek, nil); err != nil {
+			return "", err
+		}
+	case JWEAlgECDHES:
+		pub, ok := verificationKey(key).(*ecdsa.PublicKey)
+		if !ok || pub.Curve == nil {
+			return "", errInvalidJWEKey
+		}
+		ephemeral, err := ecdsa.GenerateKey(pub.Curve, rand.Reader)
+		if err != nil {
+			return "", err
+		}
+		epk, err := NewJWK("", "", &ephemeral.PublicKey)
+		if err != nil {
+			return "", errInvalidJWEKey
+		}
+		epk.Use = ""
+		header["epk"] = epk
+		delete(header, "apu")
+		delete(header, "apv")
+		z, err := ecdhSharedSecret(pub, ephemeral)
+		if err != nil {
+			return "", err
+		}
+		cek = concatKDF(z, enc, nil, nil, keySize)
+	default:
+		return "", errUnsupportedJWEAlg
+	}
+
+	headerBytes, err := json.Marshal(header)
+	if err != nil {
+		return "", err
+	}
+	encodedHeader := base64.RawURLEncoding.EncodeToString(headerBytes)
+	gcm, err := newGCM(cek)
+	if err != nil {
+		return "", err
+	}
+	iv := make([]byte, gcm.NonceSize())
+	if _, err = rand.Read(iv); err != nil {
+		return "", err
+	}
+	sealed := gcm.Seal(nil, iv, payload, []byte(encodedHeader))
+	ciphertext, tag := sealed[:len(sealed)-gcm.Overhead()], sealed[len(sealed)-gcm.Overhead():]
+
+	return strings.Join([]string{
+		encodedHeader,
+		base64.RawURLEncoding.EncodeToString(encryptedKey),
+		base64.RawURLEncoding.EncodeToString(iv),
+		base64.RawURLEncoding.EncodeToString(ciphertext),
+		base64.RawURLEncoding.EncodeToString(tag),
+	}, "."), nil
+}
+
+// DecryptToken decrypts given JWE compact serialization string using given key, and returns the payload and the "cty" header. The key
+// management algorithm is determined by the key type, that is, JWEAlgDirect for []byte, JWEAlgRSAOAEP256 for *rsa.PrivateKey (or
+// crypto.Decrypter with RSA public key) and JWEAlgECDHES for *ecdsa.PrivateKey, tokens with other algorithms will be rejected. Returned
+// errors are ValidationError, and IsTokenInvalidError will be true for them.
+func DecryptToken(token string, key interface{}) ([]byte, string, error) {
+	payload, cty, err := decryptToken(token, key)
+	if err != nil {
+		if _, ok := err.(*jwt.ValidationError); !ok {
+			err = &jwt.ValidationError{Inner: err, Errors: jwt.ValidationErrorUnverifiable}
+		}
+		return nil, "", newValidationError(err, nil, nil, time.Time{})
+	}
+	return payload, cty, nil
+}
+
+// decryptToken is the implementation of DecryptToken.
+func decryptToken(token string, key interface{}) ([]byte, string, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 5 {
+		return nil, "", jwt.NewValidationError("token contains an invalid number of segments", jwt.ValidationErrorMalformed)
+	}
+	segments := make([][]byte, 5)
+	for i, part := range parts {
+		bs, err := base64.RawURLEncoding.DecodeString(part)
+		if err != nil {
+			return nil, "", &jwt.ValidationError{Inner: err, Errors: jwt.ValidationErrorMalformed}
+		}
+		segments[i] = bs
+	}
+	header := &jweHeader{}
+	if err := json.Unmarshal(segments[0], header); err != nil {
+		return nil, "", &jwt.ValidationError{Inner: err, Errors: jwt.ValidationErrorMalformed}
+	}
+	if header.Zip != "" || len(header.Crit) > 0 {
+		return nil, "", &jwt.ValidationError{Inner: errInvalidJWE, Errors: jwt.ValidationErrorMalformed}
+	}
+	keySize, err := jweKeySize(header.Enc)
+	if err != nil {
+		return nil, "", err
+	}
+
+	// key management
+	var cek []byte
+	switch k := key.(type) {
+	case []byte:
+		if header.Alg != JWEAlgDirect {
+			return nil, "", newAlgorithmValidationError(header.Alg, []string{JWEAlgDirect})
+		}
+		if len(segments[1]) != 0 || len(k) != keySize {
+			return nil, "", errInvalidJWEKey
+		}
+		cek = k
+	case *ecdsa.PrivateKey:
+		if header.Alg != JWEAlgECDHES {
+			return nil, "", newAlgorithmValidationError(header.Alg, []string{JWEAlgECDHES})
+		}
+		if len(segments[1]) != 0 || header.EPK == nil || header.EPK.KeyType != "EC" {
+			return nil, "", errInvalidJWE
+		}
+		epk, err := header.EPK.Key()
+		if err != nil {
+			return nil, "", err
+		}
+		pub := epk.(*ecdsa.PublicKey)
+		apu, err := base64.RawURLEncoding.DecodeString(header.APU)
+		if err != nil {
+			return nil, "", errInvalidJWE
+		}
+		apv, err := base64.RawURLEncoding.DecodeString(header.APV)
+		if err != nil {
+			return nil, "", errInvalidJWE
+		}
+		z, err := ecdhSharedSecret(pub, k) // point is checked to be on the curve
+		if err != nil {
+			return nil, "", err
+		}
+		cek = concatKDF(z, header.Enc, apu, apv, keySize)
+	case crypto.Decrypter:
+		if _, ok := k.Public().(*rsa.PublicKey); !ok {
+			return nil, "", errInvalidJWEKey
+		}
+		if header.Alg != JWEAlgRSAOAEP256 {
+			return nil, "", newAlgorithmValidationError(header.Alg, []string{JWEAlgRSAOAEP256})
+		}
+		// use a random key when the encrypted key is invalid, to make all the failures indistinguishable, see RFC 7516 section 11.5
+		randomCEK := make([]byte, keySize)
+		if _, err = rand.Read(randomCEK); err != nil {
+			return nil, "", err
+		}
+		if cek, err = k.Decrypt(rand.Reader, segments[1], &rsa.OAEPOptions{Hash: crypto.SHA256}); err != nil || len(cek) != keySize {
+			cek = randomCEK
+		}
+	default:
+		return nil, "", errInvalidJWEKey
+	}
+
+	// content decryption
+	gcm, err := newGCM(cek)
+	if err != nil {
+		return nil, "", err
+	}
+	if len(segments[2]) != gcm.NonceSize() || len(segments[4]) != gcm.Overhead() {
+		return nil, "", &jwt.ValidationError{Inner: errInvalidJWE, Errors: jwt.ValidationErrorMalformed}
+	}
+	sealed := append(segments[3][:len(segments[3]):len(segments[3])], segments[4]...)
+	payload, err := gcm.Open(nil, segments[2], sealed, []byte(parts[0]))
+	if err != nil {
+		return nil, "", &jwt.ValidationError{Inner: errJWEDecryption, Errors: jwt.ValidationErrorSignatureInvalid}
+	}
+	return payload, header.Cty, nil
+}
+
+// newGCM creates an AES-GCM cipher.AEAD with given key.
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// concatKDF derives a key from shared secret using the Concat KDF defined in NIST SP 800-56A with SHA-256, see RFC 7518 section 4.6.2.
+func concatKDF(z []byte, algID string, apu, apv []byte, keySize int) []byte {
+	lengthPrefixed := func(data []byte) []byte {
+		out := make([]byte, 4, 4+len(data))
+		binary.BigEndian.PutUint32(out, uint32(len(data)))
+		return append(out, data...)
+	}
+	otherInfo := lengthPrefixed([]byte(algID))
+	otherInfo = append(otherInfo, lengthPrefixed(apu)...)
+	otherInfo = append(otherInfo, lengthPrefixed(apv)...)
+	suppPubInfo := make([]byte, 4)
+	binary.BigEndian.PutUint32(suppPubInfo, uint32(keySize*8))
+	otherInfo = append(otherInfo, suppPubInfo...)
+
+	out := make([]byte, 0, keySize+sha256.Size)
+	counter := make([]byte, 4)
+	for i := uint32(1); len(out) < keySize; i++ {
+		binary.BigEndian.PutUint32(counter, i)
+		h := sha256.New()
+		_, _ = h.Write(counter)
+		_, _ = h.Write(z)
+		_, _ = h.Write(otherInfo)
+		out = h.Sum(out)
+	}
+	return out[:keySize]
+}
+
+// GenerateEncryptedToken generates an encrypted token (JWE) whose payload is the given jwt.Claims in JSON, see EncryptToken for details
+// of algorithms and key. Note that the token is not signed, so for JWEAlgRSAOAEP256 and JWEAlgECDHES, anyone who has the public key can
+// generate a valid token, please use GenerateNestedToken if the token's issuer needs to be authenticated.
+func GenerateEncryptedToken(alg, enc string, claims jwt.Claims, key interface{}, options ...GenerateOption) (string, error) {
+	opt := newGenerateOptions(options)
+	if opt.autoTokenID {
+		claims = &claimsWithTokenID{Claims: claims}
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	options = append(options[:len(options):len(options)], WithHeader("typ", "JWT"))
+	return EncryptToken(payload, alg, enc, key, options...)
+}
+
+// ParseEncryptedToken decrypts the encrypted token generated by GenerateEncryptedToken using given decryption key and returns the parsed
+// custom jwt.Claims, see DecryptToken for details of key. Claims are validated in the same way as ParseToken, and ParseOption-s except
+// WithAllowedAlgorithms are supported.
+func ParseEncryptedToken(token string, key interface{}, claims jwt.Claims, options ...ParseOption) (jwt.Claims, error) {
+	payload, cty, err := DecryptToken(token, key)
+	if err != nil {
+		return nil, err
+	}
+	opt := newParseOptions(options)
+	if strings.EqualFold(cty, "JWT") {
+		err = &jwt.ValidationError{Inner: errors.New("xjwt: nested jwt must be parsed by ParseNestedToken"), Errors: jwt.ValidationErrorMalformed}
+		return nil, newValidationError(err, nil, opt, opt.now())
+	}
+	if err = json.Unmarshal(payload, claims); err != nil {
+		err = &jwt.ValidationError{Inner: err, Errors: jwt.ValidationErrorMalformed}
+		return nil, newValidationError(err, nil, opt, opt.now())
+	}
+
+	registered, decodeErr := decodeRegisteredClaimsFromPayload(payload)
+	if !opt.validateClaims {
+		if err = claims.Valid(); err != nil {
+			if _, ok := err.(*jwt.ValidationError); !ok {
+				err = &jwt.ValidationError{Inner: err, Errors: jwt.ValidationErrorClaimsInvalid}
+			}
+			return nil, newValidationError(err, registered, opt, opt.now())
+		}
+	}
+	if opt.validateClaims || opt.revocationStore != nil {
+		if decodeErr != nil {
+			return nil, newValidationError(decodeErr, nil, opt, opt.now())
+		}
+		if err = checkRegisteredClaims(registered, claims, opt); err != nil {
+			return nil, newValidationError(err, registered, opt, opt.now())
+		}
+	}
+	return claims, nil
+}
+
+// GenerateNestedToken generates a nested token, that is, a signed token (JWS) generated by GenerateToken and then encrypted (JWE) with
+// "cty" header "JWT". GenerateOption-s are applied to both the inner signed token and the outer JWE header (such as WithKeyID), see
+// GenerateToken and EncryptToken for details of keys.
+func GenerateNestedToken(method jwt.SigningMethod, claims jwt.Claims, signingKey interface{}, alg, enc string, encryptionKey interface{}, options ...GenerateOption) (string, error) {
+	signed, err := GenerateToken(method, claims, signingKey, options...)
+	if err != nil {
+		return "", err
+	}
+	options = append(options[:len(options):len(options)], WithHeader("cty", "JWT"))
+	return EncryptToken([]byte(signed), alg, enc, encryptionKey, options...)
+}
+
+// ParseNestedToken decrypts the nested token generated by GenerateNestedToken using given decryption key, and then parses the inner
+// signed token using given verification key and custom jwt.Claims, see DecryptToken and ParseTokenWithKey for details of keys.
+func ParseNestedToken(token string, decryptionKey, verificationKey interface{}, claims jwt.Claims, options ...ParseOption) (*jwt.Token, error) {
+	payload, cty, err := DecryptToken(token, decryptionKey)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.EqualFold(cty, "JWT") {
+		return nil, newValidationError(&jwt.ValidationError{Inner: errNotNestedJWT, Errors: jwt.ValidationErrorMalformed}, nil, nil, time.Time{})
+	}
+	return ParseTokenWithKey(string(payload), verificationKey, claims, options...)
+}
@@ -0,0 +1,246 @@
+package xjwt
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"github.com/Aoi-hosizora/ahlib/xtesting"
+	"github.com/golang-jwt/jwt/v4"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestConcatKDF(t *testing.T) {
+	// rfc 7518 appendix c
+	z := []byte{158, 86, 217, 29, 129, 113, 53, 211, 114, 131, 66, 131, 191, 132, 38, 156, 251, 49, 110, 163, 218, 128, 106, 72, 246, 218, 167, 121, 140, 254, 144, 196}
+	key := concatKDF(z, "A128GCM", []byte("Alice"), []byte("Bob"), 16)
+	xtesting.Equal(t, base64.RawURLEncoding.EncodeToString(key), "VqqN6vgjbSBcIijNcacQGg")
+	xtesting.Equal(t, len(concatKDF(z, "A256GCM", nil, nil, 32)), 32)
+	xtesting.Equal(t, len(concatKDF(z, "x", nil, nil, 48)), 48)
+}
+
+func TestEncryptToken(t *testing.T) {
+	testKeys()
+	payload := []byte("hello world")
+	for _, tc := range []struct {
+		giveAlg        string
+		giveEnc        string
+		giveEncryptKey interface{}
+		giveDecryptKey interface{}
+	}{
+		{JWEAlgDirect, JWEEncA128GCM, []byte("0123456789abcdef"), []byte("0123456789abcdef")},
+		{JWEAlgDirect, JWEEncA192GCM, []byte("0123456789abcdef01234567"), []byte("0123456789abcdef01234567")},
+		{JWEAlgDirect, JWEEncA256GCM, []byte("0123456789abcdef0123456789abcdef"), []byte("0123456789abcdef0123456789abcdef")},
+		{JWEAlgRSAOAEP256, JWEEncA256GCM, &testRSAKey.PublicKey, testRSAKey},
+		{JWEAlgRSAOAEP256, JWEEncA128GCM, testRSAKey, testRSAKey},
+		{JWEAlgECDHES, JWEEncA256GCM, &testEC256Key.PublicKey, testEC256Key},
+		{JWEAlgECDHES, JWEEncA128GCM, &testEC384Key.PublicKey, testEC384Key},
+		{JWEAlgECDHES, JWEEncA256GCM, testEC521Key, testEC521Key},
+	} {
+		token, err := EncryptToken(payload, tc.giveAlg, tc.giveEnc, tc.giveEncryptKey, WithKeyID("kid"), WithHeader("alg", "none"))
+		xtesting.Nil(t, err)
+		parts := strings.Split(token, ".")
+		xtesting.Equal(t, len(parts), 5)
+		header := map[string]interface{}{}
+		bs, _ := base64.RawURLEncoding.DecodeString(parts[0])
+		xtesting.Nil(t, json.Unmarshal(bs, &header))
+		xtesting.Equal(t, header["alg"], tc.giveAlg)
+		xtesting.Equal(t, header["enc"], tc.giveEnc)
+		xtesting.Equal(t, header["kid"], "kid")
+
+		decrypted, cty, err := DecryptToken(token, tc.giveDecryptKey)
+		xtesting.Nil(t, err)
+		xtesting.Equal(t, decrypted, payload)
+		xtesting.Equal(t, cty, "")
+
+		// tampered
+		for i := range parts {
+			if parts[i] == "" {
+				continue
+			}
+			tampered := make([]string, 5)
+			copy(tampered, parts)
+			bs, _ := base64.RawURLEncoding.DecodeString(parts[i])
+			bs[len(bs)-1] ^= 1
+			tampered[i] = base64.RawURLEncoding.EncodeToString(bs)
+			_, _, err = DecryptToken(strings.Join(tampered, "."), tc.giveDecryptKey)
+			xtesting.NotNil(t, err)
+			xtesting.True(t, IsTokenInvalidError(err))
+		}
+	}
+
+	// invalid encrypt
+	for _, tc := range []struct {
+		giveAlg string
+		giveEnc string
+		giveKey interface{}
+	}{
+		{JWEAlgDirect, "A256CBC-HS512", []byte("0123456789abcdef0123456789abcdef")},
+		{"A256KW", JWEEncA256GCM, []byte("0123456789abcdef0123456789abcdef")},
+		{JWEAlgDirect, JWEEncA256GCM, []byte("0123456789abcdef")},
+		{JWEAlgDirect, JWEEncA256GCM, testRSAKey},
+		{JWEAlgRSAOAEP256, JWEEncA256GCM, testEC256Key},
+		{JWEAlgECDHES, JWEEncA256GCM, testRSAKey},
+		{JWEAlgECDHES, JWEEncA256GCM, &ecdsa.PublicKey{}},
+	} {
+		_, err := EncryptToken(payload, tc.giveAlg, tc.giveEnc, tc.giveKey)
+		xtesting.NotNil(t, err)
+	}
+
+	// invalid decrypt
+	secret := []byte("0123456789abcdef0123456789abcdef")
+	dirToken, _ := EncryptToken(payload, JWEAlgDirect, JWEEncA256GCM, secret)
+	rsaToken, _ := EncryptToken(payload, JWEAlgRSAOAEP256, JWEEncA256GCM, testRSAKey)
+	ecToken, _ := EncryptToken(payload, JWEAlgECDHES, JWEEncA256GCM, testEC256Key)
+	otherEC, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	for _, tc := range []struct {
+		giveToken  string
+		giveKey    interface{}
+		wantAlgErr bool
+	}{
+		{"a.b.c", secret, false},
+		{"a.b.c.d.!", secret, false},
+		{"e30....", secret, false},
+		{dirToken, []byte("0123456789abcdef"), false},
+		{dirToken, []byte("0123456789abcdef0123456789abcdeX"), false},
+		{dirToken, testRSAKey, true},
+		{dirToken, testEC256Key, true},
+		{rsaToken, secret, true},
+		{ecToken, secret, true},
+		{ecToken, testEC384Key, false},
+		{ecToken, otherEC, false},
+		{ecToken, "key", false},
+		{ecToken, testEdKey, false},
+	} {
+		_, _, err := DecryptToken(tc.giveToken, tc.giveKey)
+		xtesting.NotNil(t, err)
+		xtesting.True(t, IsTokenInvalidError(err))
+		xtesting.Equal(t, IsAlgorithmError(err), tc.wantAlgErr)
+	}
+
+	// corrupted encrypted key and corrupted tag are indistinguishable
+	rsaParts := strings.Split(rsaToken, ".")
+	var errs []error
+	for _, i := range []int{1, 4} {
+		for _, corrupt := range []func([]byte) []byte{
+			func(bs []byte) []byte { bs[len(bs)-1] ^= 1; return bs },
+			func(bs []byte) []byte { return bs[:len(bs)-1] },
+		} {
+			tampered := append([]string(nil), rsaParts...)
+			bs, _ := base64.RawURLEncoding.DecodeString(rsaParts[i])
+			tampered[i] = base64.RawURLEncoding.EncodeToString(corrupt(bs))
+			_, _, err := DecryptToken(strings.Join(tampered, "."), testRSAKey)
+			if i == 4 && len(tampered[i]) != len(rsaParts[i]) {
+				continue // malformed tag length
+			}
+			errs = append(errs, err)
+		}
+	}
+	xtesting.Equal(t, len(errs), 3)
+	for _, err := range errs {
+		xtesting.Equal(t, err, errs[0])
+		xtesting.True(t, errors.Is(err, ErrTokenSignatureInvalid))
+		xtesting.False(t, errors.Is(err, ErrTokenUnverifiable))
+	}
+	wrongSize, _ := rsa.EncryptOAEP(sha256.New(), rand.Reader, &testRSAKey.PublicKey, []byte("short"), nil)
+	rsaParts[1] = base64.RawURLEncoding.EncodeToString(wrongSize)
+	_, _, err := DecryptToken(strings.Join(rsaParts, "."), testRSAKey)
+	xtesting.Equal(t, err, errs[0])
+
+	// off-curve public key
+	offCurve := &ecdsa.PublicKey{Curve: elliptic.P256(), X: big.NewInt(1), Y: big.NewInt(1)}
+	_, err = ecdhSharedSecret(offCurve, testEC256Key)
+	xtesting.Equal(t, err, errInvalidJWEKey)
+	_, err = ecdhSharedSecret(&testEC384Key.PublicKey, testEC256Key)
+	xtesting.Equal(t, err, errInvalidJWEKey)
+	_, err = EncryptToken(payload, JWEAlgECDHES, JWEEncA256GCM, offCurve)
+	xtesting.Equal(t, err, errInvalidJWEKey)
+	z1, err := ecdhSharedSecret(&testEC256Key.PublicKey, otherEC)
+	xtesting.Nil(t, err)
+	z2, err := ecdhSharedSecret(&otherEC.PublicKey, testEC256Key)
+	xtesting.Nil(t, err)
+	xtesting.Equal(t, z1, z2)
+	xtesting.Equal(t, len(z1), 32)
+
+	// invalid epk and unsupported headers
+	for _, header := range []string{
+		`{"alg":"ECDH-ES","enc":"A256GCM"}`,
+		`{"alg":"ECDH-ES","enc":"A256GCM","epk":{"kty":"EC","crv":"P-256","x":"AQ","y":"AQ"}}`,
+		`{"alg":"ECDH-ES","enc":"A256GCM","epk":{"kty":"OKP","crv":"Ed25519","x":"AQ"}}`,
+		`{"alg":"dir","enc":"A256GCM","zip":"DEF"}`,
+		`{"alg":"dir","enc":"A256GCM","crit":["exp"]}`,
+	} {
+		parts := strings.Split(ecToken, ".")
+		parts[0] = base64.RawURLEncoding.EncodeToString([]byte(header))
+		_, _, err := DecryptToken(strings.Join(parts, "."), testEC256Key)
+		xtesting.NotNil(t, err)
+		_, _, err = DecryptToken(strings.Join(parts, "."), secret)
+		xtesting.NotNil(t, err)
+	}
+}
+
+func TestEncryptedAndNestedToken(t *testing.T) {
+	testKeys()
+	secret := []byte("0123456789abcdef0123456789abcdef")
+	now := time.Now()
+	claims := &jwt.RegisteredClaims{Issuer: "auth", Subject: "pii", ExpiresAt: jwt.NewNumericDate(now.Add(time.Hour))}
+
+	// encrypted
+	token, err := GenerateEncryptedToken(JWEAlgRSAOAEP256, JWEEncA256GCM, claims, testRSAKey)
+	xtesting.Nil(t, err)
+	parsed, err := ParseEncryptedToken(token, testRSAKey, &jwt.RegisteredClaims{}, WithIssuer("auth"))
+	xtesting.Nil(t, err)
+	xtesting.Equal(t, parsed.(*jwt.RegisteredClaims).Subject, "pii")
+	xtesting.Equal(t, len(parsed.(*jwt.RegisteredClaims).ID), 32)
+	_, err = ParseEncryptedToken(token, testRSAKey, &jwt.RegisteredClaims{}, WithIssuer("other"))
+	xtesting.True(t, IsIssuerError(err))
+	_, err = ParseEncryptedToken(token, testEC256Key, &jwt.RegisteredClaims{})
+	xtesting.True(t, IsTokenInvalidError(err))
+	store := NewMemoryRevocationStore()
+	_, err = ParseEncryptedToken(token, testRSAKey, &jwt.RegisteredClaims{}, WithRevocationStore(store))
+	xtesting.Nil(t, err)
+	xtesting.Nil(t, store.Revoke(context.Background(), parsed.(*jwt.RegisteredClaims).ID, now.Add(time.Hour)))
+	_, err = ParseEncryptedToken(token, testRSAKey, &jwt.RegisteredClaims{}, WithRevocationStore(store))
+	xtesting.True(t, IsRevokedError(err))
+	expired, err := GenerateEncryptedToken(JWEAlgDirect, JWEEncA256GCM, &jwt.RegisteredClaims{ExpiresAt: jwt.NewNumericDate(now.Add(-time.Hour))}, secret)
+	xtesting.Nil(t, err)
+	_, err = ParseEncryptedToken(expired, secret, &jwt.RegisteredClaims{})
+	xtesting.True(t, IsExpiredError(err))
+	notJSON, _ := EncryptToken([]byte("x"), JWEAlgDirect, JWEEncA256GCM, secret)
+	_, err = ParseEncryptedToken(notJSON, secret, &jwt.RegisteredClaims{})
+	xtesting.True(t, IsTokenInvalidError(err))
+
+	// nested
+	token, err = GenerateNestedToken(jwt.SigningMethodES256, claims, testEC256Key, JWEAlgECDHES, JWEEncA256GCM, testEC384Key.Public(), WithKeyID("sig"))
+	xtesting.Nil(t, err)
+	tokenObj, err := ParseNestedToken(token, testEC384Key, testEC256Key.Public(), &jwt.RegisteredClaims{}, WithIssuer("auth"))
+	xtesting.Nil(t, err)
+	xtesting.Equal(t, tokenObj.Header["kid"], "sig")
+	outer := map[string]interface{}{}
+	bs, _ := base64.RawURLEncoding.DecodeString(strings.Split(token, ".")[0])
+	xtesting.Nil(t, json.Unmarshal(bs, &outer))
+	xtesting.Equal(t, outer["kid"], "sig")
+	xtesting.Equal(t, outer["cty"], "JWT")
+	xtesting.Equal(t, tokenObj.Claims.(*jwt.RegisteredClaims).Subject, "pii")
+	_, err = ParseNestedToken(token, testEC384Key, testEC384Key.Public(), &jwt.RegisteredClaims{})
+	xtesting.True(t, IsAlgorithmError(err))
+	_, err = ParseNestedToken(token, testEC256Key, testEC256Key.Public(), &jwt.RegisteredClaims{})
+	xtesting.True(t, IsTokenInvalidError(err))
+	_, err = ParseEncryptedToken(token, testEC384Key, &jwt.RegisteredClaims{})
+	xtesting.True(t, IsTokenInvalidError(err))
+	encrypted, _ := GenerateEncryptedToken(JWEAlgDirect, JWEEncA256GCM, claims, secret)
+	_, err = ParseNestedToken(encrypted, secret, secret, &jwt.RegisteredClaims{})
+	xtesting.True(t, IsTokenInvalidError(err))
+	_, err = GenerateNestedToken(jwt.SigningMethodES256, claims, testRSAKey, JWEAlgDirect, JWEEncA256GCM, secret)
+	xtesting.NotNil(t, err)
+	_, err = GenerateEncryptedToken(JWEAlgDirect, JWEEncA256GCM, claims, []byte("short"))
+	xtesting.NotNil(t, err)
+}
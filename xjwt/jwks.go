@@ -0,0 +1,445 @@
+package xjwt
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/golang-jwt/jwt/v4"
+	"io"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// JWK represents a JSON Web Key defined in RFC 7517, only the public part of RSA ("RSA"), EC ("EC") and OKP ("OKP", Ed25519 only) keys,
+// and symmetric ("oct") keys are supported.
+type JWK struct {
+	// KeyType represents the "kty" (Key Type) parameter, such as "RSA", "EC", "OKP" and "oct".
+	KeyType string `json:"kty"`
+
+	// Use represents the "use" (Public Key Use) parameter, such as "sig" and "enc".
+	Use string `json:"use,omitempty"`
+
+	// Algorithm represents the "alg" (Algorithm) parameter, such as "RS256".
+	Algorithm string `json:"alg,omitempty"`
+
+	// KeyID represents the "kid" (Key ID) parameter, which is matched with the "kid" header of tokens.
+	KeyID string `json:"kid,omitempty"`
+
+	// N represents the base64url encoded modulus of RSA public key.
+	N string `json:"n,omitempty"`
+
+	// E represents the base64url encoded exponent of RSA public key.
+	E string `json:"e,omitempty"`
+
+	// Curve represents the curve of EC ("P-256", "P-384" and "P-521") or OKP ("Ed25519") public key.
+	Curve string `json:"crv,omitempty"`
+
+	// X represents the base64url encoded x coordinate of EC public key, or the OKP public key.
+	X string `json:"x,omitempty"`
+
+	// Y represents the base64url encoded y coordinate of EC public key.
+	Y string `json:"y,omitempty"`
+
+	// K represents the base64url encoded symmetric key.
+	K string `json:"k,omitempty"`
+}
+
+// JWKS represents a JSON Web Key Set defined in RFC 7517.
+type JWKS struct {
+	// Keys represents the JWK-s in the set.
+	Keys []*JWK `json:"keys"`
+}
+
+var (
+	errUnsupportedJWKKey = errors.New("xjwt: unsupported key type for jwk")
+	errInvalidJWK        = errors.New("xjwt: invalid jwk")
+)
+
+// NewJWK creates a JWK from given key, with given key id and algorithm. The key can be []byte secret, *rsa.PublicKey, *ecdsa.PublicKey
+// (P-256, P-384 or P-521), ed25519.PublicKey, and private keys (or crypto.Signer) will be converted to their public keys.
+func NewJWK(kid, alg string, key interface{}) (*JWK, error) {
+	jwk := &JWK{KeyID: kid, Algorithm: alg}
+	switch k := verificationKey(key).(type) {
+	case []byte:
+		if len(k) == 0 {
+			return nil, errInvalidJWK
+		}
+		jwk.KeyType = "oct"
+		jwk.K = base64.RawURLEncoding.EncodeToString(k)
+	case *rsa.PublicKey:
+		jwk.KeyType = "RSA"
+		jwk.Use = "sig"
+		jwk.N = base64.RawURLEncoding.EncodeToString(k.N.Bytes())
+		jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(k.E)).Bytes())
+	case *ecdsa.PublicKey:
+		if k.Curve == nil {
+			return nil, errUnsupportedJWKKey
+		}
+		params := k.Curve.Params()
+		switch params.Name {
+		case "P-256", "P-384", "P-521":
+		default:
+			return nil, errUnsupportedJWKKey
+		}
+		size := (params.BitSize + 7) / 8
+		jwk.KeyType = "EC"
+		jwk.Use = "sig"
+		jwk.Curve = params.Name
+		jwk.X = base64.RawURLEncoding.EncodeToString(padBytes(k.X.Bytes(), size))
+		jwk.Y = base64.RawURLEncoding.EncodeToString(padBytes(k.Y.Bytes(), size))
+	case ed25519.PublicKey:
+		jwk.KeyType = "OKP"
+		jwk.Use = "sig"
+		jwk.Curve = "Ed25519"
+		jwk.X = base64.RawURLEncoding.EncodeToString(k)
+	default:
+		return nil, errUnsupportedJWKKey
+	}
+	return jwk, nil
+}
+
+// padBytes left-pads given bytes with zero to given size.
+func padBytes(b []byte, size int) []byte {
+	if len(b) >= size {
+		return b
+	}
+	out := make([]byte, size)
+	copy(out[size-len(b):], b)
+	return out
+}
+
+// Key decodes JWK to key, returns []byte for "oct", *rsa.PublicKey for "RSA", *ecdsa.PublicKey for "EC" and ed25519.PublicKey for "OKP".
+func (j *JWK) Key() (interface{}, error) {
+	decode := func(s string) ([]byte, error) {
+		if s == "" {
+			return nil, errInvalidJWK
+		}
+		return base64.RawURLEncoding.DecodeString(s)
+	}
+
+	switch j.KeyType {
+	case "oct":
+		return decode(j.K)
+	case "RSA":
+		n, err := decode(j.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decode(j.E)
+		if err != nil {
+			return nil, err
+		}
+		if len(e) > 4 {
+			return nil, errInvalidJWK
+		}
+		eInt := int(new(big.Int).SetBytes(e).Int64())
+		if eInt < 2 {
+			return nil, errInvalidJWK
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: eInt}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch j.Curve {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, errUnsupportedJWKKey
+		}
+		x, err := decode(j.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decode(j.Y)
+		if err != nil {
+			return nil, err
+		}
+		key := &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		if !curve.IsOnCurve(key.X, key.Y) {
+			return nil, errInvalidJWK
+		}
+		return key, nil
+	case "OKP":
+		if j.Curve != "Ed25519" {
+			return nil, errUnsupportedJWKKey
+		}
+		x, err := decode(j.X)
+		if err != nil {
+			return nil, err
+		}
+		if len(x) != ed25519.PublicKeySize {
+			return nil, errInvalidJWK
+		}
+		return ed25519.PublicKey(x), nil
+	}
+	return nil, errUnsupportedJWKKey
+}
+
+// Key returns the JWK with given key id from JWKS.
+func (j *JWKS) Key(kid string) (*JWK, bool) {
+	for _, key := range j.Keys {
+		if key.KeyID == kid {
+			return key, true
+		}
+	}
+	return nil, false
+}
+
+// NewJWKSFromKeySet creates a JWKS which contains the public part of all the keys in KeySet, symmetric keys will be skipped.
+func NewJWKSFromKeySet(keySet *KeySet) (*JWKS, error) {
+	jwks := &JWKS{Keys: make([]*JWK, 0)}
+	for _, key := range keySet.Keys() {
+		if _, ok := key.Method.(*jwt.SigningMethodHMAC); ok {
+			continue
+		}
+		jwk, err := NewJWK(key.ID, key.Method.Alg(), key.Key)
+		if err != nil {
+			return nil, err
+		}
+		jwks.Keys = append(jwks.Keys, jwk)
+	}
+	return jwks, nil
+}
+
+// NewJWKSHandler creates a http.Handler which serves the public part of given KeySet as JWKS in JSON, usually mounted at
+// "/.well-known/jwks.json". Note that symmetric keys are never served.
+func NewJWKSHandler(keySet *KeySet) http.Handler {
+	if keySet == nil {
+		panic(panicNilKeySet)
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+		jwks, err := NewJWKSFromKeySet(keySet)
+		if err != nil {
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+		bs, err := json.Marshal(jwks)
+		if err != nil {
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		if r.Method == http.MethodGet {
+			_, _ = w.Write(bs)
+		}
+	})
+}
+
+// minRemoteRSAKeyBits is the minimum size of RSA keys accepted from remote JWKS, see RFC 7518 section 3.3.
+const minRemoteRSAKeyBits = 2048
+
+const (
+	panicNilKeySet   = "xjwt: nil key set"
+	panicEmptyJWKURL = "xjwt: empty jwks url"
+)
+
+// JWKSFetcherOption represents an option for NewJWKSFetcher, can be created by WithXXX functions.
+type JWKSFetcherOption func(*jwksFetcherOptions)
+
+// jwksFetcherOptions is a type of NewJWKSFetcher's options.
+type jwksFetcherOptions struct {
+	httpClient         *http.Client
+	refreshInterval    time.Duration
+	minRefetchInterval time.Duration
+}
+
+// WithJWKSHTTPClient creates a JWKSFetcherOption to specify the http.Client used to fetch JWKS, defaults to a client with 10s timeout.
+func WithJWKSHTTPClient(client *http.Client) JWKSFetcherOption {
+	return func(o *jwksFetcherOptions) {
+		o.httpClient = client
+	}
+}
+
+// WithJWKSRefreshInterval creates a JWKSFetcherOption to specify the interval of refreshing cached JWKS, defaults to 1 hour.
+func WithJWKSRefreshInterval(interval time.Duration) JWKSFetcherOption {
+	return func(o *jwksFetcherOptions) {
+		o.refreshInterval = interval
+	}
+}
+
+// WithJWKSMinRefetchInterval creates a JWKSFetcherOption to specify the minimum interval of refetching JWKS when the "kid" is not found
+// in cache, this is used to prevent refetching too frequently caused by tokens with unknown "kid", defaults to 1 minute, and negative
+// value means no limit.
+func WithJWKSMinRefetchInterval(interval time.Duration) JWKSFetcherOption {
+	return func(o *jwksFetcherOptions) {
+		o.minRefetchInterval = interval
+	}
+}
+
+// JWKSFetcher represents a fetcher which fetches JWKS from remote url and caches the decoded keys. The cache will be refreshed after
+// refresh interval, or when the "kid" is not found in cache (at most once per minimum refetch interval).
+// Example:
+// 	fetcher := NewJWKSFetcher("https://example.com/.well-known/jwks.json")
+// 	token, err := ParseTokenWithKeyfunc(signedToken, fetcher.Keyfunc, &jwt.StandardClaims{})
+type JWKSFetcher struct {
+	url     string
+	options *jwksFetcherOptions
+	now     func() time.Time
+
+	mu        sync.RWMutex
+	keys      map[string]*jwksFetcherKey
+	fetchedAt time.Time
+
+	fetchMu sync.Mutex
+}
+
+// jwksFetcherKey represents a decoded key stored in JWKSFetcher.
+type jwksFetcherKey struct {
+	jwk *JWK
+	key interface{}
+}
+
+// NewJWKSFetcher creates a JWKSFetcher with given url and JWKSFetcherOption-s, note that no request will be sent until the first use.
+func NewJWKSFetcher(url string, options ...JWKSFetcherOption) *JWKSFetcher {
+	if url == "" {
+		panic(panicEmptyJWKURL)
+	}
+	opt := &jwksFetcherOptions{}
+	for _, o := range options {
+		if o != nil {
+			o(opt)
+		}
+	}
+	if opt.httpClient == nil {
+		opt.httpClient = &http.Client{Timeout: 10 * time.Second}
+	}
+	if opt.refreshInterval <= 0 {
+		opt.refreshInterval = time.Hour
+	}
+	if opt.minRefetchInterval < 0 {
+		opt.minRefetchInterval = 0
+	} else if opt.minRefetchInterval == 0 {
+		opt.minRefetchInterval = time.Minute
+	}
+	return &JWKSFetcher{url: url, options: opt, now: time.Now, keys: make(map[string]*jwksFetcherKey)}
+}
+
+// Refresh fetches JWKS from remote url and replaces the cached keys, keys with unsupported type or invalid value will be skipped. Note
+// that symmetric ("oct") keys are also skipped, because a secret published in a remote JWKS can be used by anyone to sign tokens, and
+// so are RSA keys smaller than 2048 bits.
+func (j *JWKSFetcher) Refresh(ctx context.Context) error {
+	j.fetchMu.Lock()
+	defer j.fetchMu.Unlock()
+	return j.refresh(ctx)
+}
+
+// refresh is the implementation of Refresh, caller must hold the fetchMu lock.
+func (j *JWKSFetcher) refresh(ctx context.Context) error {
+	now := j.now()
+	j.mu.Lock()
+	j.fetchedAt = now // also updated when failed, to prevent refetching too frequently
+	j.mu.Unlock()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, j.url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := j.options.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		_, _ = io.Copy(ioutil.Discard, resp.Body)
+		return fmt.Errorf("xjwt: unexpected status code %d when fetching jwks", resp.StatusCode)
+	}
+	jwks := &JWKS{}
+	if err = json.NewDecoder(resp.Body).Decode(jwks); err != nil {
+		return err
+	}
+
+	keys := make(map[string]*jwksFetcherKey, len(jwks.Keys))
+	for _, jwk := range jwks.Keys {
+		if jwk == nil || (jwk.Use != "" && jwk.Use != "sig") || jwk.KeyType == "oct" {
+			continue // only asymmetric keys are accepted from remote
+		}
+		key, err := jwk.Key()
+		if err != nil {
+			continue
+		}
+		if k, ok := key.(*rsa.PublicKey); ok && k.N.BitLen() < minRemoteRSAKeyBits {
+			continue // too weak to be trusted
+		}
+		keys[jwk.KeyID] = &jwksFetcherKey{jwk: jwk, key: key}
+	}
+	j.mu.Lock()
+	j.keys = keys
+	j.mu.Unlock()
+	return nil
+}
+
+// lookup looks up the key with given key id from cache, returns the key and whether the cache needs to be refreshed.
+func (j *JWKSFetcher) lookup(kid string) (*jwksFetcherKey, bool, bool) {
+	j.mu.RLock()
+	defer j.mu.RUnlock()
+	key, ok := j.keys[kid]
+	now := j.now()
+	expired := j.fetchedAt.IsZero() || !now.Before(j.fetchedAt.Add(j.options.refreshInterval))
+	refetchable := expired || !now.Before(j.fetchedAt.Add(j.options.minRefetchInterval))
+	return key, ok, expired || (!ok && refetchable)
+}
+
+// Key returns the decoded key with given key id, the JWKS will be fetched if the cache is expired or the key id is not found.
+func (j *JWKSFetcher) Key(ctx context.Context, kid string) (interface{}, error) {
+	key, err := j.fetchKey(ctx, kid)
+	if err != nil {
+		return nil, err
+	}
+	return key.key, nil
+}
+
+// fetchKey looks up the key with given key id, and refreshes the cache if needed. Note that the stale key will be used if refreshing
+// failed.
+func (j *JWKSFetcher) fetchKey(ctx context.Context, kid string) (*jwksFetcherKey, error) {
+	key, ok, needRefresh := j.lookup(kid)
+	if needRefresh {
+		j.fetchMu.Lock()
+		if _, _, stillNeed := j.lookup(kid); stillNeed { // maybe refreshed by other goroutine
+			if err := j.refresh(ctx); err != nil && !ok {
+				j.fetchMu.Unlock()
+				return nil, err
+			}
+		}
+		j.fetchMu.Unlock()
+		if newKey, newOk, _ := j.lookup(kid); newOk {
+			key, ok = newKey, true
+		}
+	}
+	if !ok {
+		return nil, ErrKeyNotFound
+	}
+	return key, nil
+}
+
+// Keyfunc returns the verification key for given jwt.Token by its "kid" header, the token's algorithm must be the same as the JWK's
+// "alg" if present. This method can be used as jwt.Keyfunc, see ParseTokenWithKeyfunc.
+func (j *JWKSFetcher) Keyfunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	key, err := j.fetchKey(context.Background(), kid)
+	if err != nil {
+		return nil, err
+	}
+	if alg := token.Method.Alg(); key.jwk.Algorithm != "" && key.jwk.Algorithm != alg {
+		return nil, newAlgorithmValidationError(alg, []string{key.jwk.Algorithm})
+	}
+	return key.key, nil
+}
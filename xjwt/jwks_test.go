@@ -0,0 +1,238 @@
+package xjwt
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"errors"
+	"github.com/Aoi-hosizora/ahlib/xtesting"
+	"github.com/golang-jwt/jwt/v4"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestJWK(t *testing.T) {
+	testKeys()
+	for _, tc := range []struct {
+		giveKey interface{}
+		wantKey interface{}
+		wantKty string
+	}{
+		{[]byte("secret"), []byte("secret"), "oct"},
+		{testRSAKey, &testRSAKey.PublicKey, "RSA"},
+		{&testRSAKey.PublicKey, &testRSAKey.PublicKey, "RSA"},
+		{testEC256Key, &testEC256Key.PublicKey, "EC"},
+		{testEC384Key, &testEC384Key.PublicKey, "EC"},
+		{testEC521Key, &testEC521Key.PublicKey, "EC"},
+		{testEdKey, testEdKey.Public(), "OKP"},
+		{opaqueSigner{testEC256Key}, &testEC256Key.PublicKey, "EC"},
+	} {
+		jwk, err := NewJWK("kid", "alg", tc.giveKey)
+		xtesting.Nil(t, err)
+		xtesting.Equal(t, jwk.KeyType, tc.wantKty)
+		bs, err := json.Marshal(jwk)
+		xtesting.Nil(t, err)
+		decoded := &JWK{}
+		xtesting.Nil(t, json.Unmarshal(bs, decoded))
+		xtesting.Equal(t, decoded, jwk)
+		key, err := decoded.Key()
+		xtesting.Nil(t, err)
+		xtesting.Equal(t, key, tc.wantKey)
+	}
+
+	// rfc 7517 appendix a.1
+	ecJWK := &JWK{KeyType: "EC", Curve: "P-256", X: "MKBCTNIcKUSDii11ySs3526iDZ8AiTo7Tu6KPAqv7D4", Y: "4Etl6SRW2YiLUrN5vfvVHuhp7x8PxltmWWlbbM4IFyM"}
+	_, err := ecJWK.Key()
+	xtesting.Nil(t, err)
+
+	// padding of ec coordinates
+	for i := 0; i < 20; i++ {
+		key, _ := ecdsa.GenerateKey(elliptic.P521(), rand.Reader)
+		jwk, err := NewJWK("", "", key)
+		xtesting.Nil(t, err)
+		xtesting.Equal(t, len(jwk.X), 88)
+		xtesting.Equal(t, len(jwk.Y), 88)
+	}
+
+	// invalid
+	for _, give := range []interface{}{nil, []byte{}, "test", &ecdsa.PublicKey{}} {
+		_, err := NewJWK("", "", give)
+		xtesting.NotNil(t, err)
+	}
+	for _, give := range []*JWK{
+		{KeyType: "unknown"},
+		{KeyType: "oct"},
+		{KeyType: "oct", K: "!!!"},
+		{KeyType: "RSA", E: "AQAB"},
+		{KeyType: "RSA", N: "AQAB"},
+		{KeyType: "RSA", N: "AQAB", E: "AQ"},
+		{KeyType: "RSA", N: "AQAB", E: "AQABAQAB"},
+		{KeyType: "EC", Curve: "P-224", X: "AQ", Y: "AQ"},
+		{KeyType: "EC", Curve: "P-256", Y: "AQ"},
+		{KeyType: "EC", Curve: "P-256", X: "AQ"},
+		{KeyType: "EC", Curve: "P-256", X: "AQ", Y: "AQ"},
+		{KeyType: "OKP", Curve: "X25519", X: "AQ"},
+		{KeyType: "OKP", Curve: "Ed25519"},
+		{KeyType: "OKP", Curve: "Ed25519", X: "AQ"},
+	} {
+		_, err := give.Key()
+		xtesting.NotNil(t, err)
+	}
+}
+
+func TestJWKSHandler(t *testing.T) {
+	testKeys()
+	xtesting.PanicWithValue(t, panicNilKeySet, func() { NewJWKSHandler(nil) })
+
+	ks := NewKeySet(time.Hour)
+	xtesting.Nil(t, ks.Add(&Key{ID: "hs", Method: jwt.SigningMethodHS256, Key: []byte("secret")}))
+	xtesting.Nil(t, ks.Add(&Key{ID: "rs", Method: jwt.SigningMethodRS256, Key: testRSAKey}))
+	xtesting.Nil(t, ks.Rotate(&Key{ID: "es", Method: jwt.SigningMethodES256, Key: testEC256Key}))
+	handler := NewJWKSHandler(ks)
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/.well-known/jwks.json", nil))
+	xtesting.Equal(t, rec.Code, http.StatusOK)
+	xtesting.Equal(t, rec.Header().Get("Content-Type"), "application/json")
+	jwks := &JWKS{}
+	xtesting.Nil(t, json.Unmarshal(rec.Body.Bytes(), jwks))
+	xtesting.Equal(t, len(jwks.Keys), 2)
+	_, ok := jwks.Key("hs")
+	xtesting.False(t, ok)
+	jwk, ok := jwks.Key("es")
+	xtesting.True(t, ok)
+	xtesting.Equal(t, jwk.Algorithm, "ES256")
+	key, err := jwk.Key()
+	xtesting.Nil(t, err)
+	xtesting.Equal(t, key, &testEC256Key.PublicKey)
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodHead, "/", nil))
+	xtesting.Equal(t, rec.Code, http.StatusOK)
+	xtesting.Equal(t, rec.Body.Len(), 0)
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/", nil))
+	xtesting.Equal(t, rec.Code, http.StatusMethodNotAllowed)
+}
+
+func TestJWKSFetcher(t *testing.T) {
+	testKeys()
+	xtesting.PanicWithValue(t, panicEmptyJWKURL, func() { NewJWKSFetcher("") })
+
+	now := time.Now()
+	ks := NewKeySet(time.Hour)
+	ks.now = func() time.Time { return now }
+	xtesting.Nil(t, ks.Rotate(&Key{ID: "k1", Method: jwt.SigningMethodRS256, Key: testRSAKey}))
+	var count int32
+	var fail int32
+	handler := NewJWKSHandler(ks)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&count, 1)
+		if atomic.LoadInt32(&fail) == 1 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		handler.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+
+	fetcher := NewJWKSFetcher(server.URL, WithJWKSHTTPClient(server.Client()), WithJWKSRefreshInterval(time.Hour), WithJWKSMinRefetchInterval(time.Minute))
+	fetcher.now = func() time.Time { return now }
+	claims := &jwt.StandardClaims{Subject: "test"}
+
+	// first fetch
+	token1, err := ks.GenerateToken(claims)
+	xtesting.Nil(t, err)
+	parsed, err := ParseTokenWithKeyfunc(token1, fetcher.Keyfunc, &jwt.StandardClaims{})
+	xtesting.Nil(t, err)
+	xtesting.Equal(t, parsed.Claims.(*jwt.StandardClaims).Subject, "test")
+	_, err = ParseTokenWithKeyfunc(token1, fetcher.Keyfunc, &jwt.StandardClaims{})
+	xtesting.Nil(t, err)
+	xtesting.Equal(t, atomic.LoadInt32(&count), int32(1))
+
+	// kid miss, refetch is limited
+	xtesting.Nil(t, ks.Rotate(&Key{ID: "k2", Method: jwt.SigningMethodES256, Key: testEC256Key}))
+	token2, err := ks.GenerateToken(claims)
+	xtesting.Nil(t, err)
+	_, err = ParseTokenWithKeyfunc(token2, fetcher.Keyfunc, &jwt.StandardClaims{})
+	xtesting.True(t, errors.Is(err, ErrKeyNotFound))
+	xtesting.Equal(t, atomic.LoadInt32(&count), int32(1))
+	now = now.Add(time.Minute)
+	_, err = ParseTokenWithKeyfunc(token2, fetcher.Keyfunc, &jwt.StandardClaims{})
+	xtesting.Nil(t, err)
+	xtesting.Equal(t, atomic.LoadInt32(&count), int32(2))
+	_, err = fetcher.Key(context.Background(), "k3")
+	xtesting.Equal(t, err, ErrKeyNotFound)
+	xtesting.Equal(t, atomic.LoadInt32(&count), int32(2))
+
+	// refresh interval, stale keys are used when failed
+	atomic.StoreInt32(&fail, 1)
+	now = now.Add(time.Hour)
+	_, err = ParseTokenWithKeyfunc(token2, fetcher.Keyfunc, &jwt.StandardClaims{})
+	xtesting.Nil(t, err)
+	xtesting.Equal(t, atomic.LoadInt32(&count), int32(3))
+	xtesting.NotNil(t, fetcher.Refresh(context.Background()))
+	atomic.StoreInt32(&fail, 0)
+	xtesting.Nil(t, fetcher.Refresh(context.Background()))
+	xtesting.Equal(t, atomic.LoadInt32(&count), int32(5))
+
+	// algorithm mismatch
+	fake, err := GenerateTokenWithSigner(jwt.SigningMethodPS256, claims, testRSAKey, WithKeyID("k2"))
+	xtesting.Nil(t, err)
+	_, err = ParseTokenWithKeyfunc(fake, fetcher.Keyfunc, &jwt.StandardClaims{})
+	xtesting.True(t, IsAlgorithmError(err))
+	_, err = ParseTokenWithKeyfunc(token1, nil, &jwt.StandardClaims{})
+	xtesting.True(t, IsTokenInvalidError(err))
+
+	// failed at first
+	fetcher = NewJWKSFetcher(server.URL+"/not-exist", WithJWKSMinRefetchInterval(-1))
+	atomic.StoreInt32(&fail, 1)
+	_, err = ParseTokenWithKeyfunc(token1, fetcher.Keyfunc, &jwt.StandardClaims{})
+	xtesting.True(t, IsTokenInvalidError(err))
+	fetcher = NewJWKSFetcher("://invalid")
+	_, err = fetcher.Key(context.Background(), "k1")
+	xtesting.NotNil(t, err)
+
+	// symmetric keys are skipped
+	secret := []byte("published secret")
+	octJWK, err := NewJWK("oct", "", secret)
+	xtesting.Nil(t, err)
+	rsaJWK, err := NewJWK("rsa", "", testRSAKey)
+	xtesting.Nil(t, err)
+	octServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(&JWKS{Keys: []*JWK{octJWK, rsaJWK}})
+	}))
+	defer octServer.Close()
+	fetcher = NewJWKSFetcher(octServer.URL, WithJWKSHTTPClient(octServer.Client()))
+	forged, err := GenerateToken(jwt.SigningMethodHS256, claims, secret, WithKeyID("oct"))
+	xtesting.Nil(t, err)
+	_, err = ParseTokenWithKeyfunc(forged, fetcher.Keyfunc, &jwt.StandardClaims{})
+	xtesting.True(t, errors.Is(err, ErrKeyNotFound))
+	_, err = fetcher.Key(context.Background(), "oct")
+	xtesting.Equal(t, err, ErrKeyNotFound)
+	_, err = fetcher.Key(context.Background(), "rsa")
+	xtesting.Nil(t, err)
+
+	// weak rsa keys are skipped
+	weakKey, err := rsa.GenerateKey(rand.Reader, 1024)
+	xtesting.Nil(t, err)
+	weakJWK, err := NewJWK("weak", "", weakKey)
+	xtesting.Nil(t, err)
+	weakServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(&JWKS{Keys: []*JWK{weakJWK, rsaJWK}})
+	}))
+	defer weakServer.Close()
+	fetcher = NewJWKSFetcher(weakServer.URL, WithJWKSHTTPClient(weakServer.Client()))
+	weakToken, err := GenerateToken(jwt.SigningMethodRS256, claims, weakKey, WithKeyID("weak"))
+	xtesting.Nil(t, err)
+	_, err = ParseTokenWithKeyfunc(weakToken, fetcher.Keyfunc, &jwt.StandardClaims{})
+	xtesting.True(t, errors.Is(err, ErrKeyNotFound))
+	_, err = fetcher.Key(context.Background(), "rsa")
+	xtesting.Nil(t, err)
+}
@@ -0,0 +1,211 @@
+package xjwt
+
+import (
+	"crypto"
+	"encoding/json"
+	"errors"
+	"github.com/golang-jwt/jwt/v4"
+	"strings"
+	"time"
+)
+
+var (
+	errDetachedPayload  = errors.New("xjwt: jws payload is detached")
+	errAttachedPayload  = errors.New("xjwt: jws payload is not detached")
+	errUnsupportedCrit  = errors.New("xjwt: jws contains unsupported critical header")
+	errInvalidB64Header = errors.New("xjwt: jws contains invalid b64 header")
+)
+
+// SignPayload signs given arbitrary payload using given jwt.SigningMethod and key, and returns JWS compact serialization with the
+// base64url encoded payload attached. See GenerateToken for details of key, and GenerateOption-s except WithAutoTokenID are supported.
+// Note that "typ" header is not set by default.
+func SignPayload(method jwt.SigningMethod, payload []byte, key interface{}, options ...GenerateOption) (string, error) {
+	return signJWS(method, payload, key, true, false, newGenerateOptions(options))
+}
+
+// SignDetachedPayload signs given arbitrary payload in the same way as SignPayload, but the payload is detached from the returned JWS,
+// that is "<header>..<signature>" described in RFC 7515 Appendix F. The payload must be transferred separately, such as in http body,
+// and be verified by VerifyDetachedPayload.
+// Example:
+// 	signature, _ := SignDetachedPayload(jwt.SigningMethodHS256, body, secret, WithKeyID("k1"))
+// 	req.Header.Set("X-Signature", signature)
+// 	err := VerifyDetachedPayload(req.Header.Get("X-Signature"), body, secret)
+func SignDetachedPayload(method jwt.SigningMethod, payload []byte, key interface{}, options ...GenerateOption) (string, error) {
+	return signJWS(method, payload, key, true, true, newGenerateOptions(options))
+}
+
+// SignUnencodedPayload signs given arbitrary payload without base64url encoding, that is the unencoded payload option described in RFC
+// 7797, which adds "b64": false and "crit": ["b64"] headers. The payload is detached from the returned JWS, see SignDetachedPayload.
+func SignUnencodedPayload(method jwt.SigningMethod, payload []byte, key interface{}, options ...GenerateOption) (string, error) {
+	return signJWS(method, payload, key, false, true, newGenerateOptions(options))
+}
+
+// signJWS is the implementation of SignPayload, SignDetachedPayload and SignUnencodedPayload.
+func signJWS(method jwt.SigningMethod, payload []byte, key interface{}, encoded, detached bool, opt *generateOptions) (string, error) {
+	if method == nil {
+		return "", jwt.ErrInvalidKeyType
+	}
+	header := map[string]interface{}{}
+	for k, v := range opt.headers {
+		if k != "alg" && k != "b64" && k != "crit" {
+			header[k] = v
+		}
+	}
+	header["alg"] = method.Alg()
+	if !encoded {
+		header["b64"] = false
+		header["crit"] = []string{"b64"}
+	}
+	headerJSON, err := json.Marshal(header)
+	if err != nil {
+		return "", err
+	}
+
+	headerSegment := jwt.EncodeSegment(headerJSON)
+	payloadSegment := string(payload)
+	if encoded {
+		payloadSegment = jwt.EncodeSegment(payload)
+	}
+	signature, err := signString(method, headerSegment+"."+payloadSegment, key)
+	if err != nil {
+		return "", err
+	}
+	if detached {
+		payloadSegment = ""
+	}
+	return headerSegment + "." + payloadSegment + "." + signature, nil
+}
+
+// signString signs given signing string and returns the base64url encoded signature, crypto.Signer is supported for RS, PS, ES and
+// EdDSA series algorithms, see signToken.
+func signString(method jwt.SigningMethod, signingString string, key interface{}) (string, error) {
+	switch method.(type) {
+	case *jwt.SigningMethodRSA, *jwt.SigningMethodRSAPSS, *jwt.SigningMethodECDSA, *jwt.SigningMethodEd25519:
+		if signer, ok := key.(crypto.Signer); ok {
+			signature, err := signWithSigner(method, []byte(signingString), signer)
+			if err != nil {
+				return "", err
+			}
+			return jwt.EncodeSegment(signature), nil
+		}
+	}
+	return method.Sign(signingString, key)
+}
+
+// VerifyPayload verifies the JWS compact serialization generated by SignPayload using given verification key, and returns the attached
+// payload. Both base64url encoded and unencoded (RFC 7797) payloads are supported, and detached payload and unencoded payload containing
+// '.' will be rejected. See
+// ParseTokenWithKey for details of key, and only WithAllowedAlgorithms is used in ParseOption-s. Returned errors are ValidationError.
+func VerifyPayload(jws string, key interface{}, options ...ParseOption) ([]byte, error) {
+	payload, err := verifyJWS(jws, nil, key, newParseOptions(options))
+	if err != nil {
+		return nil, newValidationError(err, nil, nil, time.Time{})
+	}
+	return payload, nil
+}
+
+// VerifyDetachedPayload verifies the detached JWS generated by SignDetachedPayload or SignUnencodedPayload with given payload using given
+// verification key. JWS with attached payload will be rejected. See ParseTokenWithKey for details of key, and only WithAllowedAlgorithms
+// is used in ParseOption-s. Returned errors are ValidationError.
+func VerifyDetachedPayload(jws string, payload []byte, key interface{}, options ...ParseOption) error {
+	if payload == nil {
+		payload = []byte{}
+	}
+	if _, err := verifyJWS(jws, payload, key, newParseOptions(options)); err != nil {
+		return newValidationError(err, nil, nil, time.Time{})
+	}
+	return nil
+}
+
+// verifyJWS verifies given JWS compact serialization, detachedPayload is nil for attached payload, and the verified payload is returned.
+func verifyJWS(jws string, detachedPayload []byte, key interface{}, opt *parseOptions) ([]byte, error) {
+	// "<header>.<payload>.<signature>", split by the first and the last '.' to report attached unencoded payload containing '.'
+	first, last := strings.Index(jws, "."), strings.LastIndex(jws, ".")
+	if first == -1 || first == last {
+		return nil, jwt.NewValidationError("token contains an invalid number of segments", jwt.ValidationErrorMalformed)
+	}
+	headerSegment, payloadSegment, signature := jws[:first], jws[first+1:last], jws[last+1:]
+
+	headerJSON, err := jwt.DecodeSegment(headerSegment)
+	if err != nil {
+		return nil, &jwt.ValidationError{Inner: err, Errors: jwt.ValidationErrorMalformed}
+	}
+	header := make(map[string]interface{})
+	if err = json.Unmarshal(headerJSON, &header); err != nil {
+		return nil, &jwt.ValidationError{Inner: err, Errors: jwt.ValidationErrorMalformed}
+	}
+	encoded, err := checkB64Header(header)
+	if err != nil {
+		return nil, &jwt.ValidationError{Inner: err, Errors: jwt.ValidationErrorMalformed}
+	}
+	if detachedPayload != nil && payloadSegment != "" {
+		return nil, &jwt.ValidationError{Inner: errAttachedPayload, Errors: jwt.ValidationErrorMalformed}
+	}
+	if detachedPayload == nil && payloadSegment == "" {
+		return nil, &jwt.ValidationError{Inner: errDetachedPayload, Errors: jwt.ValidationErrorMalformed}
+	}
+	if encoded && strings.Contains(payloadSegment, ".") {
+		return nil, jwt.NewValidationError("token contains an invalid number of segments", jwt.ValidationErrorMalformed)
+	}
+	if !encoded && strings.Contains(payloadSegment, ".") {
+		// attached unencoded payload must not contain '.', see RFC 7797 section 5.2
+		return nil, &jwt.ValidationError{Inner: errInvalidB64Header, Errors: jwt.ValidationErrorMalformed}
+	}
+
+	// payload and signing string
+	var payload []byte
+	switch {
+	case detachedPayload != nil && encoded:
+		payload, payloadSegment = detachedPayload, jwt.EncodeSegment(detachedPayload)
+	case detachedPayload != nil:
+		payload, payloadSegment = detachedPayload, string(detachedPayload) // detached unencoded payload can contain '.'
+	case encoded:
+		if payload, err = jwt.DecodeSegment(payloadSegment); err != nil {
+			return nil, &jwt.ValidationError{Inner: err, Errors: jwt.ValidationErrorMalformed}
+		}
+	default:
+		payload = []byte(payloadSegment)
+	}
+
+	// signing method and key
+	alg, _ := header["alg"].(string)
+	method := jwt.GetSigningMethod(alg)
+	if method == nil {
+		return nil, jwt.NewValidationError("signing method (alg) is unavailable.", jwt.ValidationErrorUnverifiable)
+	}
+	tokenObj := &jwt.Token{Raw: jws, Method: method, Header: header}
+	verifyingKey, err := verifyingKeyfunc(func(*jwt.Token) (interface{}, error) { return key, nil }, opt)(tokenObj)
+	if err != nil {
+		return nil, err
+	}
+	if err = method.Verify(headerSegment+"."+payloadSegment, signature, verifyingKey); err != nil {
+		return nil, &jwt.ValidationError{Inner: err, Errors: jwt.ValidationErrorSignatureInvalid}
+	}
+	return payload, nil
+}
+
+// checkB64Header checks the "b64" and "crit" headers described in RFC 7797, and returns whether the payload is base64url encoded.
+func checkB64Header(header map[string]interface{}) (bool, error) {
+	critB64 := false
+	if crit, ok := header["crit"]; ok {
+		values, ok := crit.([]interface{})
+		if !ok || len(values) == 0 {
+			return false, errUnsupportedCrit
+		}
+		for _, v := range values {
+			if v != "b64" {
+				return false, errUnsupportedCrit
+			}
+			critB64 = true
+		}
+	}
+	b64, ok := header["b64"]
+	if !ok {
+		return true, nil
+	}
+	encoded, ok := b64.(bool)
+	if !ok || !critB64 {
+		return false, errInvalidB64Header
+	}
+	return encoded, nil
+}
@@ -0,0 +1,132 @@
+package xjwt
+
+import (
+	"encoding/base64"
+	"errors"
+	"github.com/Aoi-hosizora/ahlib/xtesting"
+	"github.com/golang-jwt/jwt/v4"
+	"strings"
+	"testing"
+)
+
+func TestJWSPayload(t *testing.T) {
+	// RFC 7797 Section 4
+	key, _ := base64.RawURLEncoding.DecodeString("AyM1SysPpbyDfgZld3umj1qzKObwVMkoqQ-EstJQLr_T-1qS0gZH75aKtMN3Yj0iPS4hcgUuTwjAzZr1Z9CAow")
+	payload := []byte("$.02")
+
+	t.Run("rfc7797 examples", func(t *testing.T) {
+		attached, err := SignPayload(jwt.SigningMethodHS256, payload, key)
+		xtesting.Nil(t, err)
+		xtesting.Equal(t, attached, "eyJhbGciOiJIUzI1NiJ9.JC4wMg.5mvfOroL-g7HyqJoozehmsaqmvTYGEq5jTI1gVvoEoQ")
+		got, err := VerifyPayload(attached, key)
+		xtesting.Nil(t, err)
+		xtesting.Equal(t, got, payload)
+
+		detached, err := SignDetachedPayload(jwt.SigningMethodHS256, payload, key)
+		xtesting.Nil(t, err)
+		xtesting.Equal(t, detached, "eyJhbGciOiJIUzI1NiJ9..5mvfOroL-g7HyqJoozehmsaqmvTYGEq5jTI1gVvoEoQ")
+		xtesting.Nil(t, VerifyDetachedPayload(detached, payload, key))
+
+		unencoded, err := SignUnencodedPayload(jwt.SigningMethodHS256, payload, key, WithHeader("b64", true), WithHeader("crit", nil))
+		xtesting.Nil(t, err)
+		xtesting.Equal(t, unencoded, "eyJhbGciOiJIUzI1NiIsImI2NCI6ZmFsc2UsImNyaXQiOlsiYjY0Il19..A5dxf2s96_n5FLueVuW1Z_vh161FwXZC4YLPff6dmDY")
+		xtesting.Nil(t, VerifyDetachedPayload(unencoded, payload, key))
+
+		// attached unencoded payload, which must not contain '.'
+		_, err = VerifyPayload("eyJhbGciOiJIUzI1NiIsImI2NCI6ZmFsc2UsImNyaXQiOlsiYjY0Il19.$.02.A5dxf2s96_n5FLueVuW1Z_vh161FwXZC4YLPff6dmDY", key)
+		xtesting.True(t, CheckValidationError(err, jwt.ValidationErrorMalformed))
+		xtesting.True(t, errors.Is(err, errInvalidB64Header))
+		unencoded, err = SignUnencodedPayload(jwt.SigningMethodHS256, []byte("$02"), key)
+		xtesting.Nil(t, err)
+		got, err = VerifyPayload(strings.Replace(unencoded, "..", ".$02.", 1), key)
+		xtesting.Nil(t, err)
+		xtesting.Equal(t, got, []byte("$02"))
+	})
+
+	t.Run("signing methods", func(t *testing.T) {
+		testKeys()
+		for _, tc := range []struct {
+			giveMethod jwt.SigningMethod
+			giveKey    interface{}
+		}{
+			{jwt.SigningMethodHS512, []byte("secret")},
+			{jwt.SigningMethodRS256, testRSAKey},
+			{jwt.SigningMethodPS384, &opaqueSigner{testRSAKey}},
+			{jwt.SigningMethodES256, testEC256Key},
+			{jwt.SigningMethodES384, &opaqueSigner{testEC384Key}},
+			{jwt.SigningMethodEdDSA, testEdKey},
+		} {
+			body := []byte(`{"event":"push","ref":"refs/heads/main"}`)
+			for _, sign := range []func(jwt.SigningMethod, []byte, interface{}, ...GenerateOption) (string, error){SignDetachedPayload, SignUnencodedPayload} {
+				jws, err := sign(tc.giveMethod, body, tc.giveKey, WithKeyID("k1"))
+				xtesting.Nil(t, err)
+				xtesting.Equal(t, strings.Count(jws, "."), 2)
+				xtesting.True(t, strings.Contains(jws, ".."))
+				xtesting.Nil(t, VerifyDetachedPayload(jws, body, tc.giveKey))
+				xtesting.Nil(t, VerifyDetachedPayload(jws, body, tc.giveKey, WithAllowedAlgorithms(tc.giveMethod.Alg())))
+
+				err = VerifyDetachedPayload(jws, []byte(`{"event":"push","ref":"refs/heads/dev"}`), tc.giveKey)
+				xtesting.True(t, IsTokenInvalidError(err))
+				xtesting.False(t, IsAlgorithmError(err))
+				err = VerifyDetachedPayload(jws, body, tc.giveKey, WithAllowedAlgorithms("HS256", "RS512"))
+				xtesting.True(t, IsAlgorithmError(err))
+			}
+
+			jws, err := SignPayload(tc.giveMethod, body, tc.giveKey)
+			xtesting.Nil(t, err)
+			got, err := VerifyPayload(jws, tc.giveKey)
+			xtesting.Nil(t, err)
+			xtesting.Equal(t, got, body)
+		}
+
+		_, err := SignDetachedPayload(nil, payload, key)
+		xtesting.NotNil(t, err)
+		_, err = SignDetachedPayload(jwt.SigningMethodRS256, payload, key)
+		xtesting.NotNil(t, err)
+		jws, _ := SignDetachedPayload(jwt.SigningMethodHS256, payload, key)
+		xtesting.True(t, IsAlgorithmError(VerifyDetachedPayload(jws, payload, &testRSAKey.PublicKey)))
+		xtesting.True(t, IsAlgorithmError(VerifyDetachedPayload(jws, payload, nil)))
+	})
+
+	t.Run("invalid jws", func(t *testing.T) {
+		attached, _ := SignPayload(jwt.SigningMethodHS256, payload, key)
+		detached, _ := SignDetachedPayload(jwt.SigningMethodHS256, payload, key)
+		xtesting.Nil(t, VerifyDetachedPayload(detached, payload, key))
+		for _, tc := range []struct {
+			giveJWS       string
+			givePayload   []byte
+			wantMalformed bool
+		}{
+			{"", payload, true},
+			{"a.b", payload, true},
+			{"!.." + "sig", payload, true},
+			{"e30..sig", payload, false},                                             // no alg
+			{"eyJhbGciOiJub25lIn0..", payload, false},                                // none
+			{attached, payload, true},                                                // attached but expected detached
+			{detached, nil, true},                                                    // detached but expected attached
+			{"eyJhbGciOiJIUzI1NiJ9.a.b.c", nil, true},                                // too many segments
+			{"eyJhbGciOiJIUzI1NiJ9.!.sig", nil, true},                                // invalid base64
+			{"eyJhbGciOiJIUzI1NiIsImNyaXQiOlsiZXhwIl19..", payload, true},            // {"alg":"HS256","crit":["exp"]}
+			{"eyJhbGciOiJIUzI1NiIsImNyaXQiOltdfQ..", payload, true},                  // {"alg":"HS256","crit":[]}
+			{"eyJhbGciOiJIUzI1NiIsImI2NCI6ZmFsc2V9..", payload, true},                // {"alg":"HS256","b64":false}
+			{"eyJhbGciOiJIUzI1NiIsImI2NCI6MCwiY3JpdCI6WyJiNjQiXX0..", payload, true}, // {"alg":"HS256","b64":0,"crit":["b64"]}
+			{strings.TrimSuffix(detached, "Q") + "A", payload, false},                // invalid signature
+		} {
+			var err error
+			if tc.givePayload == nil {
+				_, err = VerifyPayload(tc.giveJWS, key)
+			} else {
+				err = VerifyDetachedPayload(tc.giveJWS, tc.givePayload, key)
+			}
+			xtesting.NotNil(t, err)
+			xtesting.True(t, IsTokenInvalidError(err))
+			xtesting.Equal(t, CheckValidationError(err, jwt.ValidationErrorMalformed), tc.wantMalformed)
+		}
+
+		// empty payload
+		jws, err := SignDetachedPayload(jwt.SigningMethodHS256, nil, key)
+		xtesting.Nil(t, err)
+		xtesting.Nil(t, VerifyDetachedPayload(jws, nil, key))
+		xtesting.Nil(t, VerifyDetachedPayload(jws, []byte{}, key))
+	})
+}
@@ -0,0 +1,248 @@
+package xjwt
+
+import (
+	"crypto"
+	"errors"
+	"github.com/golang-jwt/jwt/v4"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Key represents a key with "kid" (Key ID) and signing method, which is stored in KeySet.
+type Key struct {
+	// ID represents the key id, which will be set to "kid" header, required.
+	ID string
+
+	// Method represents the signing method used with this key, required.
+	Method jwt.SigningMethod
+
+	// Key represents the key, must be []byte secret for HS series, crypto.Signer (private key) or public key for RS, PS, ES and EdDSA
+	// series. Note that keys which only contain public key can only be used to verify tokens, and can not be set as active key.
+	Key interface{}
+}
+
+// canSign checks whether the key can be used to sign tokens.
+func (k *Key) canSign() bool {
+	if _, ok := k.Method.(*jwt.SigningMethodHMAC); ok {
+		_, ok = k.Key.([]byte)
+		return ok
+	}
+	_, ok := k.Key.(crypto.Signer)
+	return ok
+}
+
+// keySetItem represents an item stored in KeySet.
+type keySetItem struct {
+	key       *Key
+	retiredAt time.Time // zero means not retired
+}
+
+// KeySet represents a set of keys indexed by "kid", which is used to support key rotation. The active key is used to generate tokens,
+// and all the keys that are not retired, or retired within the grace period, can be used to verify tokens.
+// Example:
+// 	ks := NewKeySet(24 * time.Hour)
+// 	_ = ks.Rotate(&Key{ID: "2021Q1", Method: jwt.SigningMethodHS256, Key: secret1})
+// 	token, _ := ks.GenerateToken(claims) // signed by 2021Q1
+// 	_ = ks.Rotate(&Key{ID: "2021Q2", Method: jwt.SigningMethodHS256, Key: secret2})
+// 	_, err := ParseTokenWithKeySet(token, ks, &jwt.StandardClaims{}) // 2021Q1 can be still used in 24 hours
+type KeySet struct {
+	mu          sync.RWMutex
+	items       map[string]*keySetItem
+	activeID    string
+	gracePeriod time.Duration
+	now         func() time.Time
+}
+
+var (
+	// ErrKeyNotFound represents an error of key not found or expired in KeySet.
+	ErrKeyNotFound = errors.New("xjwt: key not found")
+
+	// ErrNoActiveKey represents an error of no active key in KeySet.
+	ErrNoActiveKey = errors.New("xjwt: no active key")
+
+	errInvalidKeySetKey   = errors.New("xjwt: invalid key, id, method and key are required")
+	errDuplicateKeySetKey = errors.New("xjwt: duplicate key id")
+	errKeyCannotSign      = errors.New("xjwt: key can not be used to sign")
+)
+
+// NewKeySet creates an empty KeySet with given grace period for retired keys.
+func NewKeySet(gracePeriod time.Duration) *KeySet {
+	if gracePeriod < 0 {
+		gracePeriod = 0
+	}
+	return &KeySet{items: make(map[string]*keySetItem), gracePeriod: gracePeriod, now: time.Now}
+}
+
+// Add adds a Key to KeySet, the key id must be unique, and the key must match its signing method.
+func (k *KeySet) Add(key *Key) error {
+	if key == nil || key.ID == "" || key.Method == nil || key.Key == nil {
+		return errInvalidKeySetKey
+	}
+	if !methodMatchesKey(key.Method, verificationKey(key.Key)) {
+		return &AlgorithmError{Algorithm: key.Method.Alg()}
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if _, ok := k.items[key.ID]; ok {
+		return errDuplicateKeySetKey
+	}
+	k.items[key.ID] = &keySetItem{key: key}
+	return nil
+}
+
+// SetActive sets the key with given id as the active key, and the previous active key will be retired.
+func (k *KeySet) SetActive(kid string) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	item, ok := k.items[kid]
+	if !ok || (!item.retiredAt.IsZero() && !k.inGracePeriod(item)) {
+		return ErrKeyNotFound
+	}
+	if !item.key.canSign() {
+		return errKeyCannotSign
+	}
+	if k.activeID != "" && k.activeID != kid {
+		if old, ok := k.items[k.activeID]; ok {
+			old.retiredAt = k.now()
+		}
+	}
+	item.retiredAt = time.Time{}
+	k.activeID = kid
+	return nil
+}
+
+// Rotate adds given Key to KeySet and sets it as the active key, the previous active key will be retired.
+func (k *KeySet) Rotate(key *Key) error {
+	if err := k.Add(key); err != nil {
+		return err
+	}
+	if err := k.SetActive(key.ID); err != nil {
+		k.Remove(key.ID)
+		return err
+	}
+	return nil
+}
+
+// Retire retires the key with given id, the key can still be used to verify tokens within the grace period. If the key is the active
+// key, KeySet will have no active key after retiring.
+func (k *KeySet) Retire(kid string) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	item, ok := k.items[kid]
+	if !ok {
+		return ErrKeyNotFound
+	}
+	if item.retiredAt.IsZero() {
+		item.retiredAt = k.now()
+	}
+	if k.activeID == kid {
+		k.activeID = ""
+	}
+	return nil
+}
+
+// Remove removes the key with given id immediately.
+func (k *KeySet) Remove(kid string) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	delete(k.items, kid)
+	if k.activeID == kid {
+		k.activeID = ""
+	}
+}
+
+// Prune removes all the retired keys whose grace period have passed.
+func (k *KeySet) Prune() {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	for kid, item := range k.items {
+		if !item.retiredAt.IsZero() && !k.inGracePeriod(item) {
+			delete(k.items, kid)
+		}
+	}
+}
+
+// inGracePeriod checks whether given retired item is still in the grace period, caller must hold the lock.
+func (k *KeySet) inGracePeriod(item *keySetItem) bool {
+	return k.now().Before(item.retiredAt.Add(k.gracePeriod))
+}
+
+// ActiveKey returns the active key.
+func (k *KeySet) ActiveKey() (*Key, bool) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	item, ok := k.items[k.activeID]
+	if !ok {
+		return nil, false
+	}
+	return item.key, true
+}
+
+// Key returns the key with given id which can be used to verify tokens, that is, it is not retired or still in the grace period.
+func (k *KeySet) Key(kid string) (*Key, bool) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	item, ok := k.items[kid]
+	if !ok || (!item.retiredAt.IsZero() && !k.inGracePeriod(item)) {
+		return nil, false
+	}
+	return item.key, true
+}
+
+// Keys returns all the keys which can be used to verify tokens, sorted by key id.
+func (k *KeySet) Keys() []*Key {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	keys := make([]*Key, 0, len(k.items))
+	for _, item := range k.items {
+		if item.retiredAt.IsZero() || k.inGracePeriod(item) {
+			keys = append(keys, item.key)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].ID < keys[j].ID })
+	return keys
+}
+
+// GenerateToken generates token using given jwt.Claims and the active key, the "kid" header will be set to the active key's id.
+func (k *KeySet) GenerateToken(claims jwt.Claims, options ...GenerateOption) (string, error) {
+	key, ok := k.ActiveKey()
+	if !ok {
+		return "", ErrNoActiveKey
+	}
+	options = append(options[:len(options):len(options)], WithKeyID(key.ID))
+	return GenerateToken(key.Method, claims, key.Key, options...)
+}
+
+// Keyfunc returns the verification key for given jwt.Token by its "kid" header, the active key will be used if the token has no "kid"
+// header. Note that the token's algorithm must be the same as the key's signing method. This method can be used as jwt.Keyfunc.
+func (k *KeySet) Keyfunc(token *jwt.Token) (interface{}, error) {
+	var key *Key
+	var ok bool
+	if kid, exist := token.Header["kid"]; exist {
+		kidStr, isStr := kid.(string)
+		if !isStr {
+			return nil, ErrKeyNotFound
+		}
+		key, ok = k.Key(kidStr)
+	} else {
+		key, ok = k.ActiveKey()
+	}
+	if !ok {
+		return nil, ErrKeyNotFound
+	}
+	if alg := token.Method.Alg(); alg != key.Method.Alg() {
+		return nil, newAlgorithmValidationError(alg, []string{key.Method.Alg()})
+	}
+	return key.Key, nil
+}
+
+// ParseTokenWithKeySet parses jwt token string using given KeySet and custom jwt.Claims, and returns jwt.Token. The verification key is
+// selected by the token's "kid" header, see KeySet.Keyfunc.
+func ParseTokenWithKeySet(signedToken string, keySet *KeySet, claims jwt.Claims, options ...ParseOption) (*jwt.Token, error) {
+	if keySet == nil {
+		return nil, newValidationError(jwt.NewValidationError("no key set was provided", jwt.ValidationErrorUnverifiable), nil, nil, time.Time{})
+	}
+	return parseTokenWithKeyfunc(signedToken, keySet.Keyfunc, claims, newParseOptions(options))
+}
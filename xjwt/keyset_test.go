@@ -0,0 +1,121 @@
+package xjwt
+
+import (
+	"errors"
+	"github.com/Aoi-hosizora/ahlib/xtesting"
+	"github.com/golang-jwt/jwt/v4"
+	"testing"
+	"time"
+)
+
+func TestKeySet(t *testing.T) {
+	testKeys()
+	now := time.Now()
+	ks := NewKeySet(time.Hour)
+	ks.now = func() time.Time { return now }
+	claims := &jwt.StandardClaims{Subject: "test"}
+
+	// no active key
+	_, err := ks.GenerateToken(claims)
+	xtesting.Equal(t, err, ErrNoActiveKey)
+	_, ok := ks.ActiveKey()
+	xtesting.False(t, ok)
+
+	// invalid keys
+	for _, key := range []*Key{
+		nil,
+		{ID: "", Method: jwt.SigningMethodHS256, Key: []byte("1")},
+		{ID: "k", Method: nil, Key: []byte("1")},
+		{ID: "k", Method: jwt.SigningMethodHS256, Key: nil},
+		{ID: "k", Method: jwt.SigningMethodHS256, Key: testRSAKey},
+		{ID: "k", Method: jwt.SigningMethodES256, Key: testEC384Key},
+	} {
+		xtesting.NotNil(t, ks.Add(key))
+	}
+	xtesting.Equal(t, ks.SetActive("not-exist"), ErrKeyNotFound)
+	xtesting.Equal(t, ks.Retire("not-exist"), ErrKeyNotFound)
+
+	// rotate
+	xtesting.Nil(t, ks.Rotate(&Key{ID: "k1", Method: jwt.SigningMethodHS256, Key: []byte("secret1")}))
+	xtesting.NotNil(t, ks.Add(&Key{ID: "k1", Method: jwt.SigningMethodHS256, Key: []byte("secret1")}))
+	token1, err := ks.GenerateToken(claims)
+	xtesting.Nil(t, err)
+	parsed, err := ParseTokenWithKeySet(token1, ks, &jwt.StandardClaims{})
+	xtesting.Nil(t, err)
+	xtesting.Equal(t, parsed.Header["kid"], "k1")
+
+	xtesting.Nil(t, ks.Rotate(&Key{ID: "k2", Method: jwt.SigningMethodRS256, Key: testRSAKey}))
+	key, ok := ks.ActiveKey()
+	xtesting.True(t, ok)
+	xtesting.Equal(t, key.ID, "k2")
+	token2, err := ks.GenerateToken(claims, WithHeader("typ", "at+jwt"))
+	xtesting.Nil(t, err)
+	parsed, err = ParseTokenWithKeySet(token2, ks, &jwt.StandardClaims{})
+	xtesting.Nil(t, err)
+	xtesting.Equal(t, parsed.Header["kid"], "k2")
+	xtesting.Equal(t, parsed.Header["typ"], "at+jwt")
+	_, err = ParseTokenWithKeySet(token1, ks, &jwt.StandardClaims{}) // in grace period
+	xtesting.Nil(t, err)
+	xtesting.Equal(t, len(ks.Keys()), 2)
+
+	// grace period passed
+	now = now.Add(time.Hour)
+	_, ok = ks.Key("k1")
+	xtesting.False(t, ok)
+	_, err = ParseTokenWithKeySet(token1, ks, &jwt.StandardClaims{})
+	xtesting.NotNil(t, err)
+	xtesting.True(t, IsTokenInvalidError(err))
+	xtesting.True(t, errors.Is(err, ErrKeyNotFound))
+	xtesting.Equal(t, ks.SetActive("k1"), ErrKeyNotFound)
+	xtesting.Equal(t, len(ks.Keys()), 1)
+	ks.Prune()
+	xtesting.Equal(t, len(ks.items), 1)
+
+	// verification only key
+	xtesting.Nil(t, ks.Add(&Key{ID: "k3", Method: jwt.SigningMethodES256, Key: &testEC256Key.PublicKey}))
+	xtesting.Equal(t, ks.SetActive("k3"), errKeyCannotSign)
+	token3, _ := GenerateTokenWithSigner(jwt.SigningMethodES256, claims, testEC256Key, WithKeyID("k3"))
+	_, err = ParseTokenWithKeySet(token3, ks, &jwt.StandardClaims{})
+	xtesting.Nil(t, err)
+	xtesting.Equal(t, ks.Keys()[0].ID, "k2")
+	xtesting.Equal(t, ks.Keys()[1].ID, "k3")
+
+	// wrong algorithm or kid
+	token4, _ := GenerateTokenWithSigner(jwt.SigningMethodES384, claims, testEC384Key, WithKeyID("k3"))
+	_, err = ParseTokenWithKeySet(token4, ks, &jwt.StandardClaims{})
+	xtesting.True(t, IsAlgorithmError(err))
+	token5, _ := GenerateToken(jwt.SigningMethodHS256, claims, []byte("secret1"), WithHeader("kid", 1))
+	_, err = ParseTokenWithKeySet(token5, ks, &jwt.StandardClaims{})
+	xtesting.True(t, errors.Is(err, ErrKeyNotFound))
+	_, err = ParseTokenWithKeySet(token2, nil, &jwt.StandardClaims{})
+	xtesting.True(t, IsTokenInvalidError(err))
+
+	// no kid -> active key
+	token6, _ := GenerateTokenWithRS256(claims, testRSAKey)
+	_, err = ParseTokenWithKeySet(token6, ks, &jwt.StandardClaims{})
+	xtesting.Nil(t, err)
+
+	// retire and remove
+	xtesting.Nil(t, ks.Retire("k2"))
+	_, ok = ks.ActiveKey()
+	xtesting.False(t, ok)
+	_, err = ParseTokenWithKeySet(token2, ks, &jwt.StandardClaims{})
+	xtesting.Nil(t, err)
+	_, err = ParseTokenWithKeySet(token6, ks, &jwt.StandardClaims{})
+	xtesting.NotNil(t, err)
+	xtesting.Nil(t, ks.SetActive("k2")) // reactivate in grace period
+	_, err = ParseTokenWithKeySet(token6, ks, &jwt.StandardClaims{})
+	xtesting.Nil(t, err)
+	ks.Remove("k2")
+	_, ok = ks.ActiveKey()
+	xtesting.False(t, ok)
+	_, err = ParseTokenWithKeySet(token2, ks, &jwt.StandardClaims{})
+	xtesting.NotNil(t, err)
+
+	// rotate with invalid key
+	xtesting.NotNil(t, ks.Rotate(&Key{ID: "k4", Method: jwt.SigningMethodRS256, Key: &testRSAKey.PublicKey}))
+	_, ok = ks.Key("k4")
+	xtesting.False(t, ok)
+	xtesting.NotNil(t, ks.Rotate(nil))
+	xtesting.Equal(t, NewKeySet(-1).gracePeriod, time.Duration(0))
+}
@@ -0,0 +1,200 @@
+package xjwt
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/golang-jwt/jwt/v4"
+	"net/http"
+	"strings"
+)
+
+// TokenExtractor represents a function to extract jwt token string from http.Request, empty string means token not found.
+type TokenExtractor func(r *http.Request) string
+
+// FromAuthorizationHeader returns a TokenExtractor which extracts token from "Authorization: Bearer <token>" header.
+func FromAuthorizationHeader() TokenExtractor {
+	return func(r *http.Request) string {
+		auth := strings.TrimSpace(r.Header.Get("Authorization"))
+		if len(auth) <= 7 || !strings.EqualFold(auth[:7], "Bearer ") {
+			return ""
+		}
+		return strings.TrimSpace(auth[7:])
+	}
+}
+
+// FromCookie returns a TokenExtractor which extracts token from cookie with given name.
+func FromCookie(name string) TokenExtractor {
+	return func(r *http.Request) string {
+		cookie, err := r.Cookie(name)
+		if err != nil {
+			return ""
+		}
+		return cookie.Value
+	}
+}
+
+// FromQuery returns a TokenExtractor which extracts token from query parameter with given name.
+func FromQuery(name string) TokenExtractor {
+	return func(r *http.Request) string {
+		return r.URL.Query().Get(name)
+	}
+}
+
+// MiddlewareConfig represents the config of jwt authentication middleware.
+type MiddlewareConfig struct {
+	// Key represents the verification key, see ParseTokenWithKey, required if Keyfunc is nil.
+	Key interface{}
+
+	// Keyfunc represents the jwt.Keyfunc used to resolve verification key, such as KeySet.Keyfunc and JWKSFetcher.Keyfunc, it takes
+	// precedence over Key.
+	Keyfunc jwt.Keyfunc
+
+	// NewClaims represents the function to create an empty jwt.Claims for each request, defaults to creating *jwt.RegisteredClaims.
+	NewClaims func() jwt.Claims
+
+	// ParseOptions represents the ParseOption-s used to parse tokens, defaults to empty. Note that the request's context is always passed
+	// to RevocationStore, and WithContext is ignored.
+	ParseOptions []ParseOption
+
+	// Extractors represents the TokenExtractor-s used to extract token in order, the first non-empty token will be used, defaults to
+	// FromAuthorizationHeader only.
+	Extractors []TokenExtractor
+
+	// Realm represents the realm in "WWW-Authenticate" response header, defaults to empty, no realm will be responded.
+	Realm string
+
+	// Optional represents whether to allow requests without token, the next handler will be invoked without token in context. Note that
+	// requests with invalid token are still rejected. Defaults to false.
+	Optional bool
+
+	// ErrorHandler represents the function to handle authentication errors, err is ErrMissingToken or the error returned from parsing.
+	// Defaults to responding 401 with "WWW-Authenticate" header described in RFC 6750, see WriteUnauthorized.
+	ErrorHandler func(w http.ResponseWriter, r *http.Request, err error)
+}
+
+// ErrMissingToken represents an error of no token found in request.
+var ErrMissingToken = errors.New("xjwt: missing token")
+
+const panicNilKeyAndKeyfunc = "xjwt: nil key and keyfunc"
+
+// tokenContextKey is the context key of jwt.Token.
+type tokenContextKey struct{}
+
+// NewMiddleware creates a net/http middleware which authenticates requests by jwt token, the parsed jwt.Token will be stored in request's
+// context, and can be got by TokenFromContext and ClaimsFromContext.
+// Example:
+// 	mw := NewMiddleware(&MiddlewareConfig{
+// 		Key:          secret,
+// 		ParseOptions: []ParseOption{WithIssuer("auth")},
+// 		Extractors:   []TokenExtractor{FromAuthorizationHeader(), FromCookie("token")},
+// 	})
+// 	http.Handle("/api", mw(apiHandler))
+func NewMiddleware(config *MiddlewareConfig) func(next http.Handler) http.Handler {
+	if config == nil {
+		panic(panicNilConfig)
+	}
+	keyFunc := config.Keyfunc
+	if keyFunc == nil {
+		if config.Key == nil {
+			panic(panicNilKeyAndKeyfunc)
+		}
+		key := config.Key
+		keyFunc = func(*jwt.Token) (interface{}, error) { return key, nil }
+	}
+	newClaims := config.NewClaims
+	if newClaims == nil {
+		newClaims = func() jwt.Claims { return &jwt.RegisteredClaims{} }
+	}
+	extractors := config.Extractors
+	if len(extractors) == 0 {
+		extractors = []TokenExtractor{FromAuthorizationHeader()}
+	}
+	errorHandler := config.ErrorHandler
+	if errorHandler == nil {
+		realm := config.Realm
+		errorHandler = func(w http.ResponseWriter, r *http.Request, err error) {
+			WriteUnauthorized(w, realm, err)
+		}
+	}
+	parseOptions := newParseOptions(config.ParseOptions)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			signedToken := ""
+			for _, extractor := range extractors {
+				if signedToken = extractor(r); signedToken != "" {
+					break
+				}
+			}
+			if signedToken == "" {
+				if config.Optional {
+					next.ServeHTTP(w, r)
+				} else {
+					errorHandler(w, r, ErrMissingToken)
+				}
+				return
+			}
+
+			opt := *parseOptions
+			opt.ctx = r.Context()
+			tokenObj, err := parseTokenWithKeyfunc(signedToken, keyFunc, newClaims(), &opt)
+			if err != nil {
+				errorHandler(w, r, err)
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), tokenContextKey{}, tokenObj)))
+		})
+	}
+}
+
+// TokenFromContext returns the jwt.Token stored in context by the middleware created by NewMiddleware.
+func TokenFromContext(ctx context.Context) (*jwt.Token, bool) {
+	tokenObj, ok := ctx.Value(tokenContextKey{}).(*jwt.Token)
+	return tokenObj, ok
+}
+
+// ClaimsFromContext returns the jwt.Claims stored in context by the middleware created by NewMiddleware, its type is the same as the
+// one returned by MiddlewareConfig.NewClaims.
+func ClaimsFromContext(ctx context.Context) (jwt.Claims, bool) {
+	tokenObj, ok := TokenFromContext(ctx)
+	if !ok {
+		return nil, false
+	}
+	return tokenObj.Claims, true
+}
+
+// WriteUnauthorized writes a 401 response with "WWW-Authenticate: Bearer" header described in RFC 6750 for given authentication error.
+// The "error" attribute is omitted for ErrMissingToken, otherwise it is "invalid_token" with "error_description" explaining the error.
+func WriteUnauthorized(w http.ResponseWriter, realm string, err error) {
+	var params []string
+	if realm != "" {
+		params = append(params, fmt.Sprintf("realm=%q", realm))
+	}
+	if err != nil && err != ErrMissingToken {
+		params = append(params, `error="invalid_token"`, fmt.Sprintf("error_description=%q", unauthorizedDescription(err)))
+	}
+	challenge := "Bearer"
+	if len(params) > 0 {
+		challenge += " " + strings.Join(params, ", ")
+	}
+	w.Header().Set("WWW-Authenticate", challenge)
+	http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+}
+
+// unauthorizedDescription returns the "error_description" for given authentication error.
+func unauthorizedDescription(err error) string {
+	switch {
+	case IsTokenInvalidError(err):
+		return "The access token is malformed or has an invalid signature"
+	case IsExpiredError(err):
+		return "The access token expired"
+	case IsNotValidYetError(err):
+		return "The access token is not valid yet"
+	case IsRevokedError(err):
+		return "The access token has been revoked"
+	case IsIssuerError(err), IsAudienceError(err), IsSubjectError(err), IsIssuedAtError(err), IsIdError(err), IsClaimsInvalidError(err):
+		return "The access token has invalid claims"
+	}
+	return "The access token is invalid"
+}
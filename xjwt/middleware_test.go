@@ -0,0 +1,159 @@
+package xjwt
+
+import (
+	"context"
+	"errors"
+	"github.com/Aoi-hosizora/ahlib/xtesting"
+	"github.com/golang-jwt/jwt/v4"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestTokenExtractor(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/?token=q", nil)
+	r.AddCookie(&http.Cookie{Name: "token", Value: "c"})
+	xtesting.Equal(t, FromQuery("token")(r), "q")
+	xtesting.Equal(t, FromQuery("other")(r), "")
+	xtesting.Equal(t, FromCookie("token")(r), "c")
+	xtesting.Equal(t, FromCookie("other")(r), "")
+	for _, tc := range []struct {
+		give string
+		want string
+	}{
+		{"", ""},
+		{"Bearer", ""},
+		{"Bearer ", ""},
+		{"Basic xxx", ""},
+		{"Bearer h", "h"},
+		{"bearer  h ", "h"},
+	} {
+		r.Header.Set("Authorization", tc.give)
+		xtesting.Equal(t, FromAuthorizationHeader()(r), tc.want)
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	xtesting.PanicWithValue(t, panicNilConfig, func() { NewMiddleware(nil) })
+	xtesting.PanicWithValue(t, panicNilKeyAndKeyfunc, func() { NewMiddleware(&MiddlewareConfig{}) })
+
+	secret := []byte("secret")
+	now := time.Now()
+	valid, _ := GenerateTokenWithHS256(&jwt.RegisteredClaims{Issuer: "auth", Subject: "user", ExpiresAt: jwt.NewNumericDate(now.Add(time.Hour))}, secret)
+	expired, _ := GenerateTokenWithHS256(&jwt.RegisteredClaims{Issuer: "auth", ExpiresAt: jwt.NewNumericDate(now.Add(-time.Hour))}, secret)
+	notBefore, _ := GenerateTokenWithHS256(&jwt.RegisteredClaims{Issuer: "auth", NotBefore: jwt.NewNumericDate(now.Add(time.Hour))}, secret)
+	otherIssuer, _ := GenerateTokenWithHS256(&jwt.RegisteredClaims{Issuer: "other"}, secret)
+	revoked, _ := GenerateTokenWithHS256(&jwt.RegisteredClaims{Issuer: "auth"}, secret)
+	store := NewMemoryRevocationStore()
+	xtesting.Nil(t, RevokeToken(context.Background(), store, revoked))
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		claims, ok := ClaimsFromContext(r.Context())
+		if !ok {
+			_, _ = w.Write([]byte("anonymous"))
+			return
+		}
+		_, _ = w.Write([]byte(claims.(*jwt.RegisteredClaims).Subject))
+	})
+	mw := NewMiddleware(&MiddlewareConfig{
+		Key:          secret,
+		ParseOptions: []ParseOption{WithIssuer("auth"), WithRevocationStore(store)},
+		Extractors:   []TokenExtractor{FromAuthorizationHeader(), FromCookie("token")},
+		Realm:        "api",
+	})(handler)
+
+	for _, tc := range []struct {
+		giveHeader string
+		giveCookie string
+		wantCode   int
+		wantBody   string
+		wantAuth   string
+	}{
+		{"Bearer " + valid, "", 200, "user", ""},
+		{"", valid, 200, "user", ""},
+		{"Bearer " + valid, "invalid", 200, "user", ""},
+		{"", "", 401, "Unauthorized\n", `Bearer realm="api"`},
+		{"Basic xxx", "", 401, "Unauthorized\n", `Bearer realm="api"`},
+		{"Bearer invalid", "", 401, "Unauthorized\n", `Bearer realm="api", error="invalid_token", error_description="The access token is malformed or has an invalid signature"`},
+		{"Bearer " + valid + "x", "", 401, "Unauthorized\n", `Bearer realm="api", error="invalid_token", error_description="The access token is malformed or has an invalid signature"`},
+		{"Bearer " + expired, "", 401, "Unauthorized\n", `Bearer realm="api", error="invalid_token", error_description="The access token expired"`},
+		{"Bearer " + notBefore, "", 401, "Unauthorized\n", `Bearer realm="api", error="invalid_token", error_description="The access token is not valid yet"`},
+		{"Bearer " + otherIssuer, "", 401, "Unauthorized\n", `Bearer realm="api", error="invalid_token", error_description="The access token has invalid claims"`},
+		{"Bearer " + revoked, "", 401, "Unauthorized\n", `Bearer realm="api", error="invalid_token", error_description="The access token has been revoked"`},
+	} {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		if tc.giveHeader != "" {
+			r.Header.Set("Authorization", tc.giveHeader)
+		}
+		if tc.giveCookie != "" {
+			r.AddCookie(&http.Cookie{Name: "token", Value: tc.giveCookie})
+		}
+		rec := httptest.NewRecorder()
+		mw.ServeHTTP(rec, r)
+		xtesting.Equal(t, rec.Code, tc.wantCode)
+		xtesting.Equal(t, rec.Body.String(), tc.wantBody)
+		xtesting.Equal(t, rec.Header().Get("WWW-Authenticate"), tc.wantAuth)
+	}
+
+	// optional, keyfunc, query, custom claims and error handler
+	var handledErr error
+	mw = NewMiddleware(&MiddlewareConfig{
+		Keyfunc:    func(*jwt.Token) (interface{}, error) { return secret, nil },
+		NewClaims:  func() jwt.Claims { return &jwt.MapClaims{} },
+		Extractors: []TokenExtractor{FromQuery("token")},
+		Optional:   true,
+		ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+			handledErr = err
+			w.WriteHeader(http.StatusForbidden)
+		},
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tokenObj, ok := TokenFromContext(r.Context())
+		if !ok {
+			_, _ = w.Write([]byte("anonymous"))
+			return
+		}
+		_, _ = w.Write([]byte((*tokenObj.Claims.(*jwt.MapClaims))["sub"].(string)))
+	}))
+	rec := httptest.NewRecorder()
+	mw.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	xtesting.Equal(t, rec.Body.String(), "anonymous")
+	rec = httptest.NewRecorder()
+	mw.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/?token="+valid, nil))
+	xtesting.Equal(t, rec.Body.String(), "user")
+	rec = httptest.NewRecorder()
+	mw.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/?token="+expired, nil))
+	xtesting.Equal(t, rec.Code, http.StatusForbidden)
+	xtesting.True(t, IsExpiredError(handledErr))
+
+	// request's context
+	mw = NewMiddleware(&MiddlewareConfig{
+		Key:          secret,
+		ParseOptions: []ParseOption{WithRevocationStore(contextRevocationStore{})},
+		ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+			handledErr = err
+			w.WriteHeader(http.StatusUnauthorized)
+		},
+	})(handler)
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("Authorization", "Bearer "+valid)
+	rec = httptest.NewRecorder()
+	mw.ServeHTTP(rec, r)
+	xtesting.Equal(t, rec.Body.String(), "user")
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	rec = httptest.NewRecorder()
+	mw.ServeHTTP(rec, r.WithContext(canceled))
+	xtesting.Equal(t, rec.Code, http.StatusUnauthorized)
+	xtesting.True(t, errors.Is(handledErr, context.Canceled))
+
+	// write unauthorized
+	rec = httptest.NewRecorder()
+	WriteUnauthorized(rec, "", ErrMissingToken)
+	xtesting.Equal(t, rec.Header().Get("WWW-Authenticate"), "Bearer")
+	rec = httptest.NewRecorder()
+	WriteUnauthorized(rec, "", errors.New("test"))
+	xtesting.Equal(t, rec.Header().Get("WWW-Authenticate"), `Bearer error="invalid_token", error_description="The access token is invalid"`)
+	_, ok := ClaimsFromContext(httptest.NewRequest(http.MethodGet, "/", nil).Context())
+	xtesting.False(t, ok)
+}
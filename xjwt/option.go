@@ -0,0 +1,270 @@
+package xjwt
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"github.com/golang-jwt/jwt/v4"
+	"time"
+)
+
+// ParseOption represents an option for ParseToken series functions, can be created by WithXXX functions. Note that if any claims
+// validation option (WithIssuer, WithAudience, WithSubject, WithLeeway, WithMaxAge and WithTimeFunc) is specified, the registered
+// claims will be validated by xjwt, and the custom claims are validated by CustomClaimsValidator's ValidateCustom method if implemented,
+// otherwise by jwt.Claims' Valid method whose time based failures are ignored.
+//
+// Warning: a typical custom Valid method which returns early when the embedded claims' Valid fails (such as token is expired without
+// leeway, or expired in real time but not in WithTimeFunc's time) will skip its own non-time checks, and the token will be accepted by
+// xjwt. Please implement CustomClaimsValidator for such claims if any claims validation option is used.
+type ParseOption func(*parseOptions)
+
+// parseOptions is a type of ParseToken series functions' options.
+type parseOptions struct {
+	allowedAlgorithms []string
+
+	validateClaims bool
+	issuer         string
+	audiences      []string
+	subject        string
+	leeway         time.Duration
+	maxAge         time.Duration
+	timeFunc       func() time.Time
+
+	revocationStore RevocationStore
+	ctx             context.Context
+}
+
+// WithAllowedAlgorithms creates a ParseOption to specify the allowed signing algorithms, such as "HS256" and "RS256", tokens signed with
+// other algorithms will be rejected with AlgorithmError. Defaults to allow the algorithms that match the type of verification key, that
+// is HS series for []byte, RS and PS series for *rsa.PublicKey, ES series with the same curve for *ecdsa.PublicKey, and EdDSA for
+// ed25519.PublicKey. Note that "none" algorithm is always rejected.
+func WithAllowedAlgorithms(algorithms ...string) ParseOption {
+	return func(o *parseOptions) {
+		o.allowedAlgorithms = algorithms
+	}
+}
+
+// WithIssuer creates a ParseOption to require the "iss" (Issuer) claim to be equal to given issuer, otherwise IsIssuerError will be true.
+func WithIssuer(issuer string) ParseOption {
+	return func(o *parseOptions) {
+		o.validateClaims = true
+		o.issuer = issuer
+	}
+}
+
+// WithAudience creates a ParseOption to require the "aud" (Audience) claim to contain at least one of given audiences, otherwise
+// IsAudienceError will be true.
+func WithAudience(audiences ...string) ParseOption {
+	return func(o *parseOptions) {
+		o.validateClaims = true
+		o.audiences = audiences
+	}
+}
+
+// WithSubject creates a ParseOption to require the "sub" (Subject) claim to be equal to given subject, otherwise IsSubjectError will be
+// true.
+func WithSubject(subject string) ParseOption {
+	return func(o *parseOptions) {
+		o.validateClaims = true
+		o.subject = subject
+	}
+}
+
+// WithLeeway creates a ParseOption to specify the leeway for validating "exp", "nbf" and "iat" claims, which is used to tolerate clock
+// skew between servers. Defaults to no leeway.
+func WithLeeway(leeway time.Duration) ParseOption {
+	return func(o *parseOptions) {
+		o.validateClaims = true
+		o.leeway = leeway
+	}
+}
+
+// WithMaxAge creates a ParseOption to require the "iat" (Issued at) claim to exist and the token to be issued within given duration,
+// otherwise IsIssuedAtError will be true.
+func WithMaxAge(maxAge time.Duration) ParseOption {
+	return func(o *parseOptions) {
+		o.validateClaims = true
+		o.maxAge = maxAge
+	}
+}
+
+// WithTimeFunc creates a ParseOption to specify the function to get current time when validating time based claims, defaults to
+// time.Now. This is useful for testing.
+func WithTimeFunc(timeFunc func() time.Time) ParseOption {
+	return func(o *parseOptions) {
+		o.validateClaims = true
+		o.timeFunc = timeFunc
+	}
+}
+
+// WithContext creates a ParseOption to specify the context passed to RevocationStore when checking revocation, defaults to
+// context.Background(). This is useful to propagate the request's deadline and cancellation.
+func WithContext(ctx context.Context) ParseOption {
+	return func(o *parseOptions) {
+		if ctx != nil {
+			o.ctx = ctx
+		}
+	}
+}
+
+// GenerateOption represents an option for GenerateToken series functions, can be created by WithXXX functions.
+type GenerateOption func(*generateOptions)
+
+// generateOptions is a type of GenerateToken series functions' options.
+type generateOptions struct {
+	headers     map[string]interface{}
+	autoTokenID bool
+}
+
+// WithKeyID creates a GenerateOption to set the "kid" (Key ID) header of the generated token.
+func WithKeyID(kid string) GenerateOption {
+	return WithHeader("kid", kid)
+}
+
+// WithHeader creates a GenerateOption to set a custom header of the generated token. Note that "alg" header cannot be overwritten.
+func WithHeader(key string, value interface{}) GenerateOption {
+	return func(o *generateOptions) {
+		if o.headers == nil {
+			o.headers = make(map[string]interface{})
+		}
+		o.headers[key] = value
+	}
+}
+
+// WithAutoTokenID creates a GenerateOption to specify whether to generate a random "jti" (JWT ID) claim automatically if the claims
+// does not contain one, defaults to true. Note that the given claims will not be modified.
+func WithAutoTokenID(auto bool) GenerateOption {
+	return func(o *generateOptions) {
+		o.autoTokenID = auto
+	}
+}
+
+// newGenerateOptions applies given GenerateOption-s and returns generateOptions.
+func newGenerateOptions(options []GenerateOption) *generateOptions {
+	opt := &generateOptions{autoTokenID: true}
+	for _, o := range options {
+		if o != nil {
+			o(opt)
+		}
+	}
+	return opt
+}
+
+// newTokenWithOptions creates a jwt.Token using given jwt.SigningMethod, jwt.Claims and generateOptions.
+func newTokenWithOptions(method jwt.SigningMethod, claims jwt.Claims, opt *generateOptions) *jwt.Token {
+	if opt.autoTokenID {
+		claims = &claimsWithTokenID{Claims: claims}
+	}
+	tokenObj := jwt.NewWithClaims(method, claims)
+	for k, v := range opt.headers {
+		if k != "alg" {
+			tokenObj.Header[k] = v
+		}
+	}
+	return tokenObj
+}
+
+// signToken signs given jwt.Token using given key, crypto.Signer is supported for RS, PS, ES and EdDSA series algorithms.
+func signToken(tokenObj *jwt.Token, key interface{}) (string, error) {
+	switch tokenObj.Method.(type) {
+	case *jwt.SigningMethodRSA, *jwt.SigningMethodRSAPSS, *jwt.SigningMethodECDSA, *jwt.SigningMethodEd25519:
+		if signer, ok := key.(crypto.Signer); ok {
+			return generateTokenWithSigner(tokenObj, signer)
+		}
+	}
+	return tokenObj.SignedString(key)
+}
+
+// newParseOptions applies given ParseOption-s and returns parseOptions.
+func newParseOptions(options []ParseOption) *parseOptions {
+	opt := &parseOptions{ctx: context.Background()}
+	for _, o := range options {
+		if o != nil {
+			o(opt)
+		}
+	}
+	return opt
+}
+
+// parseTokenWithKeyfunc parses jwt token string using given jwt.Keyfunc, custom jwt.Claims and parseOptions, this is the core of
+// ParseToken series functions. Note that if any claims validation option is specified, the time based claims will be validated by xjwt
+// rather than jwt.Claims' Valid method, and other failures of Valid method are still reported, see checkRegisteredClaims. All the
+// validation errors are converted to *ValidationError.
+func parseTokenWithKeyfunc(signedToken string, keyFunc jwt.Keyfunc, claims jwt.Claims, opt *parseOptions) (*jwt.Token, error) {
+	parser := &jwt.Parser{SkipClaimsValidation: opt.validateClaims}
+	tokenObj, err := parser.ParseWithClaims(signedToken, claims, verifyingKeyfunc(keyFunc, opt))
+	if err != nil {
+		registered, _ := decodeRegisteredClaims(signedToken) // only used to fill in the details
+		return nil, newValidationError(err, registered, opt, opt.now())
+	}
+	if opt.validateClaims || opt.revocationStore != nil {
+		registered, err := decodeRegisteredClaims(tokenObj.Raw)
+		if err != nil {
+			return nil, newValidationError(err, nil, opt, opt.now())
+		}
+		if err = checkRegisteredClaims(registered, claims, opt); err != nil {
+			return nil, newValidationError(err, registered, opt, opt.now())
+		}
+	}
+	return tokenObj, nil
+}
+
+// now returns the current time used to validate time based claims, that is the time function specified by WithTimeFunc or jwt.TimeFunc.
+func (o *parseOptions) now() time.Time {
+	if o.timeFunc != nil {
+		return o.timeFunc()
+	}
+	return jwt.TimeFunc()
+}
+
+// verifyingKeyfunc wraps given jwt.Keyfunc to reject "none" algorithm, the algorithms not allowed by WithAllowedAlgorithms, and the
+// algorithms mismatched with the verification key.
+func verifyingKeyfunc(keyFunc jwt.Keyfunc, opt *parseOptions) jwt.Keyfunc {
+	return func(token *jwt.Token) (interface{}, error) {
+		alg := token.Method.Alg()
+		if alg == "none" || (len(opt.allowedAlgorithms) > 0 && !containsString(opt.allowedAlgorithms, alg)) {
+			return nil, newAlgorithmValidationError(alg, opt.allowedAlgorithms)
+		}
+		key, err := keyFunc(token)
+		if err != nil {
+			return nil, err
+		}
+		key = verificationKey(key)
+		if len(opt.allowedAlgorithms) == 0 && !methodMatchesKey(token.Method, key) {
+			return nil, newAlgorithmValidationError(alg, nil)
+		}
+		return key, nil
+	}
+}
+
+// methodMatchesKey checks whether given jwt.SigningMethod can be used with given verification key, custom signing methods are always
+// regarded as matched.
+func methodMatchesKey(method jwt.SigningMethod, key interface{}) bool {
+	switch m := method.(type) {
+	case *jwt.SigningMethodHMAC:
+		_, ok := key.([]byte)
+		return ok
+	case *jwt.SigningMethodRSA, *jwt.SigningMethodRSAPSS:
+		_, ok := key.(*rsa.PublicKey)
+		return ok
+	case *jwt.SigningMethodECDSA:
+		k, ok := key.(*ecdsa.PublicKey)
+		return ok && k.Curve != nil && k.Curve.Params().BitSize == m.CurveBits
+	case *jwt.SigningMethodEd25519:
+		_, ok := key.(ed25519.PublicKey)
+		return ok
+	}
+	return true
+}
+
+// containsString checks whether given string slice contains given string.
+func containsString(slice []string, s string) bool {
+	for _, item := range slice {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
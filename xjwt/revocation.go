@@ -0,0 +1,134 @@
+package xjwt
+
+import (
+	"context"
+	"errors"
+	"github.com/golang-jwt/jwt/v4"
+	"sync"
+	"time"
+)
+
+// RevocationStore represents a storage of revoked tokens keyed by "jti" (JWT ID), which is used to revoke tokens before they expire.
+// Note that implementations must be safe for concurrent use.
+type RevocationStore interface {
+	// Revoke records the given token id as revoked, the record can be removed after expiresAt, which is usually the token's "exp".
+	Revoke(ctx context.Context, tokenID string, expiresAt time.Time) error
+
+	// IsRevoked checks whether the given token id has been revoked.
+	IsRevoked(ctx context.Context, tokenID string) (bool, error)
+}
+
+var (
+	// ErrTokenRevoked represents an error of token revoked, IsIdError and IsRevokedError will be true for this error.
+	ErrTokenRevoked = errors.New("xjwt: token has been revoked")
+
+	errNoTokenID = errors.New("xjwt: token has no jti claim")
+)
+
+// WithRevocationStore creates a ParseOption to reject tokens which are revoked in given RevocationStore, IsRevokedError will be true
+// for the returned error. Note that tokens without "jti" claim will also be rejected, and the error returned from RevocationStore will be
+// reported as a claims invalid error. The context specified by WithContext is passed to RevocationStore.
+func WithRevocationStore(store RevocationStore) ParseOption {
+	return func(o *parseOptions) {
+		o.revocationStore = store
+	}
+}
+
+// checkRevocation checks whether the token with given registered claims has been revoked in RevocationStore using given context.
+func checkRevocation(ctx context.Context, claims *jwt.RegisteredClaims, store RevocationStore) error {
+	if claims.ID == "" {
+		return &jwt.ValidationError{Inner: errNoTokenID, Errors: jwt.ValidationErrorId}
+	}
+	revoked, err := store.IsRevoked(ctx, claims.ID)
+	if err != nil {
+		return &jwt.ValidationError{Inner: err, Errors: jwt.ValidationErrorClaimsInvalid}
+	}
+	if revoked {
+		return &jwt.ValidationError{Inner: ErrTokenRevoked, Errors: jwt.ValidationErrorId}
+	}
+	return nil
+}
+
+// RevokeToken revokes the given jwt token string in RevocationStore by its "jti" claim, and the record will expire at the token's "exp".
+// Note that the token is not verified here, so please make sure it has been verified before, such as by ParseToken.
+func RevokeToken(ctx context.Context, store RevocationStore, signedToken string) error {
+	claims, err := decodeRegisteredClaims(signedToken)
+	if err != nil {
+		return newValidationError(err, nil, nil, time.Time{})
+	}
+	if claims.ID == "" {
+		return errNoTokenID
+	}
+	var expiresAt time.Time // zero means never expire
+	if claims.ExpiresAt != nil {
+		expiresAt = claims.ExpiresAt.Time
+	}
+	return store.Revoke(ctx, claims.ID, expiresAt)
+}
+
+// IsRevokedError checks error is a token revoked error, which is caused by WithRevocationStore. Note that this kind of error is also a
+// JTI (Id) validation error, see IsIdError.
+func IsRevokedError(err error) bool {
+	if ve, ok := err.(*jwt.ValidationError); ok {
+		err = ve.Inner
+	}
+	return errors.Is(err, ErrTokenRevoked)
+}
+
+// MemoryRevocationStore represents an in-memory RevocationStore, which is only suitable for single instance service and testing. Records
+// are removed after they expire.
+type MemoryRevocationStore struct {
+	mu    sync.Mutex
+	items map[string]time.Time
+	now   func() time.Time
+}
+
+var _ RevocationStore = (*MemoryRevocationStore)(nil)
+
+// NewMemoryRevocationStore creates an empty MemoryRevocationStore.
+func NewMemoryRevocationStore() *MemoryRevocationStore {
+	return &MemoryRevocationStore{items: make(map[string]time.Time), now: time.Now}
+}
+
+// Revoke records the given token id as revoked until expiresAt, zero expiresAt means never expire, this implements RevocationStore.
+func (m *MemoryRevocationStore) Revoke(_ context.Context, tokenID string, expiresAt time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.prune()
+	if expiresAt.IsZero() || m.now().Before(expiresAt) {
+		m.items[tokenID] = expiresAt
+	}
+	return nil
+}
+
+// IsRevoked checks whether the given token id has been revoked and not expired, this implements RevocationStore.
+func (m *MemoryRevocationStore) IsRevoked(_ context.Context, tokenID string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	expiresAt, ok := m.items[tokenID]
+	if !ok {
+		return false, nil
+	}
+	if !expiresAt.IsZero() && !m.now().Before(expiresAt) {
+		delete(m.items, tokenID)
+		return false, nil
+	}
+	return true, nil
+}
+
+// Len returns the count of revoked records, including expired but not removed records.
+func (m *MemoryRevocationStore) Len() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.items)
+}
+
+// prune removes all the expired records, caller must hold the lock.
+func (m *MemoryRevocationStore) prune() {
+	now := m.now()
+	for id, expiresAt := range m.items {
+		if !expiresAt.IsZero() && !now.Before(expiresAt) {
+			delete(m.items, id)
+		}
+	}
+}
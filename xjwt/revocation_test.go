@@ -0,0 +1,130 @@
+package xjwt
+
+import (
+	"context"
+	"errors"
+	"github.com/Aoi-hosizora/ahlib/xtesting"
+	"github.com/golang-jwt/jwt/v4"
+	"testing"
+	"time"
+)
+
+func TestAutoTokenID(t *testing.T) {
+	secret := []byte("secret")
+	ids := make(map[string]bool)
+	for _, claims := range []jwt.Claims{&jwt.StandardClaims{Subject: "test"}, &jwt.RegisteredClaims{Subject: "test"}, jwt.MapClaims{"sub": "test"}} {
+		for i := 0; i < 2; i++ {
+			token, err := GenerateTokenWithHS256(claims, secret)
+			xtesting.Nil(t, err)
+			parsed, err := ParseToken(token, secret, &jwt.RegisteredClaims{})
+			xtesting.Nil(t, err)
+			id := parsed.Claims.(*jwt.RegisteredClaims).ID
+			xtesting.Equal(t, len(id), 32)
+			xtesting.False(t, ids[id])
+			ids[id] = true
+			xtesting.Equal(t, parsed.Claims.(*jwt.RegisteredClaims).Subject, "test")
+		}
+	}
+	xtesting.Equal(t, jwt.MapClaims{"sub": "test"}, jwt.MapClaims{"sub": "test"}) // not modified
+
+	// existed and disabled
+	token, err := GenerateTokenWithHS256(&jwt.StandardClaims{Id: "id"}, secret)
+	xtesting.Nil(t, err)
+	parsed, err := ParseToken(token, secret, &jwt.StandardClaims{})
+	xtesting.Nil(t, err)
+	xtesting.Equal(t, parsed.Claims.(*jwt.StandardClaims).Id, "id")
+	token, err = GenerateToken(jwt.SigningMethodHS256, &jwt.StandardClaims{}, secret, WithAutoTokenID(false))
+	xtesting.Nil(t, err)
+	parsed, err = ParseToken(token, secret, &jwt.StandardClaims{})
+	xtesting.Nil(t, err)
+	xtesting.Equal(t, parsed.Claims.(*jwt.StandardClaims).Id, "")
+	testKeys()
+	token, err = GenerateTokenWithES256(&jwt.StandardClaims{}, testEC256Key)
+	xtesting.Nil(t, err)
+	parsed, err = ParseTokenWithKey(token, testEC256Key, &jwt.StandardClaims{})
+	xtesting.Nil(t, err)
+	xtesting.Equal(t, len(parsed.Claims.(*jwt.StandardClaims).Id), 32)
+}
+
+type errorRevocationStore struct{}
+
+func (errorRevocationStore) Revoke(context.Context, string, time.Time) error { return errors.New("test") }
+func (errorRevocationStore) IsRevoked(context.Context, string) (bool, error) { return false, errors.New("test") }
+
+type contextRevocationStore struct{}
+
+func (contextRevocationStore) Revoke(ctx context.Context, _ string, _ time.Time) error { return ctx.Err() }
+func (contextRevocationStore) IsRevoked(ctx context.Context, _ string) (bool, error) { return false, ctx.Err() }
+
+func TestRevocation(t *testing.T) {
+	ctx := context.Background()
+	secret := []byte("secret")
+	now := time.Now()
+	store := NewMemoryRevocationStore()
+	store.now = func() time.Time { return now }
+	withStore := WithRevocationStore(store)
+
+	token1, err := GenerateTokenWithHS256(&jwt.StandardClaims{ExpiresAt: now.Add(time.Hour).Unix()}, secret)
+	xtesting.Nil(t, err)
+	token2, err := GenerateTokenWithHS256(&jwt.StandardClaims{}, secret)
+	xtesting.Nil(t, err)
+	_, err = ParseToken(token1, secret, &jwt.StandardClaims{}, withStore)
+	xtesting.Nil(t, err)
+
+	// revoke
+	xtesting.Nil(t, RevokeToken(ctx, store, token1))
+	xtesting.Nil(t, RevokeToken(ctx, store, token2))
+	xtesting.Equal(t, store.Len(), 2)
+	for _, token := range []string{token1, token2} {
+		_, err = ParseToken(token, secret, &jwt.StandardClaims{}, withStore)
+		xtesting.NotNil(t, err)
+		xtesting.True(t, IsIdError(err))
+		xtesting.True(t, IsRevokedError(err))
+		xtesting.False(t, IsTokenInvalidError(err))
+		_, err = ParseToken(token, secret, &jwt.StandardClaims{})
+		xtesting.Nil(t, err)
+	}
+
+	// expire
+	now = now.Add(time.Hour + time.Second)
+	revoked, err := store.IsRevoked(ctx, "not-exist")
+	xtesting.Nil(t, err)
+	xtesting.False(t, revoked)
+	_, err = ParseToken(token1, secret, &jwt.StandardClaims{}, withStore, WithTimeFunc(func() time.Time { return now }), WithLeeway(time.Hour))
+	xtesting.Nil(t, err)
+	xtesting.Equal(t, store.Len(), 1)
+	xtesting.Nil(t, store.Revoke(ctx, "expired", now.Add(-time.Second)))
+	xtesting.Nil(t, store.Revoke(ctx, "valid", now.Add(time.Second)))
+	xtesting.Equal(t, store.Len(), 2)
+	now = now.Add(time.Second)
+	xtesting.Nil(t, store.Revoke(ctx, "valid2", now.Add(time.Second)))
+	xtesting.Equal(t, store.Len(), 2)
+
+	// no jti, invalid token and store error
+	noID, err := GenerateToken(jwt.SigningMethodHS256, &jwt.StandardClaims{}, secret, WithAutoTokenID(false))
+	xtesting.Nil(t, err)
+	_, err = ParseToken(noID, secret, &jwt.StandardClaims{}, withStore)
+	xtesting.True(t, IsIdError(err))
+	xtesting.False(t, IsRevokedError(err))
+	xtesting.NotNil(t, RevokeToken(ctx, store, noID))
+	xtesting.NotNil(t, RevokeToken(ctx, store, "a.b"))
+	xtesting.NotNil(t, RevokeToken(ctx, store, "a.!.c"))
+	xtesting.NotNil(t, RevokeToken(ctx, store, "a.YQ.c"))
+	_, err = ParseToken(token2, secret, &jwt.StandardClaims{}, WithRevocationStore(errorRevocationStore{}))
+	xtesting.True(t, IsClaimsInvalidError(err))
+	xtesting.False(t, IsRevokedError(err))
+
+	// context
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	withCtxStore := WithRevocationStore(contextRevocationStore{})
+	_, err = ParseToken(token2, secret, &jwt.StandardClaims{}, withCtxStore)
+	xtesting.Nil(t, err)
+	_, err = ParseToken(token2, secret, &jwt.StandardClaims{}, withCtxStore, WithContext(nil))
+	xtesting.Nil(t, err)
+	_, err = ParseToken(token2, secret, &jwt.StandardClaims{}, withCtxStore, WithContext(canceled))
+	xtesting.True(t, IsClaimsInvalidError(err))
+	xtesting.True(t, errors.Is(err, context.Canceled))
+	xtesting.False(t, IsRevokedError(nil))
+	xtesting.True(t, IsRevokedError(ErrTokenRevoked))
+}
@@ -0,0 +1,217 @@
+package xjwt
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/pem"
+	"errors"
+	"github.com/golang-jwt/jwt/v4"
+	"math/big"
+)
+
+// GenerateTokenWithSigner generates token using given jwt.Claims, crypto.Signer and jwt.SigningMethod. The signer can be any crypto.Signer,
+// such as *rsa.PrivateKey, *ecdsa.PrivateKey, ed25519.PrivateKey, or a signer backed by hardware or KMS. Supported signing methods are
+// RS256/384/512, PS256/384/512, ES256/384/512 and EdDSA.
+func GenerateTokenWithSigner(method jwt.SigningMethod, claims jwt.Claims, signer crypto.Signer, options ...GenerateOption) (string, error) {
+	return generateTokenWithSigner(newTokenWithOptions(method, claims, newGenerateOptions(options)), signer)
+}
+
+// generateTokenWithSigner signs given jwt.Token using crypto.Signer.
+func generateTokenWithSigner(tokenObj *jwt.Token, signer crypto.Signer) (string, error) {
+	if signer == nil {
+		return "", jwt.ErrInvalidKey
+	}
+	signingString, err := tokenObj.SigningString()
+	if err != nil {
+		return "", err
+	}
+	signature, err := signWithSigner(tokenObj.Method, []byte(signingString), signer)
+	if err != nil {
+		return "", err
+	}
+	return signingString + "." + jwt.EncodeSegment(signature), nil
+}
+
+// signWithSigner signs given data using crypto.Signer in the way of given jwt.SigningMethod.
+func signWithSigner(method jwt.SigningMethod, data []byte, signer crypto.Signer) ([]byte, error) {
+	switch m := method.(type) {
+	case *jwt.SigningMethodRSA:
+		if _, ok := signer.Public().(*rsa.PublicKey); !ok {
+			return nil, jwt.ErrInvalidKeyType
+		}
+		digest, err := hashData(m.Hash, data)
+		if err != nil {
+			return nil, err
+		}
+		return signer.Sign(rand.Reader, digest, m.Hash)
+	case *jwt.SigningMethodRSAPSS:
+		if _, ok := signer.Public().(*rsa.PublicKey); !ok {
+			return nil, jwt.ErrInvalidKeyType
+		}
+		digest, err := hashData(m.Hash, data)
+		if err != nil {
+			return nil, err
+		}
+		return signer.Sign(rand.Reader, digest, &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash, Hash: m.Hash})
+	case *jwt.SigningMethodECDSA:
+		pub, ok := signer.Public().(*ecdsa.PublicKey)
+		if !ok {
+			return nil, jwt.ErrInvalidKeyType
+		}
+		if pub.Curve.Params().BitSize != m.CurveBits {
+			return nil, jwt.ErrInvalidKey
+		}
+		digest, err := hashData(m.Hash, data)
+		if err != nil {
+			return nil, err
+		}
+		der, err := signer.Sign(rand.Reader, digest, m.Hash)
+		if err != nil {
+			return nil, err
+		}
+		return ecdsaSignatureFromASN1(der, m.KeySize)
+	case *jwt.SigningMethodEd25519:
+		if _, ok := signer.Public().(ed25519.PublicKey); !ok {
+			return nil, jwt.ErrInvalidKeyType
+		}
+		return signer.Sign(rand.Reader, data, crypto.Hash(0))
+	default:
+		return nil, jwt.ErrInvalidKeyType
+	}
+}
+
+// hashData hashes given data using crypto.Hash.
+func hashData(hash crypto.Hash, data []byte) ([]byte, error) {
+	if !hash.Available() {
+		return nil, jwt.ErrHashUnavailable
+	}
+	h := hash.New()
+	_, _ = h.Write(data)
+	return h.Sum(nil), nil
+}
+
+// ecdsaSignatureFromASN1 converts ASN.1 DER encoded ECDSA signature to the fixed-size R || S format used by JWS.
+func ecdsaSignatureFromASN1(der []byte, keySize int) ([]byte, error) {
+	var sig struct {
+		R, S *big.Int
+	}
+	if rest, err := asn1.Unmarshal(der, &sig); err != nil {
+		return nil, err
+	} else if len(rest) != 0 || sig.R == nil || sig.S == nil {
+		return nil, errors.New("xjwt: invalid ecdsa signature")
+	}
+	rBytes, sBytes := sig.R.Bytes(), sig.S.Bytes()
+	if len(rBytes) > keySize || len(sBytes) > keySize {
+		return nil, errors.New("xjwt: invalid ecdsa signature")
+	}
+	out := make([]byte, 2*keySize)
+	copy(out[keySize-len(rBytes):keySize], rBytes)
+	copy(out[2*keySize-len(sBytes):], sBytes)
+	return out, nil
+}
+
+// GenerateTokenWithPEM generates token using given jwt.Claims, PEM encoded private key and jwt.SigningMethod. See ParsePrivateKeyFromPEM
+// for supported PEM formats.
+func GenerateTokenWithPEM(method jwt.SigningMethod, claims jwt.Claims, pemKey []byte, options ...GenerateOption) (string, error) {
+	signer, err := ParsePrivateKeyFromPEM(pemKey)
+	if err != nil {
+		return "", err
+	}
+	return GenerateTokenWithSigner(method, claims, signer, options...)
+}
+
+// ParseTokenWithPEM parses jwt token string using given PEM encoded public key (or certificate) and custom jwt.Claims, and returns
+// jwt.Token. See ParsePublicKeyFromPEM for supported PEM formats.
+func ParseTokenWithPEM(signedToken string, pemKey []byte, claims jwt.Claims, options ...ParseOption) (*jwt.Token, error) {
+	key, err := ParsePublicKeyFromPEM(pemKey)
+	if err != nil {
+		return nil, err
+	}
+	return ParseTokenWithKey(signedToken, key, claims, options...)
+}
+
+var (
+	errInvalidPEM        = errors.New("xjwt: invalid PEM encoded key")
+	errUnsupportedPEMKey = errors.New("xjwt: unsupported PEM key type")
+)
+
+// ParsePrivateKeyFromPEM parses PEM encoded private key to crypto.Signer, supports PKCS#1 RSA private key ("RSA PRIVATE KEY"), SEC 1 EC
+// private key ("EC PRIVATE KEY") and PKCS#8 private key ("PRIVATE KEY") which contains RSA, ECDSA or Ed25519 key.
+func ParsePrivateKeyFromPEM(pemKey []byte) (crypto.Signer, error) {
+	block, _ := pem.Decode(pemKey)
+	if block == nil {
+		return nil, errInvalidPEM
+	}
+
+	var key interface{}
+	var err error
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		key, err = x509.ParseECPrivateKey(block.Bytes)
+	default:
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	}
+	if err != nil {
+		return nil, err
+	}
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, errUnsupportedPEMKey
+	}
+	return signer, nil
+}
+
+// ParsePublicKeyFromPEM parses PEM encoded public key to crypto.PublicKey, supports PKCS#1 RSA public key ("RSA PUBLIC KEY"), PKIX public
+// key ("PUBLIC KEY") which contains RSA, ECDSA or Ed25519 key, and X.509 certificate ("CERTIFICATE").
+func ParsePublicKeyFromPEM(pemKey []byte) (crypto.PublicKey, error) {
+	block, _ := pem.Decode(pemKey)
+	if block == nil {
+		return nil, errInvalidPEM
+	}
+
+	var key interface{}
+	var err error
+	switch block.Type {
+	case "RSA PUBLIC KEY":
+		key, err = x509.ParsePKCS1PublicKey(block.Bytes)
+	case "CERTIFICATE":
+		var cert *x509.Certificate
+		if cert, err = x509.ParseCertificate(block.Bytes); err == nil {
+			key = cert.PublicKey
+		}
+	default:
+		key, err = x509.ParsePKIXPublicKey(block.Bytes)
+	}
+	if err != nil {
+		return nil, err
+	}
+	switch key.(type) {
+	case *rsa.PublicKey, *ecdsa.PublicKey, ed25519.PublicKey:
+		return key, nil
+	}
+	return nil, errUnsupportedPEMKey
+}
+
+// verificationKey returns the key used to verify signature, private keys will be converted to their public keys.
+func verificationKey(key interface{}) interface{} {
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		return &k.PublicKey
+	case *ecdsa.PrivateKey:
+		return &k.PublicKey
+	case ed25519.PrivateKey:
+		return k.Public()
+	case []byte:
+		return k
+	case crypto.Signer:
+		return k.Public()
+	}
+	return key
+}
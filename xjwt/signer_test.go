@@ -0,0 +1,204 @@
+package xjwt
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"github.com/Aoi-hosizora/ahlib/xtesting"
+	"github.com/golang-jwt/jwt/v4"
+	"io"
+	"math/big"
+	"sync"
+	"testing"
+	"time"
+)
+
+var (
+	testKeysOnce sync.Once
+	testRSAKey   *rsa.PrivateKey
+	testEC256Key *ecdsa.PrivateKey
+	testEC384Key *ecdsa.PrivateKey
+	testEC521Key *ecdsa.PrivateKey
+	testEdKey    ed25519.PrivateKey
+)
+
+// testKeys generates keys used in tests only once.
+func testKeys() {
+	testKeysOnce.Do(func() {
+		testRSAKey, _ = rsa.GenerateKey(rand.Reader, 2048)
+		testEC256Key, _ = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		testEC384Key, _ = ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+		testEC521Key, _ = ecdsa.GenerateKey(elliptic.P521(), rand.Reader)
+		_, testEdKey, _ = ed25519.GenerateKey(rand.Reader)
+	})
+}
+
+// opaqueSigner hides the concrete private key type, just like a KMS signer.
+type opaqueSigner struct {
+	signer crypto.Signer
+}
+
+func (o opaqueSigner) Public() crypto.PublicKey { return o.signer.Public() }
+func (o opaqueSigner) Sign(r io.Reader, digest []byte, opts crypto.SignerOpts) ([]byte, error) {
+	return o.signer.Sign(r, digest, opts)
+}
+
+func TestGenerateTokenWithSigner(t *testing.T) {
+	testKeys()
+	claims := &jwt.StandardClaims{Subject: "test", ExpiresAt: time.Now().Add(time.Hour).Unix()}
+
+	for _, tc := range []struct {
+		giveFn  func(jwt.Claims, crypto.Signer) (string, error)
+		giveKey crypto.Signer
+		wantAlg string
+	}{
+		{GenerateTokenWithRS256, testRSAKey, "RS256"},
+		{GenerateTokenWithRS384, testRSAKey, "RS384"},
+		{GenerateTokenWithRS512, testRSAKey, "RS512"},
+		{GenerateTokenWithPS256, testRSAKey, "PS256"},
+		{GenerateTokenWithPS384, testRSAKey, "PS384"},
+		{GenerateTokenWithPS512, testRSAKey, "PS512"},
+		{GenerateTokenWithES256, testEC256Key, "ES256"},
+		{GenerateTokenWithES384, testEC384Key, "ES384"},
+		{GenerateTokenWithES512, testEC521Key, "ES512"},
+		{GenerateTokenWithEdDSA, testEdKey, "EdDSA"},
+	} {
+		for _, key := range []crypto.Signer{tc.giveKey, opaqueSigner{tc.giveKey}} {
+			token, err := tc.giveFn(claims, key)
+			xtesting.Nil(t, err)
+
+			// verify by jwt itself
+			parsed, err := jwt.ParseWithClaims(token, &jwt.StandardClaims{}, func(*jwt.Token) (interface{}, error) { return key.Public(), nil })
+			xtesting.Nil(t, err)
+			xtesting.Equal(t, parsed.Method.Alg(), tc.wantAlg)
+			xtesting.Equal(t, parsed.Claims.(*jwt.StandardClaims).Subject, "test")
+
+			// verify by xjwt, with public key and private key
+			for _, verifyKey := range []interface{}{key.Public(), tc.giveKey, key} {
+				parsed, err = ParseTokenWithKey(token, verifyKey, &jwt.StandardClaims{})
+				xtesting.Nil(t, err)
+				xtesting.Equal(t, parsed.Claims.(*jwt.StandardClaims).Subject, "test")
+			}
+
+			// modified token
+			_, err = ParseTokenWithKey(token[:len(token)-4]+"AAAA", key.Public(), &jwt.StandardClaims{})
+			xtesting.NotNil(t, err)
+			xtesting.True(t, IsTokenInvalidError(err))
+		}
+	}
+
+	// invalid keys
+	for _, tc := range []struct {
+		giveMethod jwt.SigningMethod
+		giveKey    crypto.Signer
+	}{
+		{jwt.SigningMethodRS256, nil},
+		{jwt.SigningMethodRS256, testEC256Key},
+		{jwt.SigningMethodPS256, testEdKey},
+		{jwt.SigningMethodES256, testRSAKey},
+		{jwt.SigningMethodES256, testEC384Key},
+		{jwt.SigningMethodEdDSA, testRSAKey},
+		{jwt.SigningMethodHS256, testRSAKey},
+		{jwt.SigningMethodNone, testRSAKey},
+	} {
+		_, err := GenerateTokenWithSigner(tc.giveMethod, claims, tc.giveKey)
+		xtesting.NotNil(t, err)
+	}
+
+	// wrong verification key
+	token, err := GenerateTokenWithRS256(claims, testRSAKey)
+	xtesting.Nil(t, err)
+	otherKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	_, err = ParseTokenWithKey(token, &otherKey.PublicKey, &jwt.StandardClaims{})
+	xtesting.NotNil(t, err)
+	xtesting.True(t, IsTokenInvalidError(err))
+	_, err = ParseTokenWithKey(token, testEC256Key.Public(), &jwt.StandardClaims{})
+	xtesting.NotNil(t, err)
+	xtesting.True(t, IsTokenInvalidError(err))
+}
+
+func TestEcdsaSignatureFromASN1(t *testing.T) {
+	_, err := ecdsaSignatureFromASN1([]byte{0x00}, 32)
+	xtesting.NotNil(t, err)
+	_, err = ecdsaSignatureFromASN1([]byte{0x30, 0x06, 0x02, 0x01, 0x01, 0x02, 0x01, 0x02, 0x00}, 32) // trailing data
+	xtesting.NotNil(t, err)
+	_, err = ecdsaSignatureFromASN1([]byte{0x30, 0x08, 0x02, 0x03, 0x01, 0x02, 0x03, 0x02, 0x01, 0x02}, 2) // too large
+	xtesting.NotNil(t, err)
+	sig, err := ecdsaSignatureFromASN1([]byte{0x30, 0x06, 0x02, 0x01, 0x01, 0x02, 0x01, 0x02}, 4)
+	xtesting.Nil(t, err)
+	xtesting.Equal(t, sig, []byte{0, 0, 0, 1, 0, 0, 0, 2})
+}
+
+// testPEMs encodes test keys to PEM, returns pairs of private and public PEM.
+func testPEMs(t *testing.T) [][2][]byte {
+	testKeys()
+	encode := func(typ string, der []byte) []byte {
+		return pem.EncodeToMemory(&pem.Block{Type: typ, Bytes: der})
+	}
+	pkcs8 := func(key interface{}) []byte {
+		der, err := x509.MarshalPKCS8PrivateKey(key)
+		xtesting.Nil(t, err)
+		return encode("PRIVATE KEY", der)
+	}
+	pkix := func(key interface{}) []byte {
+		der, err := x509.MarshalPKIXPublicKey(key)
+		xtesting.Nil(t, err)
+		return encode("PUBLIC KEY", der)
+	}
+	ecDer, err := x509.MarshalECPrivateKey(testEC256Key)
+	xtesting.Nil(t, err)
+
+	return [][2][]byte{
+		{encode("RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(testRSAKey)), encode("RSA PUBLIC KEY", x509.MarshalPKCS1PublicKey(&testRSAKey.PublicKey))},
+		{pkcs8(testRSAKey), pkix(&testRSAKey.PublicKey)},
+		{encode("EC PRIVATE KEY", ecDer), pkix(&testEC256Key.PublicKey)},
+		{pkcs8(testEC256Key), pkix(&testEC256Key.PublicKey)},
+		{pkcs8(testEdKey), pkix(testEdKey.Public())},
+	}
+}
+
+func TestPEM(t *testing.T) {
+	claims := &jwt.StandardClaims{Subject: "test"}
+	methods := []jwt.SigningMethod{jwt.SigningMethodRS256, jwt.SigningMethodRS256, jwt.SigningMethodES256, jwt.SigningMethodES256, jwt.SigningMethodEdDSA}
+	for i, pair := range testPEMs(t) {
+		token, err := GenerateTokenWithPEM(methods[i], claims, pair[0])
+		xtesting.Nil(t, err)
+		parsed, err := ParseTokenWithPEM(token, pair[1], &jwt.StandardClaims{})
+		xtesting.Nil(t, err)
+		xtesting.Equal(t, parsed.Claims.(*jwt.StandardClaims).Subject, "test")
+	}
+
+	// certificate
+	template := &x509.Certificate{SerialNumber: big.NewInt(1), Subject: pkix.Name{CommonName: "test"}, NotBefore: time.Now(), NotAfter: time.Now().Add(time.Hour)}
+	certDer, err := x509.CreateCertificate(rand.Reader, template, template, testEC256Key.Public(), testEC256Key)
+	xtesting.Nil(t, err)
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certDer})
+	token, err := GenerateTokenWithES256(claims, testEC256Key)
+	xtesting.Nil(t, err)
+	_, err = ParseTokenWithPEM(token, certPEM, &jwt.StandardClaims{})
+	xtesting.Nil(t, err)
+
+	// invalid pem
+	for _, give := range [][]byte{nil, []byte("test"), pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: []byte{1}}),
+		pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: []byte{1}}), pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: []byte{1}})} {
+		k, err := ParsePrivateKeyFromPEM(give)
+		xtesting.Nil(t, k)
+		xtesting.NotNil(t, err)
+		_, err = GenerateTokenWithPEM(jwt.SigningMethodRS256, claims, give)
+		xtesting.NotNil(t, err)
+	}
+	for _, give := range [][]byte{nil, []byte("test"), pem.EncodeToMemory(&pem.Block{Type: "RSA PUBLIC KEY", Bytes: []byte{1}}),
+		pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: []byte{1}}), pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: []byte{1}})} {
+		k, err := ParsePublicKeyFromPEM(give)
+		xtesting.Nil(t, k)
+		xtesting.NotNil(t, err)
+		_, err = ParseTokenWithPEM(token, give, &jwt.StandardClaims{})
+		xtesting.NotNil(t, err)
+	}
+}
@@ -0,0 +1,330 @@
+package xjwt
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"github.com/golang-jwt/jwt/v4"
+	"sync"
+	"time"
+)
+
+const (
+	// AccessTokenType is the token type of access token in TokenPairClaims.
+	AccessTokenType = "access"
+
+	// RefreshTokenType is the token type of refresh token in TokenPairClaims.
+	RefreshTokenType = "refresh"
+)
+
+// TokenPairClaims represents the claims of tokens generated by TokenPairIssuer, the registered claims "sub", "exp", "iat" and "jti"
+// are always set, and "iss" and "aud" are set if configured.
+type TokenPairClaims struct {
+	jwt.RegisteredClaims
+
+	// TokenType represents the token type, AccessTokenType or RefreshTokenType.
+	TokenType string `json:"token_type"`
+
+	// FamilyID represents the id of token family, all the tokens refreshed from the same login share the same family id.
+	FamilyID string `json:"fid"`
+
+	// Extra represents the extra custom claims, which will be kept after refreshing.
+	Extra map[string]interface{} `json:"ext,omitempty"`
+}
+
+// TokenPair represents a pair of access token and refresh token, generated by TokenPairIssuer.
+type TokenPair struct {
+	AccessToken      string    `json:"access_token"`
+	AccessExpiresAt  time.Time `json:"access_expires_at"`
+	RefreshToken     string    `json:"refresh_token"`
+	RefreshExpiresAt time.Time `json:"refresh_expires_at"`
+}
+
+// RefreshTokenStore represents a storage of refresh tokens used by TokenPairIssuer, which records the only valid refresh token id of each
+// token family. Note that implementations must be safe for concurrent use.
+type RefreshTokenStore interface {
+	// Save records the given refresh token id as the current token of given family, which will expire at expiresAt.
+	Save(ctx context.Context, familyID, tokenID string, expiresAt time.Time) error
+
+	// Rotate atomically replaces the current token of given family from oldTokenID to newTokenID. ErrRefreshTokenRevoked must be returned
+	// if the family does not exist or has been expired, and ErrRefreshTokenReused must be returned (and the whole family should be
+	// revoked) if oldTokenID is not the current token, which means a used refresh token is reused.
+	Rotate(ctx context.Context, familyID, oldTokenID, newTokenID string, expiresAt time.Time) error
+
+	// RevokeFamily revokes the whole token family, it is not an error if the family does not exist.
+	RevokeFamily(ctx context.Context, familyID string) error
+}
+
+var (
+	// ErrRefreshTokenReused represents an error of a used refresh token being reused, which usually means the token has been stolen, and
+	// the whole token family has been revoked.
+	ErrRefreshTokenReused = errors.New("xjwt: refresh token reused")
+
+	// ErrRefreshTokenRevoked represents an error of refresh token revoked or expired in RefreshTokenStore.
+	ErrRefreshTokenRevoked = errors.New("xjwt: refresh token revoked")
+
+	errInvalidTokenType = errors.New("xjwt: invalid token type")
+)
+
+// TokenPairIssuerConfig represents TokenPairIssuer's config.
+type TokenPairIssuerConfig struct {
+	// Method represents the signing method of tokens, required.
+	Method jwt.SigningMethod
+
+	// Key represents the key used to sign and verify tokens, which will be passed to GenerateToken and ParseTokenWithKey, required.
+	Key interface{}
+
+	// Issuer represents the "iss" claim of tokens, which is also required when parsing, defaults to empty, no issuer will be set.
+	Issuer string
+
+	// Audience represents the "aud" claim of tokens, which is also required when parsing, defaults to empty, no audience will be set.
+	Audience []string
+
+	// AccessTokenTTL represents the lifetime of access token, defaults to 15 minutes.
+	AccessTokenTTL time.Duration
+
+	// RefreshTokenTTL represents the lifetime of refresh token, defaults to 7 days.
+	RefreshTokenTTL time.Duration
+
+	// Store represents the RefreshTokenStore used to rotate refresh tokens, defaults to a new MemoryRefreshTokenStore.
+	Store RefreshTokenStore
+
+	// TimeFunc represents the function to get current time, defaults to time.Now.
+	TimeFunc func() time.Time
+}
+
+// TokenPairIssuer represents an issuer of access and refresh token pairs. Refresh tokens are rotated on each refreshing, and reusing a
+// refresh token which has been rotated will revoke the whole token family.
+// Example:
+// 	issuer := NewTokenPairIssuer(&TokenPairIssuerConfig{Method: jwt.SigningMethodHS256, Key: secret, Issuer: "auth"})
+// 	pair, _ := issuer.Issue(ctx, "user-1", nil)              // login
+// 	claims, err := issuer.ParseAccessToken(pair.AccessToken) // authenticate
+// 	newPair, err := issuer.Refresh(ctx, pair.RefreshToken)   // refresh
+// 	_, err = issuer.Refresh(ctx, pair.RefreshToken)          // ErrRefreshTokenReused, family revoked
+type TokenPairIssuer struct {
+	// config is the token pair issuer config.
+	config *TokenPairIssuerConfig
+}
+
+const (
+	panicNilConfig        = "xjwt: nil config"
+	panicNilMethodOrKey   = "xjwt: nil signing method or key"
+	panicNegativeLifetime = "xjwt: negative token lifetime"
+)
+
+// NewTokenPairIssuer creates a TokenPairIssuer with TokenPairIssuerConfig.
+func NewTokenPairIssuer(config *TokenPairIssuerConfig) *TokenPairIssuer {
+	if config == nil {
+		panic(panicNilConfig)
+	}
+	if config.Method == nil || config.Key == nil {
+		panic(panicNilMethodOrKey)
+	}
+	if config.AccessTokenTTL < 0 || config.RefreshTokenTTL < 0 {
+		panic(panicNegativeLifetime)
+	}
+	if config.AccessTokenTTL == 0 {
+		config.AccessTokenTTL = 15 * time.Minute
+	}
+	if config.RefreshTokenTTL == 0 {
+		config.RefreshTokenTTL = 7 * 24 * time.Hour
+	}
+	if config.Store == nil {
+		config.Store = NewMemoryRefreshTokenStore()
+	}
+	if config.TimeFunc == nil {
+		config.TimeFunc = time.Now
+	}
+
+	return &TokenPairIssuer{config: config}
+}
+
+// newTokenID generates a random token id, which is 32 hex characters.
+func newTokenID() (string, error) {
+	bs := make([]byte, 16)
+	if _, err := rand.Read(bs); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(bs), nil
+}
+
+// Issue issues a new token pair with given subject and extra claims, and a new token family will be created.
+func (t *TokenPairIssuer) Issue(ctx context.Context, subject string, extra map[string]interface{}) (*TokenPair, error) {
+	familyID, err := newTokenID()
+	if err != nil {
+		return nil, err
+	}
+	pair, refreshID, err := t.generatePair(familyID, subject, extra)
+	if err != nil {
+		return nil, err
+	}
+	if err = t.config.Store.Save(ctx, familyID, refreshID, pair.RefreshExpiresAt); err != nil {
+		return nil, err
+	}
+	return pair, nil
+}
+
+// Refresh validates given refresh token, rotates it in RefreshTokenStore, and issues a new token pair in the same token family with the
+// same subject and extra claims. ErrRefreshTokenReused will be returned if the refresh token has been used.
+func (t *TokenPairIssuer) Refresh(ctx context.Context, refreshToken string) (*TokenPair, error) {
+	claims, err := t.parseToken(refreshToken, RefreshTokenType)
+	if err != nil {
+		return nil, err
+	}
+	pair, refreshID, err := t.generatePair(claims.FamilyID, claims.Subject, claims.Extra)
+	if err != nil {
+		return nil, err
+	}
+	if err = t.config.Store.Rotate(ctx, claims.FamilyID, claims.ID, refreshID, pair.RefreshExpiresAt); err != nil {
+		return nil, err
+	}
+	return pair, nil
+}
+
+// Revoke validates given refresh token and revokes its token family, this is usually used when logging out. Note that the access tokens
+// that have been issued are still valid until they expire.
+func (t *TokenPairIssuer) Revoke(ctx context.Context, refreshToken string) error {
+	claims, err := t.parseToken(refreshToken, RefreshTokenType)
+	if err != nil {
+		return err
+	}
+	return t.config.Store.RevokeFamily(ctx, claims.FamilyID)
+}
+
+// ParseAccessToken parses and validates given access token, and returns its TokenPairClaims. Note that refresh tokens will be rejected,
+// and IsClaimsInvalidError will be true for the returned error.
+func (t *TokenPairIssuer) ParseAccessToken(accessToken string, options ...ParseOption) (*TokenPairClaims, error) {
+	return t.parseToken(accessToken, AccessTokenType, options...)
+}
+
+// generatePair generates a token pair in given token family, and returns the pair and the refresh token id.
+func (t *TokenPairIssuer) generatePair(familyID, subject string, extra map[string]interface{}) (*TokenPair, string, error) {
+	now := t.config.TimeFunc()
+	pair := &TokenPair{}
+	var refreshID string
+	for _, typ := range []string{AccessTokenType, RefreshTokenType} {
+		id, err := newTokenID()
+		if err != nil {
+			return nil, "", err
+		}
+		ttl := t.config.AccessTokenTTL
+		if typ == RefreshTokenType {
+			ttl = t.config.RefreshTokenTTL
+		}
+		claims := &TokenPairClaims{
+			RegisteredClaims: jwt.RegisteredClaims{
+				Issuer:    t.config.Issuer,
+				Subject:   subject,
+				Audience:  t.config.Audience,
+				ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+				IssuedAt:  jwt.NewNumericDate(now),
+				ID:        id,
+			},
+			TokenType: typ,
+			FamilyID:  familyID,
+			Extra:     extra,
+		}
+		token, err := GenerateToken(t.config.Method, claims, t.config.Key)
+		if err != nil {
+			return nil, "", err
+		}
+		if typ == AccessTokenType {
+			pair.AccessToken, pair.AccessExpiresAt = token, claims.ExpiresAt.Time
+		} else {
+			pair.RefreshToken, pair.RefreshExpiresAt, refreshID = token, claims.ExpiresAt.Time, id
+		}
+	}
+	return pair, refreshID, nil
+}
+
+// parseToken parses given token with configured key, issuer and audience, and checks its token type.
+func (t *TokenPairIssuer) parseToken(signedToken, tokenType string, options ...ParseOption) (*TokenPairClaims, error) {
+	opts := []ParseOption{WithAllowedAlgorithms(t.config.Method.Alg()), WithTimeFunc(t.config.TimeFunc)}
+	if t.config.Issuer != "" {
+		opts = append(opts, WithIssuer(t.config.Issuer))
+	}
+	if len(t.config.Audience) > 0 {
+		opts = append(opts, WithAudience(t.config.Audience...))
+	}
+	opts = append(opts, options...)
+
+	tokenObj, err := ParseTokenWithKey(signedToken, t.config.Key, &TokenPairClaims{}, opts...)
+	if err != nil {
+		return nil, err
+	}
+	claims := tokenObj.Claims.(*TokenPairClaims)
+	if claims.TokenType != tokenType || claims.FamilyID == "" || claims.ID == "" {
+		err = &jwt.ValidationError{Inner: errInvalidTokenType, Errors: jwt.ValidationErrorClaimsInvalid}
+		return nil, newValidationError(err, &claims.RegisteredClaims, nil, time.Time{})
+	}
+	return claims, nil
+}
+
+// MemoryRefreshTokenStore represents an in-memory RefreshTokenStore, which is only suitable for single instance service and testing.
+// Expired token families are removed when saving.
+type MemoryRefreshTokenStore struct {
+	mu       sync.Mutex
+	families map[string]*memoryTokenFamily
+	now      func() time.Time
+}
+
+// memoryTokenFamily represents a token family stored in MemoryRefreshTokenStore.
+type memoryTokenFamily struct {
+	tokenID   string
+	expiresAt time.Time
+}
+
+var _ RefreshTokenStore = (*MemoryRefreshTokenStore)(nil)
+
+// NewMemoryRefreshTokenStore creates an empty MemoryRefreshTokenStore.
+func NewMemoryRefreshTokenStore() *MemoryRefreshTokenStore {
+	return &MemoryRefreshTokenStore{families: make(map[string]*memoryTokenFamily), now: time.Now}
+}
+
+// Save records the given refresh token id as the current token of given family, this implements RefreshTokenStore.
+func (m *MemoryRefreshTokenStore) Save(_ context.Context, familyID, tokenID string, expiresAt time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := m.now()
+	for id, family := range m.families {
+		if !now.Before(family.expiresAt) {
+			delete(m.families, id)
+		}
+	}
+	m.families[familyID] = &memoryTokenFamily{tokenID: tokenID, expiresAt: expiresAt}
+	return nil
+}
+
+// Rotate atomically replaces the current token of given family, this implements RefreshTokenStore.
+func (m *MemoryRefreshTokenStore) Rotate(_ context.Context, familyID, oldTokenID, newTokenID string, expiresAt time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	family, ok := m.families[familyID]
+	if !ok || !m.now().Before(family.expiresAt) {
+		delete(m.families, familyID)
+		return ErrRefreshTokenRevoked
+	}
+	if family.tokenID != oldTokenID {
+		delete(m.families, familyID)
+		return ErrRefreshTokenReused
+	}
+	family.tokenID = newTokenID
+	family.expiresAt = expiresAt
+	return nil
+}
+
+// RevokeFamily revokes the whole token family, this implements RefreshTokenStore.
+func (m *MemoryRefreshTokenStore) RevokeFamily(_ context.Context, familyID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.families, familyID)
+	return nil
+}
+
+// Len returns the count of token families stored, including expired but not removed families.
+func (m *MemoryRefreshTokenStore) Len() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.families)
+}
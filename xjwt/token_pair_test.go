@@ -0,0 +1,134 @@
+package xjwt
+
+import (
+	"context"
+	"github.com/Aoi-hosizora/ahlib/xtesting"
+	"github.com/golang-jwt/jwt/v4"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestTokenPairIssuer(t *testing.T) {
+	xtesting.PanicWithValue(t, panicNilConfig, func() { NewTokenPairIssuer(nil) })
+	xtesting.PanicWithValue(t, panicNilMethodOrKey, func() { NewTokenPairIssuer(&TokenPairIssuerConfig{Key: []byte("x")}) })
+	xtesting.PanicWithValue(t, panicNilMethodOrKey, func() { NewTokenPairIssuer(&TokenPairIssuerConfig{Method: jwt.SigningMethodHS256}) })
+	xtesting.PanicWithValue(t, panicNegativeLifetime, func() {
+		NewTokenPairIssuer(&TokenPairIssuerConfig{Method: jwt.SigningMethodHS256, Key: []byte("x"), AccessTokenTTL: -1})
+	})
+
+	ctx := context.Background()
+	now := time.Unix(1600000000, 0)
+	store := NewMemoryRefreshTokenStore()
+	store.now = func() time.Time { return now }
+	secret := []byte("secret")
+	issuer := NewTokenPairIssuer(&TokenPairIssuerConfig{
+		Method: jwt.SigningMethodHS256, Key: secret, Issuer: "auth", Audience: []string{"api"},
+		RefreshTokenTTL: 24 * time.Hour, Store: store, TimeFunc: func() time.Time { return now },
+	})
+
+	// issue
+	pair, err := issuer.Issue(ctx, "user", map[string]interface{}{"role": "admin"})
+	xtesting.Nil(t, err)
+	xtesting.Equal(t, pair.AccessExpiresAt, now.Add(15*time.Minute))
+	xtesting.Equal(t, pair.RefreshExpiresAt, now.Add(24*time.Hour))
+	claims, err := issuer.ParseAccessToken(pair.AccessToken)
+	xtesting.Nil(t, err)
+	xtesting.Equal(t, claims.Subject, "user")
+	xtesting.Equal(t, claims.Issuer, "auth")
+	xtesting.Equal(t, claims.TokenType, AccessTokenType)
+	xtesting.Equal(t, claims.Extra["role"], "admin")
+	xtesting.Equal(t, store.Len(), 1)
+
+	// wrong token type, issuer and key
+	_, err = issuer.ParseAccessToken(pair.RefreshToken)
+	xtesting.True(t, IsClaimsInvalidError(err))
+	_, err = issuer.Refresh(ctx, pair.AccessToken)
+	xtesting.True(t, IsClaimsInvalidError(err))
+	other := NewTokenPairIssuer(&TokenPairIssuerConfig{Method: jwt.SigningMethodHS256, Key: secret, Issuer: "other", TimeFunc: func() time.Time { return now }})
+	_, err = other.ParseAccessToken(pair.AccessToken)
+	xtesting.True(t, IsIssuerError(err))
+	_, err = other.Refresh(ctx, pair.RefreshToken)
+	xtesting.True(t, IsIssuerError(err))
+	plain, err := GenerateTokenWithHS256(&jwt.RegisteredClaims{Issuer: "auth", Audience: jwt.ClaimStrings{"api"}}, secret)
+	xtesting.Nil(t, err)
+	_, err = issuer.ParseAccessToken(plain)
+	xtesting.True(t, IsClaimsInvalidError(err))
+
+	// refresh and reuse
+	now = now.Add(time.Hour)
+	newPair, err := issuer.Refresh(ctx, pair.RefreshToken)
+	xtesting.Nil(t, err)
+	newClaims, err := issuer.ParseAccessToken(newPair.AccessToken)
+	xtesting.Nil(t, err)
+	xtesting.Equal(t, newClaims.Subject, "user")
+	xtesting.Equal(t, newClaims.FamilyID, claims.FamilyID)
+	xtesting.Equal(t, newClaims.Extra["role"], "admin")
+	_, err = issuer.ParseAccessToken(pair.AccessToken)
+	xtesting.True(t, IsExpiredError(err))
+	_, err = issuer.Refresh(ctx, pair.RefreshToken)
+	xtesting.Equal(t, err, ErrRefreshTokenReused)
+	_, err = issuer.Refresh(ctx, newPair.RefreshToken) // family has been revoked
+	xtesting.Equal(t, err, ErrRefreshTokenRevoked)
+
+	// revoke
+	pair, err = issuer.Issue(ctx, "user", nil)
+	xtesting.Nil(t, err)
+	xtesting.Nil(t, issuer.Revoke(ctx, pair.RefreshToken))
+	_, err = issuer.Refresh(ctx, pair.RefreshToken)
+	xtesting.Equal(t, err, ErrRefreshTokenRevoked)
+	xtesting.NotNil(t, issuer.Revoke(ctx, pair.AccessToken))
+
+	// expired
+	pair, err = issuer.Issue(ctx, "user", nil)
+	xtesting.Nil(t, err)
+	now = now.Add(25 * time.Hour)
+	_, err = issuer.Refresh(ctx, pair.RefreshToken)
+	xtesting.True(t, IsExpiredError(err))
+	_, err = issuer.Issue(ctx, "user", nil)
+	xtesting.Nil(t, err)
+	xtesting.Equal(t, store.Len(), 1)
+
+	// concurrent refresh, only one succeeds
+	pair, err = issuer.Issue(ctx, "user", nil)
+	xtesting.Nil(t, err)
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	succeeded := 0
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := issuer.Refresh(ctx, pair.RefreshToken); err == nil {
+				mu.Lock()
+				succeeded++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	xtesting.Equal(t, succeeded, 1)
+}
+
+func TestMemoryRefreshTokenStore(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	store := NewMemoryRefreshTokenStore()
+	store.now = func() time.Time { return now }
+
+	xtesting.Equal(t, store.Rotate(ctx, "f", "t1", "t2", now.Add(time.Hour)), ErrRefreshTokenRevoked)
+	xtesting.Nil(t, store.Save(ctx, "f", "t1", now.Add(time.Hour)))
+	xtesting.Nil(t, store.Rotate(ctx, "f", "t1", "t2", now.Add(time.Hour)))
+	xtesting.Nil(t, store.Rotate(ctx, "f", "t2", "t3", now.Add(time.Hour)))
+	xtesting.Equal(t, store.Rotate(ctx, "f", "t2", "t4", now.Add(time.Hour)), ErrRefreshTokenReused)
+	xtesting.Equal(t, store.Rotate(ctx, "f", "t3", "t4", now.Add(time.Hour)), ErrRefreshTokenRevoked)
+
+	xtesting.Nil(t, store.Save(ctx, "f", "t1", now.Add(time.Hour)))
+	xtesting.Nil(t, store.RevokeFamily(ctx, "f"))
+	xtesting.Nil(t, store.RevokeFamily(ctx, "f"))
+	xtesting.Equal(t, store.Len(), 0)
+
+	xtesting.Nil(t, store.Save(ctx, "f", "t1", now.Add(time.Hour)))
+	now = now.Add(time.Hour)
+	xtesting.Equal(t, store.Rotate(ctx, "f", "t1", "t2", now.Add(time.Hour)), ErrRefreshTokenRevoked)
+}
@@ -0,0 +1,103 @@
+//go:build go1.18
+// +build go1.18
+
+package xjwt
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// TypedClaims represents a typed jwt.Claims which combines jwt.RegisteredClaims and custom payload, the payload's fields are flattened
+// into the claims set when marshalling, so the payload must be marshalled to a JSON object, such as struct or map. Note that registered
+// claims will take precedence over payload's fields with the same names.
+// Example:
+// 	type User struct {
+// 		UID  uint64 `json:"uid"`
+// 		Role string `json:"role"`
+// 	}
+// 	claims := &TypedClaims[User]{RegisteredClaims: jwt.RegisteredClaims{Subject: "1"}, Payload: User{UID: 1, Role: "admin"}}
+// 	token, _ := GenerateTokenWithHS256(claims, secret) // {"jti":"...","role":"admin","sub":"1","uid":1}
+// 	parsed, _ := ParseTypedClaims[User](token, secret)
+// 	fmt.Println(parsed.Payload.Role) // admin
+type TypedClaims[T any] struct {
+	jwt.RegisteredClaims
+
+	// Payload represents the custom payload.
+	Payload T
+}
+
+var (
+	_ jwt.Claims       = (*TypedClaims[struct{}])(nil)
+	_ json.Marshaler   = TypedClaims[struct{}]{}
+	_ json.Unmarshaler = (*TypedClaims[struct{}])(nil)
+
+	errNonObjectPayload = errors.New("xjwt: payload must be marshalled to a JSON object")
+)
+
+// NewTypedClaims creates a TypedClaims with given payload and registered claims.
+func NewTypedClaims[T any](payload T, registered jwt.RegisteredClaims) *TypedClaims[T] {
+	return &TypedClaims[T]{RegisteredClaims: registered, Payload: payload}
+}
+
+// MarshalJSON marshals the registered claims and payload's fields into a single JSON object, this implements json.Marshaler. Note that
+// the value receiver is used, so both TypedClaims value and pointer are marshalled in the same way.
+func (t TypedClaims[T]) MarshalJSON() ([]byte, error) {
+	payload, err := json.Marshal(t.Payload)
+	if err != nil {
+		return nil, err
+	}
+	m := make(map[string]json.RawMessage)
+	if string(payload) != "null" {
+		if err = json.Unmarshal(payload, &m); err != nil {
+			return nil, errNonObjectPayload
+		}
+	}
+	registered, err := json.Marshal(t.RegisteredClaims)
+	if err != nil {
+		return nil, err
+	}
+	registeredMap := make(map[string]json.RawMessage)
+	if err = json.Unmarshal(registered, &registeredMap); err != nil {
+		return nil, err
+	}
+	for k, v := range registeredMap {
+		m[k] = v
+	}
+	return json.Marshal(m)
+}
+
+// UnmarshalJSON unmarshals the given JSON object to both registered claims and payload, this implements json.Unmarshaler.
+func (t *TypedClaims[T]) UnmarshalJSON(data []byte) error {
+	if err := json.Unmarshal(data, &t.RegisteredClaims); err != nil {
+		return err
+	}
+	return json.Unmarshal(data, &t.Payload)
+}
+
+// ParseTypedClaims parses jwt token string using given secret, and returns the TypedClaims with payload type T. Note that only HS
+// series algorithms are allowed by default, see ParseToken.
+func ParseTypedClaims[T any](signedToken string, secret []byte, options ...ParseOption) (*TypedClaims[T], error) {
+	return ParseTypedClaimsWithKey[T](signedToken, secret, options...)
+}
+
+// ParseTypedClaimsWithKey parses jwt token string using given verification key, and returns the TypedClaims with payload type T. See
+// ParseTokenWithKey for details of key.
+func ParseTypedClaimsWithKey[T any](signedToken string, key interface{}, options ...ParseOption) (*TypedClaims[T], error) {
+	claims := &TypedClaims[T]{}
+	if _, err := ParseTokenWithKey(signedToken, key, claims, options...); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+// ParseTypedClaimsWithKeyfunc parses jwt token string using given jwt.Keyfunc, and returns the TypedClaims with payload type T. See
+// ParseTokenWithKeyfunc for details of keyFunc.
+func ParseTypedClaimsWithKeyfunc[T any](signedToken string, keyFunc jwt.Keyfunc, options ...ParseOption) (*TypedClaims[T], error) {
+	claims := &TypedClaims[T]{}
+	if _, err := ParseTokenWithKeyfunc(signedToken, keyFunc, claims, options...); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
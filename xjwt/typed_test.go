@@ -0,0 +1,75 @@
+//go:build go1.18
+// +build go1.18
+
+package xjwt
+
+import (
+	"encoding/json"
+	"github.com/Aoi-hosizora/ahlib/xtesting"
+	"github.com/golang-jwt/jwt/v4"
+	"testing"
+	"time"
+)
+
+type testUser struct {
+	UID     uint64   `json:"uid"`
+	Role    string   `json:"role"`
+	Tags    []string `json:"tags,omitempty"`
+	Subject string   `json:"sub"` // shadowed by registered claims
+}
+
+func TestTypedClaims(t *testing.T) {
+	secret := []byte("secret")
+	claims := NewTypedClaims(testUser{UID: 1, Role: "admin", Tags: []string{"a"}, Subject: "shadowed"}, jwt.RegisteredClaims{Subject: "1", ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour))})
+	bs, err := json.Marshal(claims)
+	xtesting.Nil(t, err)
+	m := map[string]interface{}{}
+	xtesting.Nil(t, json.Unmarshal(bs, &m))
+	xtesting.Equal(t, m["uid"], float64(1))
+	xtesting.Equal(t, m["role"], "admin")
+	xtesting.Equal(t, m["sub"], "1")
+	valueBs, err := json.Marshal(*claims) // value is flattened in the same way
+	xtesting.Nil(t, err)
+	xtesting.Equal(t, valueBs, bs)
+	nested, err := json.Marshal(struct{ Claims TypedClaims[testUser] }{*claims})
+	xtesting.Nil(t, err)
+	xtesting.Equal(t, string(nested), `{"Claims":`+string(bs)+`}`)
+
+	// generate and parse
+	token, err := GenerateTokenWithHS256(claims, secret)
+	xtesting.Nil(t, err)
+	parsed, err := ParseTypedClaims[testUser](token, secret, WithSubject("1"))
+	xtesting.Nil(t, err)
+	xtesting.Equal(t, parsed.Payload.UID, uint64(1))
+	xtesting.Equal(t, parsed.Payload.Role, "admin")
+	xtesting.Equal(t, parsed.Payload.Tags, []string{"a"})
+	xtesting.Equal(t, parsed.Subject, "1")
+	xtesting.Equal(t, len(parsed.ID), 32)
+	_, err = ParseTypedClaims[testUser](token, []byte("other"))
+	xtesting.True(t, IsTokenInvalidError(err))
+
+	// pointer and map payload
+	pt, err := ParseTypedClaimsWithKeyfunc[*testUser](token, func(*jwt.Token) (interface{}, error) { return secret, nil })
+	xtesting.Nil(t, err)
+	xtesting.Equal(t, pt.Payload.Role, "admin")
+	mt, err := ParseTypedClaimsWithKey[map[string]interface{}](token, secret)
+	xtesting.Nil(t, err)
+	xtesting.Equal(t, mt.Payload["role"], "admin")
+	token, err = GenerateTokenWithHS256(&TypedClaims[*testUser]{RegisteredClaims: jwt.RegisteredClaims{Subject: "2"}}, secret)
+	xtesting.Nil(t, err)
+	pt, err = ParseTypedClaims[*testUser](token, secret)
+	xtesting.Nil(t, err)
+	xtesting.Equal(t, pt.Subject, "2")
+	xtesting.Equal(t, pt.Payload.UID, uint64(0))
+
+	// expired and invalid
+	expired := NewTypedClaims(testUser{}, jwt.RegisteredClaims{ExpiresAt: jwt.NewNumericDate(time.Now().Add(-time.Hour))})
+	token, err = GenerateTokenWithHS256(expired, secret)
+	xtesting.Nil(t, err)
+	_, err = ParseTypedClaims[testUser](token, secret)
+	xtesting.True(t, IsExpiredError(err))
+	_, err = GenerateTokenWithHS256(NewTypedClaims(1, jwt.RegisteredClaims{}), secret)
+	xtesting.NotNil(t, err)
+	_, err = ParseTypedClaims[int](token, secret)
+	xtesting.NotNil(t, err)
+}
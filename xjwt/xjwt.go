@@ -1,13 +1,19 @@
 package xjwt
 
 import (
+	"crypto"
+	"errors"
+	"fmt"
 	"github.com/golang-jwt/jwt/v4"
+	"strings"
+	"time"
 )
 
-// GenerateToken generates token using given jwt.Claims, secret and jwt.SigningMethod.
-func GenerateToken(method jwt.SigningMethod, claims jwt.Claims, key interface{}) (string, error) {
-	tokenObj := jwt.NewWithClaims(method, claims)
-	token, err := tokenObj.SignedString(key)
+// GenerateToken generates token using given jwt.Claims, secret and jwt.SigningMethod. Note that the key can also be crypto.Signer
+// for RS, PS, ES and EdDSA series signing methods.
+func GenerateToken(method jwt.SigningMethod, claims jwt.Claims, key interface{}, options ...GenerateOption) (string, error) {
+	tokenObj := newTokenWithOptions(method, claims, newGenerateOptions(options))
+	token, err := signToken(tokenObj, key)
 	if err != nil {
 		return "", err
 	}
@@ -29,35 +35,101 @@ func GenerateTokenWithHS512(claims jwt.Claims, secret []byte) (string, error) {
 	return GenerateToken(jwt.SigningMethodHS512, claims, secret)
 }
 
-// ParseToken parses jwt token string using given custom jwt.Claims and returns jwt.Token.
-func ParseToken(signedToken string, secret []byte, claims jwt.Claims) (*jwt.Token, error) {
+// GenerateTokenWithRS256 generates token using given jwt.Claims, crypto.Signer and RS256 (RSASSA-PKCS1-v1_5 SHA256) signing method.
+func GenerateTokenWithRS256(claims jwt.Claims, key crypto.Signer) (string, error) {
+	return GenerateTokenWithSigner(jwt.SigningMethodRS256, claims, key)
+}
+
+// GenerateTokenWithRS384 generates token using given jwt.Claims, crypto.Signer and RS384 (RSASSA-PKCS1-v1_5 SHA384) signing method.
+func GenerateTokenWithRS384(claims jwt.Claims, key crypto.Signer) (string, error) {
+	return GenerateTokenWithSigner(jwt.SigningMethodRS384, claims, key)
+}
+
+// GenerateTokenWithRS512 generates token using given jwt.Claims, crypto.Signer and RS512 (RSASSA-PKCS1-v1_5 SHA512) signing method.
+func GenerateTokenWithRS512(claims jwt.Claims, key crypto.Signer) (string, error) {
+	return GenerateTokenWithSigner(jwt.SigningMethodRS512, claims, key)
+}
+
+// GenerateTokenWithPS256 generates token using given jwt.Claims, crypto.Signer and PS256 (RSASSA-PSS SHA256) signing method.
+func GenerateTokenWithPS256(claims jwt.Claims, key crypto.Signer) (string, error) {
+	return GenerateTokenWithSigner(jwt.SigningMethodPS256, claims, key)
+}
+
+// GenerateTokenWithPS384 generates token using given jwt.Claims, crypto.Signer and PS384 (RSASSA-PSS SHA384) signing method.
+func GenerateTokenWithPS384(claims jwt.Claims, key crypto.Signer) (string, error) {
+	return GenerateTokenWithSigner(jwt.SigningMethodPS384, claims, key)
+}
+
+// GenerateTokenWithPS512 generates token using given jwt.Claims, crypto.Signer and PS512 (RSASSA-PSS SHA512) signing method.
+func GenerateTokenWithPS512(claims jwt.Claims, key crypto.Signer) (string, error) {
+	return GenerateTokenWithSigner(jwt.SigningMethodPS512, claims, key)
+}
+
+// GenerateTokenWithES256 generates token using given jwt.Claims, crypto.Signer and ES256 (ECDSA P-256 SHA256) signing method.
+func GenerateTokenWithES256(claims jwt.Claims, key crypto.Signer) (string, error) {
+	return GenerateTokenWithSigner(jwt.SigningMethodES256, claims, key)
+}
+
+// GenerateTokenWithES384 generates token using given jwt.Claims, crypto.Signer and ES384 (ECDSA P-384 SHA384) signing method.
+func GenerateTokenWithES384(claims jwt.Claims, key crypto.Signer) (string, error) {
+	return GenerateTokenWithSigner(jwt.SigningMethodES384, claims, key)
+}
+
+// GenerateTokenWithES512 generates token using given jwt.Claims, crypto.Signer and ES512 (ECDSA P-521 SHA512) signing method.
+func GenerateTokenWithES512(claims jwt.Claims, key crypto.Signer) (string, error) {
+	return GenerateTokenWithSigner(jwt.SigningMethodES512, claims, key)
+}
+
+// GenerateTokenWithEdDSA generates token using given jwt.Claims, crypto.Signer and EdDSA (Ed25519) signing method.
+func GenerateTokenWithEdDSA(claims jwt.Claims, key crypto.Signer) (string, error) {
+	return GenerateTokenWithSigner(jwt.SigningMethodEdDSA, claims, key)
+}
+
+// ParseToken parses jwt token string using given custom jwt.Claims and returns jwt.Token. Note that only HS series algorithms are allowed
+// by default, see WithAllowedAlgorithms, and validation failures are returned as *ValidationError (not *jwt.ValidationError, use errors.As
+// to convert it).
+func ParseToken(signedToken string, secret []byte, claims jwt.Claims, options ...ParseOption) (*jwt.Token, error) {
+	return ParseTokenWithKey(signedToken, secret, claims, options...)
+}
+
+// ParseTokenWithKey parses jwt token string using given verification key and custom jwt.Claims, and returns jwt.Token. The key can be
+// []byte secret for HS series, *rsa.PublicKey for RS and PS series, *ecdsa.PublicKey for ES series and ed25519.PublicKey for EdDSA,
+// and private keys (or crypto.Signer) will be converted to their public keys. Note that only the algorithms matched the key type are
+// allowed by default, see WithAllowedAlgorithms.
+func ParseTokenWithKey(signedToken string, key interface{}, claims jwt.Claims, options ...ParseOption) (*jwt.Token, error) {
 	keyFunc := func(token *jwt.Token) (interface{}, error) {
-		return secret, nil
+		return key, nil
 	}
-	tokenObj, err := jwt.ParseWithClaims(signedToken, claims, keyFunc)
-	if err != nil {
-		return nil, err
+	return parseTokenWithKeyfunc(signedToken, keyFunc, claims, newParseOptions(options))
+}
+
+// ParseTokenWithKeyfunc parses jwt token string using given jwt.Keyfunc and custom jwt.Claims, and returns jwt.Token. The keyFunc is used
+// to resolve verification key by token, such as KeySet.Keyfunc and JWKSFetcher.Keyfunc. Note that the algorithm checking is the same
+// as ParseTokenWithKey.
+func ParseTokenWithKeyfunc(signedToken string, keyFunc jwt.Keyfunc, claims jwt.Claims, options ...ParseOption) (*jwt.Token, error) {
+	if keyFunc == nil {
+		return nil, newValidationError(jwt.NewValidationError("no keyfunc was provided", jwt.ValidationErrorUnverifiable), nil, nil, time.Time{})
 	}
-	return tokenObj, nil
+	return parseTokenWithKeyfunc(signedToken, keyFunc, claims, newParseOptions(options))
 }
 
 // ParseTokenClaims parses jwt token string using given custom jwt.Claims and returns jwt.Claims.
-func ParseTokenClaims(signedToken string, secret []byte, claims jwt.Claims) (jwt.Claims, error) {
-	tokenObj, err := ParseToken(signedToken, secret, claims)
+func ParseTokenClaims(signedToken string, secret []byte, claims jwt.Claims, options ...ParseOption) (jwt.Claims, error) {
+	tokenObj, err := ParseToken(signedToken, secret, claims, options...)
 	if err != nil {
 		return nil, err
 	}
 	return tokenObj.Claims, nil
 }
 
-// CheckValidationError returns true if given error is jwt.ValidationError with given flag.
+// CheckValidationError returns true if given error is ValidationError or jwt.ValidationError with given flag.
 func CheckValidationError(err error, flag uint32) bool {
 	if err == nil {
 		return false
 	}
 
-	ve, ok := err.(*jwt.ValidationError)
-	return ok && ve.Errors&flag != 0
+	var ve *jwt.ValidationError
+	return errors.As(err, &ve) && ve.Errors&flag != 0
 }
 
 // IsAudienceError checks error is an AUD (Audience) validation error.
@@ -90,17 +162,52 @@ func IsNotValidYetError(err error) bool {
 	return CheckValidationError(err, jwt.ValidationErrorNotValidYet)
 }
 
-// // IsSubjectError checks error is a SUB (Subject) validation error.
-// func IsSubjectError(err error) bool {
-// 	return CheckValidationError(err, jwt.ValidationErrorSubject) // not found
-// }
+// IsSubjectError checks error is a SUB (Subject) validation error, which is caused by WithSubject.
+func IsSubjectError(err error) bool {
+	return CheckValidationError(err, ValidationErrorSubject)
+}
 
 // IsTokenInvalidError checks error is an invalid token (could not be parsed) error.
 func IsTokenInvalidError(err error) bool {
 	return CheckValidationError(err, jwt.ValidationErrorMalformed|jwt.ValidationErrorUnverifiable|jwt.ValidationErrorSignatureInvalid)
 }
 
+// IsAlgorithmError checks error is an unexpected signing algorithm error, which is caused by WithAllowedAlgorithms or the mismatch
+// between algorithm and key type. Note that this kind of error is also an invalid token error, see IsTokenInvalidError.
+func IsAlgorithmError(err error) bool {
+	if err == nil {
+		return false
+	}
+	if ve, ok := err.(*jwt.ValidationError); ok {
+		err = ve.Inner
+	}
+	var ae *AlgorithmError
+	return errors.As(err, &ae)
+}
+
 // IsClaimsInvalidError checks error is a generic claims validation error.
 func IsClaimsInvalidError(err error) bool {
 	return CheckValidationError(err, jwt.ValidationErrorClaimsInvalid)
 }
+
+// AlgorithmError represents an error of unexpected signing algorithm.
+type AlgorithmError struct {
+	Algorithm string
+	Allowed   []string
+}
+
+// Error returns the formatted error message.
+func (a *AlgorithmError) Error() string {
+	if len(a.Allowed) == 0 {
+		return fmt.Sprintf("signing method %s is invalid", a.Algorithm)
+	}
+	return fmt.Sprintf("signing method %s is invalid, allowed: %s", a.Algorithm, strings.Join(a.Allowed, ", "))
+}
+
+// newAlgorithmValidationError creates a jwt.ValidationError with AlgorithmError as inner error.
+func newAlgorithmValidationError(algorithm string, allowed []string) *jwt.ValidationError {
+	return &jwt.ValidationError{
+		Inner:  &AlgorithmError{Algorithm: algorithm, Allowed: allowed},
+		Errors: jwt.ValidationErrorUnverifiable | jwt.ValidationErrorSignatureInvalid,
+	}
+}
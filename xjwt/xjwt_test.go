@@ -1,6 +1,8 @@
 package xjwt
 
 import (
+	"crypto/x509"
+	"encoding/pem"
 	"errors"
 	"github.com/Aoi-hosizora/ahlib/xtesting"
 	"github.com/golang-jwt/jwt/v4"
@@ -174,3 +176,97 @@ func TestValidationError(t *testing.T) {
 		xtesting.Equal(t, tc.giveFn(tc.giveErr), tc.want)
 	}
 }
+
+func TestAllowedAlgorithms(t *testing.T) {
+	testKeys()
+	secret := []byte("secret")
+	claims := &jwt.StandardClaims{Subject: "test"}
+	hs256, _ := GenerateTokenWithHS256(claims, secret)
+	hs384, _ := GenerateTokenWithHS384(claims, secret)
+	rs256, _ := GenerateTokenWithRS256(claims, testRSAKey)
+	es256, _ := GenerateTokenWithES256(claims, testEC256Key)
+	none, _ := jwt.NewWithClaims(jwt.SigningMethodNone, claims).SignedString(jwt.UnsafeAllowNoneSignatureType)
+	rsaPubDer, _ := x509.MarshalPKIXPublicKey(&testRSAKey.PublicKey)
+	rsaPubPEM := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: rsaPubDer})
+	confused, _ := GenerateTokenWithHS256(claims, rsaPubPEM) // alg confusion: HS256 signed with public key
+
+	for _, tc := range []struct {
+		giveToken   string
+		giveKey     interface{}
+		giveOptions []ParseOption
+		wantAlgErr  bool
+		wantErr     bool
+	}{
+		{hs256, secret, nil, false, false},
+		{hs384, secret, nil, false, false},
+		{hs256, secret, []ParseOption{nil}, false, false},
+		{hs256, secret, []ParseOption{WithAllowedAlgorithms("HS256")}, false, false},
+		{hs384, secret, []ParseOption{WithAllowedAlgorithms("HS256")}, true, true},
+		{hs384, secret, []ParseOption{WithAllowedAlgorithms("HS256", "HS384")}, false, false},
+		{rs256, secret, nil, true, true},
+		{rs256, &testRSAKey.PublicKey, nil, false, false},
+		{rs256, &testRSAKey.PublicKey, []ParseOption{WithAllowedAlgorithms("PS256")}, true, true},
+		{hs256, &testRSAKey.PublicKey, nil, true, true},
+		{hs256, testRSAKey, nil, true, true},
+		{confused, &testRSAKey.PublicKey, nil, true, true},
+		{confused, rsaPubPEM, []ParseOption{WithAllowedAlgorithms("RS256")}, true, true},
+		{es256, &testEC256Key.PublicKey, nil, false, false},
+		{es256, &testEC384Key.PublicKey, nil, true, true},
+		{es256, testEdKey.Public(), nil, true, true},
+		{es256, &testEC384Key.PublicKey, []ParseOption{WithAllowedAlgorithms("ES256")}, false, true}, // verify failed
+		{none, secret, nil, true, true},
+		{none, jwt.UnsafeAllowNoneSignatureType, []ParseOption{WithAllowedAlgorithms("none")}, true, true},
+	} {
+		_, err := ParseTokenWithKey(tc.giveToken, tc.giveKey, &jwt.StandardClaims{}, tc.giveOptions...)
+		if !tc.wantErr {
+			xtesting.Nil(t, err)
+			continue
+		}
+		xtesting.NotNil(t, err)
+		xtesting.True(t, IsTokenInvalidError(err))
+		xtesting.Equal(t, IsAlgorithmError(err), tc.wantAlgErr)
+	}
+
+	_, err := ParseToken(rs256, secret, &jwt.StandardClaims{})
+	xtesting.True(t, IsAlgorithmError(err))
+	_, err = ParseToken(rs256, secret, &jwt.StandardClaims{}, WithAllowedAlgorithms("RS256"))
+	xtesting.False(t, IsAlgorithmError(err))
+	xtesting.True(t, IsTokenInvalidError(err))
+	_, err = ParseTokenClaims(hs384, secret, &jwt.StandardClaims{}, WithAllowedAlgorithms("HS512"))
+	xtesting.True(t, IsAlgorithmError(err))
+	xtesting.Equal(t, err.Error(), "signing method HS384 is invalid, allowed: HS512")
+	_, err = ParseToken(none, secret, &jwt.StandardClaims{})
+	xtesting.Equal(t, err.Error(), "signing method none is invalid")
+}
+
+func TestIsAlgorithmError(t *testing.T) {
+	for _, tc := range []struct {
+		giveErr error
+		want    bool
+	}{
+		{nil, false},
+		{errors.New(""), false},
+		{jwt.NewValidationError("", jwt.ValidationErrorSignatureInvalid), false},
+		{&jwt.ValidationError{Inner: errors.New(""), Errors: jwt.ValidationErrorSignatureInvalid}, false},
+		{&AlgorithmError{Algorithm: "HS256"}, true},
+		{&jwt.ValidationError{Inner: &AlgorithmError{Algorithm: "HS256"}, Errors: jwt.ValidationErrorSignatureInvalid}, true},
+	} {
+		xtesting.Equal(t, IsAlgorithmError(tc.giveErr), tc.want)
+	}
+}
+
+func TestGenerateOption(t *testing.T) {
+	testKeys()
+	secret := []byte("secret")
+	token, err := GenerateToken(jwt.SigningMethodHS256, &jwt.StandardClaims{}, secret, nil, WithKeyID("kid"), WithHeader("alg", "none"), WithHeader("typ", "at+jwt"))
+	xtesting.Nil(t, err)
+	parsed, err := ParseToken(token, secret, &jwt.StandardClaims{})
+	xtesting.Nil(t, err)
+	xtesting.Equal(t, parsed.Header, map[string]interface{}{"alg": "HS256", "kid": "kid", "typ": "at+jwt"})
+
+	token, err = GenerateToken(jwt.SigningMethodES256, &jwt.StandardClaims{}, opaqueSigner{testEC256Key}, WithKeyID("kid"))
+	xtesting.Nil(t, err)
+	parsed, err = ParseTokenWithKey(token, testEC256Key.Public(), &jwt.StandardClaims{})
+	xtesting.Nil(t, err)
+	xtesting.Equal(t, parsed.Header["kid"], "kid")
+}
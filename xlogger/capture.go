@@ -0,0 +1,289 @@
+package xlogger
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// CapturedEntry represents a log entry recorded by CaptureLogger.
+type CapturedEntry struct {
+	Level   Level
+	Message string
+	Fields  map[string]interface{}
+}
+
+// captureStore is the storage of CaptureLogger, which is shared between the loggers created by WithField and WithFields.
+type captureStore struct {
+	mu      sync.RWMutex
+	entries []*CapturedEntry
+	buf     []byte
+}
+
+// CaptureLogger is a StdLogger and LeveledLogger which records all entries in memory, it is designed to be used in tests. Note that
+// Print series methods are recorded in InfoLevel, Panic and Fatal series methods are recorded in ErrorLevel and will panic after
+// recording, and lines written by Write method are parsed as "[LEVEL] message". Note that StdLeveledLogger which outputs to CaptureLogger
+// records its fields directly rather than writing the formatted line, so that the fields are captured as they are.
+// Example:
+// 	l := NewCaptureLogger()
+// 	lib.DoSomething(l)
+// 	l.AssertContainsMessage(t, "done")
+// 	l.AssertCount(t, ErrorLevel, 0)
+type CaptureLogger struct {
+	// store is the shared entries storage.
+	store *captureStore
+
+	// fields stores the fields added by WithField and WithFields.
+	fields map[string]interface{}
+}
+
+var (
+	_ StdLogger     = (*CaptureLogger)(nil)
+	_ LeveledLogger = (*CaptureLogger)(nil)
+)
+
+// NewCaptureLogger creates an empty CaptureLogger.
+func NewCaptureLogger() *CaptureLogger {
+	return &CaptureLogger{store: &captureStore{}}
+}
+
+// record appends a new entry to the store, the extra fields (can be nil) are added to the logger's fields.
+func (c *CaptureLogger) record(level Level, message string, extra map[string]interface{}) {
+	fields := make(map[string]interface{}, len(c.fields)+len(extra))
+	for k, v := range c.fields {
+		fields[k] = v
+	}
+	for k, v := range extra {
+		fields[k] = v
+	}
+	c.store.mu.Lock()
+	c.store.entries = append(c.store.entries, &CapturedEntry{Level: level, Message: message, Fields: fields})
+	c.store.mu.Unlock()
+}
+
+func (c *CaptureLogger) Print(v ...interface{})                 { c.record(InfoLevel, fmt.Sprint(v...), nil) }
+func (c *CaptureLogger) Printf(format string, v ...interface{}) { c.record(InfoLevel, fmt.Sprintf(format, v...), nil) }
+func (c *CaptureLogger) Println(v ...interface{})               { c.record(InfoLevel, sprintln(v...), nil) }
+
+func (c *CaptureLogger) Panic(v ...interface{}) {
+	s := fmt.Sprint(v...)
+	c.record(ErrorLevel, s, nil)
+	panic(s)
+}
+
+func (c *CaptureLogger) Panicf(format string, v ...interface{}) {
+	s := fmt.Sprintf(format, v...)
+	c.record(ErrorLevel, s, nil)
+	panic(s)
+}
+
+func (c *CaptureLogger) Panicln(v ...interface{}) {
+	s := fmt.Sprintln(v...)
+	c.record(ErrorLevel, s[:len(s)-1], nil)
+	panic(s)
+}
+
+// Fatal records the message in ErrorLevel and then panics rather than exiting, so tests can recover from it.
+func (c *CaptureLogger) Fatal(v ...interface{}) {
+	c.Panic(v...)
+}
+
+// Fatalf records the message in ErrorLevel and then panics rather than exiting, so tests can recover from it.
+func (c *CaptureLogger) Fatalf(format string, v ...interface{}) {
+	c.Panicf(format, v...)
+}
+
+// Fatalln records the message in ErrorLevel and then panics rather than exiting, so tests can recover from it.
+func (c *CaptureLogger) Fatalln(v ...interface{}) {
+	c.Panicln(v...)
+}
+
+func (c *CaptureLogger) Debugf(format string, v ...interface{}) { c.record(DebugLevel, fmt.Sprintf(format, v...), nil) }
+func (c *CaptureLogger) Infof(format string, v ...interface{})  { c.record(InfoLevel, fmt.Sprintf(format, v...), nil) }
+func (c *CaptureLogger) Warnf(format string, v ...interface{})  { c.record(WarnLevel, fmt.Sprintf(format, v...), nil) }
+func (c *CaptureLogger) Errorf(format string, v ...interface{}) { c.record(ErrorLevel, fmt.Sprintf(format, v...), nil) }
+
+// WithField returns a new CaptureLogger with given field added, which shares the recorded entries with the current logger.
+func (c *CaptureLogger) WithField(key string, value interface{}) LeveledLogger {
+	return c.WithFields(map[string]interface{}{key: value})
+}
+
+// WithFields returns a new CaptureLogger with given fields added, which shares the recorded entries with the current logger.
+func (c *CaptureLogger) WithFields(fields map[string]interface{}) LeveledLogger {
+	newFields := make(map[string]interface{}, len(c.fields)+len(fields))
+	for k, v := range c.fields {
+		newFields[k] = v
+	}
+	for k, v := range fields {
+		newFields[k] = v
+	}
+	return &CaptureLogger{store: c.store, fields: newFields}
+}
+
+// Write parses given bytes into lines and records each complete line, the level is detected from "[LEVEL]" prefix (including "[WARNING]")
+// and defaults to InfoLevel, this implements io.Writer. Note that the message is recorded as it is, and no field will be parsed from it.
+func (c *CaptureLogger) Write(p []byte) (int, error) {
+	c.store.mu.Lock()
+	c.store.buf = append(c.store.buf, p...)
+	var lines []string
+	for {
+		idx := bytes.IndexByte(c.store.buf, '\n')
+		if idx == -1 {
+			break
+		}
+		lines = append(lines, strings.TrimRight(string(c.store.buf[:idx]), "\r"))
+		c.store.buf = c.store.buf[idx+1:]
+	}
+	c.store.mu.Unlock()
+
+	for _, line := range lines {
+		level, message := parseLevelPrefix(line)
+		c.record(level, message, nil)
+	}
+	return len(p), nil
+}
+
+// Entries returns a copy of all the recorded entries.
+func (c *CaptureLogger) Entries() []CapturedEntry {
+	c.store.mu.RLock()
+	defer c.store.mu.RUnlock()
+	out := make([]CapturedEntry, 0, len(c.store.entries))
+	for _, e := range c.store.entries {
+		out = append(out, *e)
+	}
+	return out
+}
+
+// Messages returns the messages of all the recorded entries.
+func (c *CaptureLogger) Messages() []string {
+	entries := c.Entries()
+	out := make([]string, 0, len(entries))
+	for _, e := range entries {
+		out = append(out, e.Message)
+	}
+	return out
+}
+
+// Reset removes all the recorded entries.
+func (c *CaptureLogger) Reset() {
+	c.store.mu.Lock()
+	c.store.entries = nil
+	c.store.buf = nil
+	c.store.mu.Unlock()
+}
+
+// ContainsMessage checks whether there is a recorded entry whose message contains given substring.
+func (c *CaptureLogger) ContainsMessage(substr string) bool {
+	for _, e := range c.Entries() {
+		if strings.Contains(e.Message, substr) {
+			return true
+		}
+	}
+	return false
+}
+
+// Count returns the count of recorded entries in given level.
+func (c *CaptureLogger) Count(level Level) int {
+	cnt := 0
+	for _, e := range c.Entries() {
+		if e.Level == level {
+			cnt++
+		}
+	}
+	return cnt
+}
+
+// ContainsFields checks whether there is a recorded entry whose fields contain all given fields, values are compared by reflect.DeepEqual.
+func (c *CaptureLogger) ContainsFields(fields map[string]interface{}) bool {
+	for _, e := range c.Entries() {
+		matched := true
+		for k, v := range fields {
+			if ev, ok := e.Fields[k]; !ok || !reflect.DeepEqual(ev, v) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return true
+		}
+	}
+	return false
+}
+
+// TestingT describes the methods of testing.TB used by the assertion helpers of CaptureLogger.
+type TestingT interface {
+	Helper()
+	Errorf(format string, args ...interface{})
+}
+
+// AssertContainsMessage asserts that there is a recorded entry whose message contains given substring.
+func (c *CaptureLogger) AssertContainsMessage(t TestingT, substr string) bool {
+	t.Helper()
+	if !c.ContainsMessage(substr) {
+		t.Errorf("xlogger: no captured entry contains message %q, captured messages: %q", substr, c.Messages())
+		return false
+	}
+	return true
+}
+
+// AssertCount asserts that the count of recorded entries in given level equals to given count.
+func (c *CaptureLogger) AssertCount(t TestingT, level Level, count int) bool {
+	t.Helper()
+	if actual := c.Count(level); actual != count {
+		t.Errorf("xlogger: expected %d captured entries in %s level, actual %d", count, level.String(), actual)
+		return false
+	}
+	return true
+}
+
+// AssertContainsFields asserts that there is a recorded entry whose fields contain all given fields.
+func (c *CaptureLogger) AssertContainsFields(t TestingT, fields map[string]interface{}) bool {
+	t.Helper()
+	if !c.ContainsFields(fields) {
+		t.Errorf("xlogger: no captured entry contains fields %v", fields)
+		return false
+	}
+	return true
+}
+
+// CleanupT describes the Cleanup method of testing.TB, which is available in go1.14 and later.
+type CleanupT interface {
+	Cleanup(func())
+}
+
+// CaptureStd redirects the output of the standard logger to a new CaptureLogger by OverrideStd, and the output, flags and prefix
+// of the standard logger will be restored on test cleanup. Note that the standard logger is shared by the whole process, so tests
+// using this function should not run in parallel.
+func CaptureStd(t CleanupT) *CaptureLogger {
+	c := NewCaptureLogger()
+	restore := OverrideStd(c, 0, "")
+	t.Cleanup(restore)
+	return c
+}
+
+// parseLevelPrefix parses the "[LEVEL]" prefix of given line, and returns the level and message without prefix. Note that "[WARNING]" is
+// also parsed as WarnLevel.
+func parseLevelPrefix(line string) (Level, string) {
+	if strings.HasPrefix(line, "[") {
+		if end := strings.IndexByte(line, ']'); end != -1 {
+			name := line[1:end]
+			if strings.EqualFold(name, "WARNING") {
+				name = WarnLevel.String()
+			}
+			for _, level := range []Level{DebugLevel, InfoLevel, WarnLevel, ErrorLevel} {
+				if strings.EqualFold(name, level.String()) {
+					return level, strings.TrimLeft(line[end+1:], " ")
+				}
+			}
+		}
+	}
+	return InfoLevel, line
+}
+
+// sprintln formats using fmt.Sprintln and trims the trailing newline.
+func sprintln(v ...interface{}) string {
+	s := fmt.Sprintln(v...)
+	return s[:len(s)-1]
+}
@@ -0,0 +1,162 @@
+package xlogger
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+)
+
+// LeveledLogger describes a leveled logger with fields, includes Debugf, Infof, Warnf, Errorf, WithField and WithFields methods.
+// Libraries can accept this interface instead of depending on a concrete logger package.
+type LeveledLogger interface {
+	Debugf(format string, v ...interface{})
+	Infof(format string, v ...interface{})
+	Warnf(format string, v ...interface{})
+	Errorf(format string, v ...interface{})
+
+	WithField(key string, value interface{}) LeveledLogger
+	WithFields(fields map[string]interface{}) LeveledLogger
+}
+
+// Level represents the log level used by LeveledLogger implementations in this package.
+type Level uint32
+
+const (
+	DebugLevel Level = iota // Debug level, used by LeveledLogger.Debugf.
+	InfoLevel               // Info level, used by LeveledLogger.Infof.
+	WarnLevel               // Warn level, used by LeveledLogger.Warnf.
+	ErrorLevel              // Error level, used by LeveledLogger.Errorf.
+)
+
+// String returns the upper-cased name of the level, such as "DEBUG" and "INFO".
+func (l Level) String() string {
+	switch l {
+	case DebugLevel:
+		return "DEBUG"
+	case InfoLevel:
+		return "INFO"
+	case WarnLevel:
+		return "WARN"
+	case ErrorLevel:
+		return "ERROR"
+	default:
+		return fmt.Sprintf("Level(%d)", uint32(l))
+	}
+}
+
+// ==================
+// NoopLeveledLogger
+// ==================
+
+// NoopLeveledLogger is a LeveledLogger which logs nothing.
+type NoopLeveledLogger struct{}
+
+var _ LeveledLogger = NoopLeveledLogger{}
+
+// NewNoopLeveledLogger creates a NoopLeveledLogger as LeveledLogger.
+func NewNoopLeveledLogger() LeveledLogger {
+	return NoopLeveledLogger{}
+}
+
+func (n NoopLeveledLogger) Debugf(string, ...interface{}) {}
+func (n NoopLeveledLogger) Infof(string, ...interface{})  {}
+func (n NoopLeveledLogger) Warnf(string, ...interface{})  {}
+func (n NoopLeveledLogger) Errorf(string, ...interface{}) {}
+
+func (n NoopLeveledLogger) WithField(string, interface{}) LeveledLogger       { return n }
+func (n NoopLeveledLogger) WithFields(map[string]interface{}) LeveledLogger { return n }
+
+// ==================
+// StdLeveledLogger
+// ==================
+
+// StdLeveledLogger is a LeveledLogger implemented on top of log.Logger, it logs messages in "[LEVEL] message key=value" format, that can
+// be parsed by xlogrus.ParseStdLogLevel. Note that fields are sorted by key when logging, and if the output of log.Logger is CaptureLogger,
+// the message and fields will be recorded directly without formatting.
+type StdLeveledLogger struct {
+	// logger is the log.Logger used to log.
+	logger *log.Logger
+
+	// level is the lowest level to be logged.
+	level Level
+
+	// fields stores the fields added by WithField and WithFields.
+	fields map[string]interface{}
+}
+
+var _ LeveledLogger = (*StdLeveledLogger)(nil)
+
+const (
+	panicNilLogger = "xlogger: nil logger"
+)
+
+// NewStdLeveledLogger creates a StdLeveledLogger as LeveledLogger using given log.Logger and the lowest level to be logged.
+func NewStdLeveledLogger(logger *log.Logger, level Level) *StdLeveledLogger {
+	if logger == nil {
+		panic(panicNilLogger)
+	}
+	return &StdLeveledLogger{logger: logger, level: level}
+}
+
+func (s *StdLeveledLogger) Debugf(format string, v ...interface{}) { s.logf(DebugLevel, format, v...) }
+func (s *StdLeveledLogger) Infof(format string, v ...interface{})  { s.logf(InfoLevel, format, v...) }
+func (s *StdLeveledLogger) Warnf(format string, v ...interface{})  { s.logf(WarnLevel, format, v...) }
+func (s *StdLeveledLogger) Errorf(format string, v ...interface{}) { s.logf(ErrorLevel, format, v...) }
+
+// WithField returns a new StdLeveledLogger with given field added.
+func (s *StdLeveledLogger) WithField(key string, value interface{}) LeveledLogger {
+	return s.WithFields(map[string]interface{}{key: value})
+}
+
+// WithFields returns a new StdLeveledLogger with given fields added.
+func (s *StdLeveledLogger) WithFields(fields map[string]interface{}) LeveledLogger {
+	newFields := make(map[string]interface{}, len(s.fields)+len(fields))
+	for k, v := range s.fields {
+		newFields[k] = v
+	}
+	for k, v := range fields {
+		newFields[k] = v
+	}
+	return &StdLeveledLogger{logger: s.logger, level: s.level, fields: newFields}
+}
+
+// logf formats and logs the message with fields if given level is enabled.
+func (s *StdLeveledLogger) logf(level Level, format string, v ...interface{}) {
+	if level < s.level {
+		return
+	}
+	if c, ok := s.logger.Writer().(*CaptureLogger); ok {
+		c.record(level, fmt.Sprintf(format, v...), s.fields) // keep fields structured, rather than parsing formatted text
+		return
+	}
+	sb := strings.Builder{}
+	sb.WriteString("[")
+	sb.WriteString(level.String())
+	sb.WriteString("] ")
+	sb.WriteString(fmt.Sprintf(format, v...))
+	sb.WriteString(FormatFields(s.fields))
+	_ = s.logger.Output(3, sb.String())
+}
+
+// FormatFields formats given fields to " key1=value1 key2=value2" string, the fields are sorted by key, and an empty string will
+// be returned if fields is empty.
+func FormatFields(fields map[string]interface{}) string {
+	if len(fields) == 0 {
+		return ""
+	}
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	sb := strings.Builder{}
+	for _, k := range keys {
+		sb.WriteString(" ")
+		sb.WriteString(k)
+		sb.WriteString("=")
+		sb.WriteString(fmt.Sprintf("%v", fields[k]))
+	}
+	return sb.String()
+}
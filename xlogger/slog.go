@@ -0,0 +1,85 @@
+//go:build go1.21
+// +build go1.21
+
+package xlogger
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"reflect"
+	"runtime"
+	"sort"
+	"time"
+)
+
+// SlogLeveledLogger is a LeveledLogger implemented on top of slog.Logger, fields are added as slog attributes. Note that this type
+// is only available in go1.21 and later.
+type SlogLeveledLogger struct {
+	// logger is the slog.Logger used to log.
+	logger *slog.Logger
+}
+
+var _ LeveledLogger = (*SlogLeveledLogger)(nil)
+
+// NewSlogLeveledLogger creates a SlogLeveledLogger as LeveledLogger using given slog.Logger.
+func NewSlogLeveledLogger(logger *slog.Logger) *SlogLeveledLogger {
+	if logger == nil || isNilHandler(logger.Handler()) {
+		panic(panicNilLogger)
+	}
+	return &SlogLeveledLogger{logger: logger}
+}
+
+// NewSlogLeveledLoggerWithHandler creates a SlogLeveledLogger as LeveledLogger using given slog.Handler.
+func NewSlogLeveledLoggerWithHandler(handler slog.Handler) *SlogLeveledLogger {
+	if isNilHandler(handler) {
+		panic(panicNilLogger)
+	}
+	return &SlogLeveledLogger{logger: slog.New(handler)}
+}
+
+// isNilHandler checks whether given slog.Handler is nil, including typed nil pointer such as (*slog.TextHandler)(nil).
+func isNilHandler(handler slog.Handler) bool {
+	if handler == nil {
+		return true
+	}
+	val := reflect.ValueOf(handler)
+	return val.Kind() == reflect.Ptr && val.IsNil()
+}
+
+func (s *SlogLeveledLogger) Debugf(format string, v ...interface{}) { s.logf(slog.LevelDebug, format, v...) }
+func (s *SlogLeveledLogger) Infof(format string, v ...interface{})  { s.logf(slog.LevelInfo, format, v...) }
+func (s *SlogLeveledLogger) Warnf(format string, v ...interface{})  { s.logf(slog.LevelWarn, format, v...) }
+func (s *SlogLeveledLogger) Errorf(format string, v ...interface{}) { s.logf(slog.LevelError, format, v...) }
+
+// WithField returns a new SlogLeveledLogger with given field added as attribute.
+func (s *SlogLeveledLogger) WithField(key string, value interface{}) LeveledLogger {
+	return &SlogLeveledLogger{logger: s.logger.With(key, value)}
+}
+
+// WithFields returns a new SlogLeveledLogger with given fields added as attributes, the attributes are sorted by key.
+func (s *SlogLeveledLogger) WithFields(fields map[string]interface{}) LeveledLogger {
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	args := make([]interface{}, 0, len(fields))
+	for _, k := range keys {
+		args = append(args, slog.Any(k, fields[k]))
+	}
+	return &SlogLeveledLogger{logger: s.logger.With(args...)}
+}
+
+// logf formats and logs the message if given level is enabled, the source is reported as the caller of Debugf, Infof, Warnf and Errorf
+// rather than this file.
+func (s *SlogLeveledLogger) logf(level slog.Level, format string, v ...interface{}) {
+	ctx := context.Background()
+	if !s.logger.Enabled(ctx, level) {
+		return
+	}
+	var pcs [1]uintptr
+	runtime.Callers(3, pcs[:]) // skip [Callers, logf, XXXf]
+	record := slog.NewRecord(time.Now(), level, fmt.Sprintf(format, v...), pcs[0])
+	_ = s.logger.Handler().Handle(ctx, record)
+}
@@ -0,0 +1,66 @@
+//go:build go1.21
+// +build go1.21
+
+package xlogger
+
+import (
+	"bytes"
+	"github.com/Aoi-hosizora/ahlib/xtesting"
+	"log/slog"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSlogLeveledLogger(t *testing.T) {
+	xtesting.Panic(t, func() { NewSlogLeveledLogger(nil) })
+	xtesting.Panic(t, func() { NewSlogLeveledLoggerWithHandler(nil) })
+	xtesting.Panic(t, func() { NewSlogLeveledLoggerWithHandler((*slog.TextHandler)(nil)) })
+	xtesting.Panic(t, func() { NewSlogLeveledLogger(&slog.Logger{}) })
+
+	buf := &bytes.Buffer{}
+	removeTime := func(_ []string, a slog.Attr) slog.Attr {
+		if a.Key == slog.TimeKey {
+			return slog.Attr{}
+		}
+		return a
+	}
+	handler := slog.NewTextHandler(buf, &slog.HandlerOptions{Level: slog.LevelDebug, ReplaceAttr: removeTime})
+	l := NewSlogLeveledLoggerWithHandler(handler)
+	for _, tc := range []struct {
+		giveFn func()
+		want   string
+	}{
+		{func() { l.Debugf("test %d", 1) }, "level=DEBUG msg=\"test 1\"\n"},
+		{func() { l.Infof("test %d", 2) }, "level=INFO msg=\"test 2\"\n"},
+		{func() { l.Warnf("test %d", 3) }, "level=WARN msg=\"test 3\"\n"},
+		{func() { l.Errorf("test %d", 4) }, "level=ERROR msg=\"test 4\"\n"},
+		{func() { l.WithField("k", "v").Infof("test") }, "level=INFO msg=test k=v\n"},
+		{func() { l.WithFields(map[string]interface{}{"b": 2, "a": 1}).WithField("c", true).Infof("test") }, "level=INFO msg=test a=1 b=2 c=true\n"},
+	} {
+		buf.Reset()
+		tc.giveFn()
+		xtesting.Equal(t, buf.String(), tc.want)
+	}
+
+	l = NewSlogLeveledLogger(slog.New(slog.NewTextHandler(buf, &slog.HandlerOptions{Level: slog.LevelWarn, ReplaceAttr: removeTime})))
+	buf.Reset()
+	l.Debugf("test")
+	l.Infof("test")
+	xtesting.Equal(t, buf.String(), "")
+	l.Warnf("test")
+	xtesting.Equal(t, buf.String(), "level=WARN msg=test\n")
+
+	// source
+	buf.Reset()
+	l = NewSlogLeveledLoggerWithHandler(slog.NewTextHandler(buf, &slog.HandlerOptions{AddSource: true, ReplaceAttr: removeTime}))
+	l.Infof("test")
+	l.WithField("k", "v").Errorf("test")
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	xtesting.Equal(t, len(lines), 2)
+	for _, line := range lines {
+		xtesting.True(t, strings.Contains(line, "source="), line)
+		xtesting.False(t, strings.Contains(line, filepath.Join("xlogger", "slog.go")), line)
+		xtesting.True(t, strings.Contains(line, "slog_test.go:"), line)
+	}
+}
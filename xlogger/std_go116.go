@@ -0,0 +1,13 @@
+//go:build go1.16
+// +build go1.16
+
+package xlogger
+
+import (
+	"log"
+)
+
+// Default returns the standard logger used by log package's top-level functions, this is the same as log.Default.
+func Default() *log.Logger {
+	return log.Default()
+}
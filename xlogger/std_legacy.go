@@ -0,0 +1,19 @@
+//go:build !go1.16
+// +build !go1.16
+
+package xlogger
+
+import (
+	"log"
+	_ "unsafe"
+)
+
+// Note: log.Default is not available before go1.16, so go:linkname is still used in the old toolchains.
+
+//go:linkname std log.std
+var std *log.Logger
+
+// Default returns the standard logger used by log package's top-level functions.
+func Default() *log.Logger {
+	return std
+}
@@ -1,14 +1,16 @@
 package xlogger
 
 import (
+	"io"
 	"log"
-	_ "unsafe"
+	"sync"
 )
 
-//go:linkname Std log.std
-
-// Std represents the standard logger creates by log.New, that equals to `log.New(os.Stderr, "", log.LstdFlags)`.
-var Std *log.Logger
+// Std represents the standard logger used by log package's top-level functions, that equals to `log.New(os.Stderr, "", log.LstdFlags)`
+// by default, and it is the same as the logger returned by Default.
+//
+// Deprecated: Std is a shared mutable global, use Default, SetOutput, SetFlags, SetPrefix and OverrideStd instead.
+var Std = Default()
 
 var _ StdLogger = (*log.Logger)(nil)
 
@@ -26,3 +28,69 @@ type StdLogger interface {
 	Fatalf(format string, v ...interface{})
 	Fatalln(v ...interface{})
 }
+
+// Writer returns the output destination of the standard logger, this is the same as log.Writer.
+func Writer() io.Writer {
+	return log.Writer()
+}
+
+// Flags returns the output flags of the standard logger, this is the same as log.Flags.
+func Flags() int {
+	return log.Flags()
+}
+
+// Prefix returns the output prefix of the standard logger, this is the same as log.Prefix.
+func Prefix() string {
+	return log.Prefix()
+}
+
+// SetOutput sets the output destination of the standard logger, this is the same as log.SetOutput.
+func SetOutput(w io.Writer) {
+	log.SetOutput(w)
+}
+
+// SetFlags sets the output flags of the standard logger, this is the same as log.SetFlags.
+func SetFlags(flag int) {
+	log.SetFlags(flag)
+}
+
+// SetPrefix sets the output prefix of the standard logger, this is the same as log.SetPrefix.
+func SetPrefix(prefix string) {
+	log.SetPrefix(prefix)
+}
+
+// overrideMu locks the overriding and restoring of the standard logger.
+var overrideMu sync.Mutex
+
+// OverrideStd overrides the output destination, flags and prefix of the standard logger, and returns a function which restores the
+// previous values. Note that nested overrides should be restored in reverse order, and the returned function only takes effect once.
+// Example:
+// 	restore := OverrideStd(buf, 0, "")
+// 	defer restore()
+func OverrideStd(w io.Writer, flag int, prefix string) (restore func()) {
+	overrideMu.Lock()
+	oldOutput, oldFlags, oldPrefix := log.Writer(), log.Flags(), log.Prefix()
+	log.SetOutput(w)
+	log.SetFlags(flag)
+	log.SetPrefix(prefix)
+	overrideMu.Unlock()
+
+	once := sync.Once{}
+	return func() {
+		once.Do(func() {
+			overrideMu.Lock()
+			log.SetOutput(oldOutput)
+			log.SetFlags(oldFlags)
+			log.SetPrefix(oldPrefix)
+			overrideMu.Unlock()
+		})
+	}
+}
+
+// WithStd overrides the output destination, flags and prefix of the standard logger, invokes given function, and restores the previous
+// values after the function returns or panics.
+func WithStd(w io.Writer, flag int, prefix string, f func()) {
+	restore := OverrideStd(w, flag, prefix)
+	defer restore()
+	f()
+}
@@ -1,6 +1,11 @@
 package xlogger
 
 import (
+	"bytes"
+	"fmt"
+	"github.com/Aoi-hosizora/ahlib/xtesting"
+	"log"
+	"strings"
 	"testing"
 )
 
@@ -40,3 +45,244 @@ func TestStdLogger(t *testing.T) {
 		l.Panicln("test")
 	}()
 }
+
+func TestLevel(t *testing.T) {
+	for _, tc := range []struct {
+		give Level
+		want string
+	}{
+		{DebugLevel, "DEBUG"},
+		{InfoLevel, "INFO"},
+		{WarnLevel, "WARN"},
+		{ErrorLevel, "ERROR"},
+		{Level(9), "Level(9)"},
+	} {
+		xtesting.Equal(t, tc.give.String(), tc.want)
+	}
+}
+
+func TestNoopLeveledLogger(t *testing.T) {
+	l := NewNoopLeveledLogger()
+	l.Debugf("test")
+	l.Infof("test")
+	l.Warnf("test")
+	l.Errorf("test")
+	xtesting.Equal(t, l.WithField("k", "v"), l)
+	xtesting.Equal(t, l.WithFields(map[string]interface{}{"k": "v"}), l)
+}
+
+func TestStdLeveledLogger(t *testing.T) {
+	xtesting.Panic(t, func() { NewStdLeveledLogger(nil, DebugLevel) })
+
+	buf := &bytes.Buffer{}
+	l := NewStdLeveledLogger(log.New(buf, "", 0), DebugLevel)
+	for _, tc := range []struct {
+		giveFn func()
+		want   string
+	}{
+		{func() { l.Debugf("test %d", 1) }, "[DEBUG] test 1\n"},
+		{func() { l.Infof("test %d", 2) }, "[INFO] test 2\n"},
+		{func() { l.Warnf("test %d", 3) }, "[WARN] test 3\n"},
+		{func() { l.Errorf("test %d", 4) }, "[ERROR] test 4\n"},
+		{func() { l.WithField("k", "v").Infof("test") }, "[INFO] test k=v\n"},
+		{func() { l.WithFields(map[string]interface{}{"b": 2, "a": 1}).WithField("c", true).Infof("test") }, "[INFO] test a=1 b=2 c=true\n"},
+		{func() { l.WithField("a", 1).WithFields(map[string]interface{}{"a": 2}).Infof("test") }, "[INFO] test a=2\n"},
+		{func() { l.WithField("a", 1); l.Infof("test") }, "[INFO] test\n"},
+	} {
+		buf.Reset()
+		tc.giveFn()
+		xtesting.Equal(t, buf.String(), tc.want)
+	}
+
+	l = NewStdLeveledLogger(log.New(buf, "", log.Lshortfile), WarnLevel)
+	buf.Reset()
+	l.Debugf("test")
+	l.Infof("test")
+	xtesting.Equal(t, buf.String(), "")
+	l.Warnf("test")
+	xtesting.True(t, strings.HasPrefix(buf.String(), "xlogger_test.go:"))
+	xtesting.True(t, strings.HasSuffix(buf.String(), ": [WARN] test\n"))
+}
+
+func TestFormatFields(t *testing.T) {
+	xtesting.Equal(t, FormatFields(nil), "")
+	xtesting.Equal(t, FormatFields(map[string]interface{}{}), "")
+	xtesting.Equal(t, FormatFields(map[string]interface{}{"k": "v"}), " k=v")
+	xtesting.Equal(t, FormatFields(map[string]interface{}{"b": nil, "a": []int{1}}), " a=[1] b=<nil>")
+}
+
+type mockT struct {
+	errors   []string
+	cleanups []func()
+}
+
+func (m *mockT) Helper()                                   {}
+func (m *mockT) Errorf(format string, args ...interface{}) { m.errors = append(m.errors, fmt.Sprintf(format, args...)) }
+func (m *mockT) Cleanup(f func())                          { m.cleanups = append(m.cleanups, f) }
+
+func TestCaptureLogger(t *testing.T) {
+	l := NewCaptureLogger()
+	var _ StdLogger = l
+	var _ LeveledLogger = l
+
+	l.Print("a", "b")
+	l.Printf("%s-%d", "a", 1)
+	l.Println("a", "b")
+	xtesting.PanicWithValue(t, "ab", func() { l.Panic("a", "b") })
+	xtesting.PanicWithValue(t, "a-1", func() { l.Panicf("%s-%d", "a", 1) })
+	xtesting.PanicWithValue(t, "a b\n", func() { l.Panicln("a", "b") })
+	xtesting.PanicWithValue(t, "ab", func() { l.Fatal("a", "b") })
+	xtesting.PanicWithValue(t, "a-1", func() { l.Fatalf("%s-%d", "a", 1) })
+	xtesting.PanicWithValue(t, "a b\n", func() { l.Fatalln("a", "b") })
+	xtesting.Equal(t, l.Messages(), []string{"ab", "a-1", "a b", "ab", "a-1", "a b", "ab", "a-1", "a b"})
+	xtesting.Equal(t, l.Count(InfoLevel), 3)
+	xtesting.Equal(t, l.Count(ErrorLevel), 6)
+
+	l.Reset()
+	xtesting.Equal(t, len(l.Entries()), 0)
+	l.Debugf("debug %d", 1)
+	l.Infof("info %d", 2)
+	l.Warnf("warn %d", 3)
+	l.Errorf("error %d", 4)
+	l.WithField("k", "v").WithFields(map[string]interface{}{"n": 1, "s": []int{1}}).Warnf("with fields")
+	xtesting.Equal(t, l.Entries(), []CapturedEntry{
+		{DebugLevel, "debug 1", map[string]interface{}{}},
+		{InfoLevel, "info 2", map[string]interface{}{}},
+		{WarnLevel, "warn 3", map[string]interface{}{}},
+		{ErrorLevel, "error 4", map[string]interface{}{}},
+		{WarnLevel, "with fields", map[string]interface{}{"k": "v", "n": 1, "s": []int{1}}},
+	})
+	xtesting.True(t, l.ContainsMessage("info"))
+	xtesting.True(t, l.ContainsMessage("fields"))
+	xtesting.False(t, l.ContainsMessage("trace"))
+	xtesting.Equal(t, l.Count(WarnLevel), 2)
+	xtesting.True(t, l.ContainsFields(nil))
+	xtesting.True(t, l.ContainsFields(map[string]interface{}{"k": "v"}))
+	xtesting.True(t, l.ContainsFields(map[string]interface{}{"k": "v", "s": []int{1}}))
+	xtesting.False(t, l.ContainsFields(map[string]interface{}{"k": "v", "n": 2}))
+	xtesting.False(t, l.ContainsFields(map[string]interface{}{"x": nil}))
+
+	// write
+	l.Reset()
+	n, err := l.Write([]byte("[WARN] a\n[err"))
+	xtesting.Equal(t, n, 13)
+	xtesting.Nil(t, err)
+	_, _ = l.Write([]byte("or] b\r\nc\n[UNKNOWN] d\n"))
+	xtesting.Equal(t, l.Entries(), []CapturedEntry{
+		{WarnLevel, "a", map[string]interface{}{}},
+		{ErrorLevel, "b", map[string]interface{}{}},
+		{InfoLevel, "c", map[string]interface{}{}},
+		{InfoLevel, "[UNKNOWN] d", map[string]interface{}{}},
+	})
+
+	// write with WARNING prefix, and messages containing "=" are kept as they are
+	l.Reset()
+	_, _ = l.Write([]byte("[WARNING] a\n[warning] b k=v\n[INFO] retry count=3\n"))
+	xtesting.Equal(t, l.Entries(), []CapturedEntry{
+		{WarnLevel, "a", map[string]interface{}{}},
+		{WarnLevel, "b k=v", map[string]interface{}{}},
+		{InfoLevel, "retry count=3", map[string]interface{}{}},
+	})
+	xtesting.True(t, l.ContainsMessage("count=3"))
+
+	// StdLeveledLogger records fields structurally
+	l.Reset()
+	l2 := l.WithField("a", "0").(*CaptureLogger)
+	std := NewStdLeveledLogger(log.New(l2, "", 0), DebugLevel)
+	std.Infof("retry count=%d", 3)
+	std.WithFields(map[string]interface{}{"n": 1, "s": "x y"}).Warnf("std")
+	xtesting.Equal(t, l.Entries(), []CapturedEntry{
+		{InfoLevel, "retry count=3", map[string]interface{}{"a": "0"}},
+		{WarnLevel, "std", map[string]interface{}{"a": "0", "n": 1, "s": "x y"}},
+	})
+	xtesting.True(t, l.ContainsMessage("count=3"))
+	l.Reset()
+	_, _ = l.Write([]byte("[WARN] a\n[err"))
+	_, _ = l.Write([]byte("or] b\r\nc\n[UNKNOWN] d\n"))
+
+	// assert
+	mt := &mockT{}
+	xtesting.True(t, l.AssertContainsMessage(mt, "a"))
+	xtesting.True(t, l.AssertCount(mt, InfoLevel, 2))
+	xtesting.True(t, l.AssertContainsFields(mt, map[string]interface{}{}))
+	xtesting.Equal(t, len(mt.errors), 0)
+	xtesting.False(t, l.AssertContainsMessage(mt, "x"))
+	xtesting.False(t, l.AssertCount(mt, InfoLevel, 1))
+	xtesting.False(t, l.AssertContainsFields(mt, map[string]interface{}{"k": "v"}))
+	xtesting.Equal(t, mt.errors, []string{
+		`xlogger: no captured entry contains message "x", captured messages: ["a" "b" "c" "[UNKNOWN] d"]`,
+		"xlogger: expected 1 captured entries in INFO level, actual 2",
+		"xlogger: no captured entry contains fields map[k:v]",
+	})
+}
+
+func TestCaptureStd(t *testing.T) {
+	oldOutput, oldFlags, oldPrefix := Std.Writer(), Std.Flags(), Std.Prefix()
+	mt := &mockT{}
+	l := CaptureStd(mt)
+	Std.Print("test")
+	NewStdLeveledLogger(Std, DebugLevel).WithField("k", "v").Warnf("test %d", 1)
+	log.Printf("[debug] %s", "test")
+	xtesting.Equal(t, l.Entries(), []CapturedEntry{
+		{InfoLevel, "test", map[string]interface{}{}},
+		{WarnLevel, "test 1", map[string]interface{}{"k": "v"}}, // recorded directly
+		{DebugLevel, "test", map[string]interface{}{}},
+	})
+
+	xtesting.Equal(t, len(mt.cleanups), 1)
+	mt.cleanups[0]()
+	xtesting.Equal(t, Std.Writer(), oldOutput)
+	xtesting.Equal(t, Std.Flags(), oldFlags)
+	xtesting.Equal(t, Std.Prefix(), oldPrefix)
+}
+
+func TestDefault(t *testing.T) {
+	xtesting.SamePointer(t, Default(), Std)
+	xtesting.SamePointer(t, Default(), Default())
+
+	oldOutput, oldFlags, oldPrefix := Writer(), Flags(), Prefix()
+	buf := &bytes.Buffer{}
+	SetOutput(buf)
+	SetFlags(0)
+	SetPrefix("prefix: ")
+	xtesting.Equal(t, Writer(), buf)
+	xtesting.Equal(t, Flags(), 0)
+	xtesting.Equal(t, Prefix(), "prefix: ")
+	log.Print("test")
+	Default().Print("test")
+	xtesting.Equal(t, buf.String(), "prefix: test\nprefix: test\n")
+	SetOutput(oldOutput)
+	SetFlags(oldFlags)
+	SetPrefix(oldPrefix)
+}
+
+func TestOverrideStd(t *testing.T) {
+	oldOutput, oldFlags, oldPrefix := Writer(), Flags(), Prefix()
+	buf1 := &bytes.Buffer{}
+	buf2 := &bytes.Buffer{}
+
+	restore1 := OverrideStd(buf1, 0, "1: ")
+	log.Print("a")
+	restore2 := OverrideStd(buf2, log.LUTC, "2: ")
+	log.Print("b")
+	xtesting.Equal(t, Flags(), log.LUTC)
+	restore2()
+	log.Print("c")
+	restore2() // only takes effect once
+	log.Print("d")
+	restore1()
+	xtesting.Equal(t, buf1.String(), "1: a\n1: c\n1: d\n")
+	xtesting.Equal(t, buf2.String(), "2: b\n")
+	xtesting.Equal(t, Writer(), oldOutput)
+	xtesting.Equal(t, Flags(), oldFlags)
+	xtesting.Equal(t, Prefix(), oldPrefix)
+
+	buf1.Reset()
+	WithStd(buf1, 0, "", func() { Std.Print("test") })
+	xtesting.Equal(t, buf1.String(), "test\n")
+	xtesting.Panic(t, func() { WithStd(buf1, 0, "", func() { log.Panic("test") }) })
+	xtesting.Equal(t, buf1.String(), "test\ntest\n")
+	xtesting.Equal(t, Writer(), oldOutput)
+	xtesting.Equal(t, Flags(), oldFlags)
+	xtesting.Equal(t, Prefix(), oldPrefix)
+}
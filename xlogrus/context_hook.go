@@ -0,0 +1,127 @@
+package xlogrus
+
+import (
+	"context"
+	"github.com/sirupsen/logrus"
+)
+
+// SpanContext describes an OpenTelemetry-style span context, which contains trace id and span id. Note that the span context of
+// go.opentelemetry.io/otel/trace does not implement this interface directly, a tiny adapter is needed.
+type SpanContext interface {
+	TraceID() string
+	SpanID() string
+}
+
+// ContextHookConfig represents ContextHook's config.
+type ContextHookConfig struct {
+	// Keys represents the map from field name to context key, values of these keys in entry.Context will be extracted into fields.
+	Keys map[string]interface{}
+
+	// SpanContextFunc represents the function to get SpanContext from context.Context, defaults to nil, span context will not be extracted.
+	SpanContextFunc func(ctx context.Context) SpanContext
+
+	// TraceIDField represents the field name of trace id, defaults to "trace_id".
+	TraceIDField string
+
+	// SpanIDField represents the field name of span id, defaults to "span_id".
+	SpanIDField string
+}
+
+// ContextHook represents a logrus hook for extracting values from logrus.Entry's context into fields.
+// Example:
+// 	hook := NewContextHook(&ContextHookConfig{
+// 		Keys:            map[string]interface{}{"request_id": requestIDKey},
+// 		SpanContextFunc: func(ctx context.Context) SpanContext { return otelSpanContext{trace.SpanContextFromContext(ctx)} },
+// 	})
+// 	logger.AddHook(hook)
+// 	logger.WithContext(ctx).Info("test")
+type ContextHook struct {
+	// config is the context hook config.
+	config *ContextHookConfig
+}
+
+// NewContextHook creates a ContextHook as logrus.Hook with ContextHookConfig.
+func NewContextHook(config *ContextHookConfig) logrus.Hook {
+	if config == nil {
+		panic(panicNilConfig)
+	}
+	if config.TraceIDField == "" {
+		config.TraceIDField = "trace_id"
+	}
+	if config.SpanIDField == "" {
+		config.SpanIDField = "span_id"
+	}
+
+	return &ContextHook{config: config}
+}
+
+// Levels returns all the logrus.Level-s, that is the hook fires on every level, this implements logrus.Hook.
+func (c *ContextHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+// Fire extracts configured values from logrus.Entry's context into fields, this implements logrus.Hook.
+func (c *ContextHook) Fire(entry *logrus.Entry) error {
+	ctx := entry.Context
+	if ctx == nil {
+		return nil
+	}
+	if entry.Data == nil {
+		entry.Data = make(logrus.Fields, len(c.config.Keys)+2)
+	}
+
+	for field, key := range c.config.Keys {
+		if value := ctx.Value(key); value != nil {
+			entry.Data[field] = value
+		}
+	}
+	if c.config.SpanContextFunc != nil {
+		if sc := c.config.SpanContextFunc(ctx); sc != nil {
+			if traceID := sc.TraceID(); traceID != "" {
+				entry.Data[c.config.TraceIDField] = traceID
+			}
+			if spanID := sc.SpanID(); spanID != "" {
+				entry.Data[c.config.SpanIDField] = spanID
+			}
+		}
+	}
+	return nil
+}
+
+// entryContextKey is the context key type for storing *logrus.Entry.
+type entryContextKey struct{}
+
+// NewContextWithEntry returns a copy of given context.Context which carries given *logrus.Entry.
+func NewContextWithEntry(ctx context.Context, entry *logrus.Entry) context.Context {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return context.WithValue(ctx, entryContextKey{}, entry)
+}
+
+// EntryFromContext returns the *logrus.Entry stored in given context.Context, and the returned entry's context has been set to ctx.
+func EntryFromContext(ctx context.Context) (*logrus.Entry, bool) {
+	if ctx == nil {
+		return nil, false
+	}
+	entry, ok := ctx.Value(entryContextKey{}).(*logrus.Entry)
+	if !ok || entry == nil {
+		return nil, false
+	}
+	return entry.WithContext(ctx), true
+}
+
+// EntryFromContextOr returns the *logrus.Entry stored in given context.Context, or returns a new entry created from given fallback
+// logrus.Logger if not found. Note that the returned entry's context has always been set to ctx.
+func EntryFromContextOr(ctx context.Context, fallback *logrus.Logger) *logrus.Entry {
+	if entry, ok := EntryFromContext(ctx); ok {
+		return entry
+	}
+	if fallback == nil {
+		fallback = logrus.StandardLogger()
+	}
+	if ctx == nil {
+		return logrus.NewEntry(fallback)
+	}
+	return fallback.WithContext(ctx)
+}
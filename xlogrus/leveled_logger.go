@@ -0,0 +1,38 @@
+package xlogrus
+
+import (
+	"github.com/Aoi-hosizora/ahlib-more/xlogger"
+	"github.com/sirupsen/logrus"
+)
+
+// LeveledLoggerAdapter represents an adapter which implements xlogger.LeveledLogger on top of logrus.FieldLogger, such as
+// logrus.Logger and logrus.Entry.
+type LeveledLoggerAdapter struct {
+	// logger is the logrus.FieldLogger used to log.
+	logger logrus.FieldLogger
+}
+
+var _ xlogger.LeveledLogger = (*LeveledLoggerAdapter)(nil)
+
+// NewLeveledLoggerAdapter creates a LeveledLoggerAdapter using given logrus.FieldLogger.
+func NewLeveledLoggerAdapter(logger logrus.FieldLogger) *LeveledLoggerAdapter {
+	if logger == nil {
+		panic(panicNilLogger)
+	}
+	return &LeveledLoggerAdapter{logger: logger}
+}
+
+func (l *LeveledLoggerAdapter) Debugf(format string, v ...interface{}) { l.logger.Debugf(format, v...) }
+func (l *LeveledLoggerAdapter) Infof(format string, v ...interface{})  { l.logger.Infof(format, v...) }
+func (l *LeveledLoggerAdapter) Warnf(format string, v ...interface{})  { l.logger.Warnf(format, v...) }
+func (l *LeveledLoggerAdapter) Errorf(format string, v ...interface{}) { l.logger.Errorf(format, v...) }
+
+// WithField returns a new LeveledLoggerAdapter with given field added.
+func (l *LeveledLoggerAdapter) WithField(key string, value interface{}) xlogger.LeveledLogger {
+	return &LeveledLoggerAdapter{logger: l.logger.WithField(key, value)}
+}
+
+// WithFields returns a new LeveledLoggerAdapter with given fields added.
+func (l *LeveledLoggerAdapter) WithFields(fields map[string]interface{}) xlogger.LeveledLogger {
+	return &LeveledLoggerAdapter{logger: l.logger.WithFields(fields)}
+}
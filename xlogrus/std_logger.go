@@ -0,0 +1,243 @@
+package xlogrus
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/Aoi-hosizora/ahlib-more/xlogger"
+	"github.com/sirupsen/logrus"
+	"log"
+	"strings"
+	"sync"
+)
+
+// StdLoggerAdapter represents an adapter which implements xlogger.StdLogger on top of logrus.Entry, each method family (Print,
+// Panic and Fatal) is logged in the level chosen by the creator.
+// Example:
+// 	var l xlogger.StdLogger = NewStdLoggerAdapter(logrus.StandardLogger(), logrus.InfoLevel, logrus.ErrorLevel, logrus.FatalLevel)
+// 	l.Printf("Listening on %s", addr)
+type StdLoggerAdapter struct {
+	// entry is the logrus.Entry used to log.
+	entry *logrus.Entry
+
+	// printLevel is the level of Print series methods.
+	printLevel logrus.Level
+
+	// panicLevel is the level of Panic series methods.
+	panicLevel logrus.Level
+
+	// fatalLevel is the level of Fatal series methods.
+	fatalLevel logrus.Level
+}
+
+var _ xlogger.StdLogger = (*StdLoggerAdapter)(nil)
+
+const (
+	panicNilLogger = "xlogrus: nil logger"
+)
+
+// NewStdLoggerAdapter creates a StdLoggerAdapter using given logrus.Logger and the levels for Print, Panic and Fatal series methods.
+func NewStdLoggerAdapter(logger *logrus.Logger, printLevel, panicLevel, fatalLevel logrus.Level) *StdLoggerAdapter {
+	if logger == nil {
+		panic(panicNilLogger)
+	}
+	return NewStdLoggerAdapterWithEntry(logrus.NewEntry(logger), printLevel, panicLevel, fatalLevel)
+}
+
+// NewStdLoggerAdapterWithEntry creates a StdLoggerAdapter using given logrus.Entry and the levels for Print, Panic and Fatal series methods.
+func NewStdLoggerAdapterWithEntry(entry *logrus.Entry, printLevel, panicLevel, fatalLevel logrus.Level) *StdLoggerAdapter {
+	if entry == nil || entry.Logger == nil {
+		panic(panicNilLogger)
+	}
+	for _, level := range []logrus.Level{printLevel, panicLevel, fatalLevel} {
+		if level < logrus.PanicLevel || level > logrus.TraceLevel {
+			panic(panicInvalidLevel)
+		}
+	}
+	return &StdLoggerAdapter{entry: entry, printLevel: printLevel, panicLevel: panicLevel, fatalLevel: fatalLevel}
+}
+
+// Print logs in print level, arguments are handled in the manner of fmt.Print.
+func (s *StdLoggerAdapter) Print(v ...interface{}) {
+	s.entry.Log(s.printLevel, v...)
+}
+
+// Printf logs in print level, arguments are handled in the manner of fmt.Printf.
+func (s *StdLoggerAdapter) Printf(format string, v ...interface{}) {
+	s.entry.Logf(s.printLevel, format, v...)
+}
+
+// Println logs in print level, arguments are handled in the manner of fmt.Println.
+func (s *StdLoggerAdapter) Println(v ...interface{}) {
+	s.entry.Logln(s.printLevel, v...)
+}
+
+// Panic logs in panic level and then panics with the message string, arguments are handled in the manner of fmt.Print.
+func (s *StdLoggerAdapter) Panic(v ...interface{}) {
+	s.logAndPanic(fmt.Sprint(v...))
+}
+
+// Panicf logs in panic level and then panics with the message string, arguments are handled in the manner of fmt.Printf.
+func (s *StdLoggerAdapter) Panicf(format string, v ...interface{}) {
+	s.logAndPanic(fmt.Sprintf(format, v...))
+}
+
+// Panicln logs in panic level and then panics with the message string, arguments are handled in the manner of fmt.Println.
+func (s *StdLoggerAdapter) Panicln(v ...interface{}) {
+	s.logAndPanic(fmt.Sprintln(v...))
+}
+
+// logAndPanic logs given message in panic level and then panics with the message string, which is the same as log.Panic. Note that the
+// *logrus.Entry panicked by logrus itself when panic level is logrus.PanicLevel will be recovered, and replaced with the message string.
+func (s *StdLoggerAdapter) logAndPanic(message string) {
+	func() {
+		defer func() {
+			if r := recover(); r != nil {
+				if _, ok := r.(*logrus.Entry); !ok {
+					panic(r) // panicked by hooks or formatters
+				}
+			}
+		}()
+		s.entry.Log(s.panicLevel, strings.TrimSuffix(message, "\n"))
+	}()
+	panic(message)
+}
+
+// Fatal logs in fatal level and then calls logger's Exit(1), arguments are handled in the manner of fmt.Print.
+func (s *StdLoggerAdapter) Fatal(v ...interface{}) {
+	s.entry.Log(s.fatalLevel, v...)
+	s.entry.Logger.Exit(1)
+}
+
+// Fatalf logs in fatal level and then calls logger's Exit(1), arguments are handled in the manner of fmt.Printf.
+func (s *StdLoggerAdapter) Fatalf(format string, v ...interface{}) {
+	s.entry.Logf(s.fatalLevel, format, v...)
+	s.entry.Logger.Exit(1)
+}
+
+// Fatalln logs in fatal level and then calls logger's Exit(1), arguments are handled in the manner of fmt.Println.
+func (s *StdLoggerAdapter) Fatalln(v ...interface{}) {
+	s.entry.Logln(s.fatalLevel, v...)
+	s.entry.Logger.Exit(1)
+}
+
+// StdLogWriter represents an io.Writer which redirects each line written by log.Logger into logrus.Entry. The level of each line is
+// detected from its prefix, such as "[WARN]" or "[ERROR]", and the lines without level prefix will be logged in the default level. Note
+// that the lines in logrus.PanicLevel (such as "[PANIC]" prefix) are logged in logrus.ErrorLevel, because the panic is raised by log.Logger
+// itself rather than by the writer.
+// Example:
+// 	srv := &http.Server{ErrorLog: log.New(NewStdLogWriter(logrus.NewEntry(logger), logrus.ErrorLevel), "", 0)}
+type StdLogWriter struct {
+	// entry is the logrus.Entry used to log.
+	entry *logrus.Entry
+
+	// defaultLevel is the level used when no level prefix is found.
+	defaultLevel logrus.Level
+
+	// mu locks the buf.
+	mu sync.Mutex
+
+	// buf stores the incomplete line.
+	buf []byte
+}
+
+// NewStdLogWriter creates a StdLogWriter using given logrus.Entry and default level.
+func NewStdLogWriter(entry *logrus.Entry, defaultLevel logrus.Level) *StdLogWriter {
+	if entry == nil || entry.Logger == nil {
+		panic(panicNilLogger)
+	}
+	if defaultLevel < logrus.PanicLevel || defaultLevel > logrus.TraceLevel {
+		panic(panicInvalidLevel)
+	}
+	return &StdLogWriter{entry: entry, defaultLevel: defaultLevel}
+}
+
+// Write splits given bytes into lines and logs each complete line, this implements io.Writer.
+func (s *StdLogWriter) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.buf = append(s.buf, p...)
+	for {
+		idx := bytes.IndexByte(s.buf, '\n')
+		if idx == -1 {
+			break
+		}
+		line := string(s.buf[:idx])
+		s.buf = s.buf[idx+1:]
+		s.logLine(line)
+	}
+	if len(s.buf) == 0 {
+		s.buf = nil
+	}
+	return len(p), nil
+}
+
+// logLine detects the level of given line and logs it.
+func (s *StdLogWriter) logLine(line string) {
+	line = strings.TrimRight(line, "\r")
+	level, message := ParseStdLogLevel(line, s.defaultLevel)
+	if level <= logrus.PanicLevel {
+		level = logrus.ErrorLevel // Entry.Log will panic when level is PanicLevel, and the line must not be treated as fatal
+	}
+	s.entry.Log(level, message)
+}
+
+// ParseStdLogLevel detects the level from given log message's prefix, and returns the detected level and the message without prefix.
+// Supported prefixes are "[TRACE]", "[DEBUG]", "[INFO]", "[WARN]", "[WARNING]", "[ERROR]", "[FATAL]" and "[PANIC]", case-insensitively.
+func ParseStdLogLevel(message string, defaultLevel logrus.Level) (logrus.Level, string) {
+	trimmed := strings.TrimLeft(message, " \t")
+	if len(trimmed) < 2 || trimmed[0] != '[' {
+		return defaultLevel, message
+	}
+	end := strings.IndexByte(trimmed, ']')
+	if end == -1 {
+		return defaultLevel, message
+	}
+
+	var level logrus.Level
+	switch strings.ToUpper(trimmed[1:end]) {
+	case "TRACE":
+		level = logrus.TraceLevel
+	case "DEBUG":
+		level = logrus.DebugLevel
+	case "INFO":
+		level = logrus.InfoLevel
+	case "WARN", "WARNING":
+		level = logrus.WarnLevel
+	case "ERROR":
+		level = logrus.ErrorLevel
+	case "FATAL":
+		level = logrus.FatalLevel
+	case "PANIC":
+		level = logrus.PanicLevel
+	default:
+		return defaultLevel, message
+	}
+	return level, strings.TrimLeft(trimmed[end+1:], " \t")
+}
+
+// RedirectStdLog redirects the output of the standard logger in log package to given logrus.Entry with StdLogWriter by xlogger.OverrideStd,
+// the flags and prefix of the standard logger will be cleared, and the returned function can be used to restore the previous output,
+// flags and prefix.
+func RedirectStdLog(entry *logrus.Entry, defaultLevel logrus.Level) (restore func()) {
+	writer := NewStdLogWriter(entry, defaultLevel)
+	return xlogger.OverrideStd(writer, 0, "")
+}
+
+// RedirectLogger redirects the output of given log.Logger to given logrus.Entry with StdLogWriter, the flags and prefix of the logger
+// will be cleared, and the returned function can be used to restore the previous output, flags and prefix.
+func RedirectLogger(logger *log.Logger, entry *logrus.Entry, defaultLevel logrus.Level) (restore func()) {
+	if logger == nil {
+		panic(panicNilLogger)
+	}
+	writer := NewStdLogWriter(entry, defaultLevel)
+	oldOutput, oldFlags, oldPrefix := logger.Writer(), logger.Flags(), logger.Prefix()
+	logger.SetOutput(writer)
+	logger.SetFlags(0)
+	logger.SetPrefix("")
+	return func() {
+		logger.SetOutput(oldOutput)
+		logger.SetFlags(oldFlags)
+		logger.SetPrefix(oldPrefix)
+	}
+}
@@ -1,9 +1,12 @@
 package xlogrus
 
 import (
+	"context"
+	"github.com/Aoi-hosizora/ahlib-more/xlogger"
 	"github.com/Aoi-hosizora/ahlib/xtesting"
 	"github.com/sirupsen/logrus"
 	"io"
+	"log"
 	"os"
 	"runtime"
 	"strings"
@@ -128,3 +131,268 @@ func TestRotateLogHook(t *testing.T) {
 	xtesting.Equal(t, hook.Levels(), []logrus.Level{logrus.PanicLevel, logrus.FatalLevel, logrus.ErrorLevel, logrus.WarnLevel})
 	xtesting.Nil(t, hook.Fire(logrus.WithField("key", "value")))
 }
+
+type testSpanContext struct{ traceID, spanID string }
+
+func (t testSpanContext) TraceID() string { return t.traceID }
+func (t testSpanContext) SpanID() string  { return t.spanID }
+
+func TestContextHook(t *testing.T) {
+	type ctxKey string
+	xtesting.Panic(t, func() { NewContextHook(nil) })
+
+	l := logrus.New()
+	sb := &strings.Builder{}
+	l.SetOutput(sb)
+	l.SetFormatter(&logrus.JSONFormatter{DisableTimestamp: true})
+	hook := NewContextHook(&ContextHookConfig{
+		Keys: map[string]interface{}{"request_id": ctxKey("rid"), "user": ctxKey("user")},
+		SpanContextFunc: func(ctx context.Context) SpanContext {
+			if sc, ok := ctx.Value(ctxKey("span")).(SpanContext); ok {
+				return sc
+			}
+			return nil
+		},
+	})
+	xtesting.Equal(t, hook.Levels(), logrus.AllLevels)
+	l.AddHook(hook)
+
+	for _, tc := range []struct {
+		giveCtx context.Context
+		want    string
+	}{
+		{nil, `{"level":"info","msg":"test"}`},
+		{context.Background(), `{"level":"info","msg":"test"}`},
+		{context.WithValue(context.Background(), ctxKey("rid"), "123"), `{"level":"info","msg":"test","request_id":"123"}`},
+		{context.WithValue(context.WithValue(context.Background(), ctxKey("rid"), "123"), ctxKey("user"), 1), `{"level":"info","msg":"test","request_id":"123","user":1}`},
+		{context.WithValue(context.Background(), ctxKey("span"), testSpanContext{"t1", "s1"}), `{"level":"info","msg":"test","span_id":"s1","trace_id":"t1"}`},
+		{context.WithValue(context.Background(), ctxKey("span"), testSpanContext{"t1", ""}), `{"level":"info","msg":"test","trace_id":"t1"}`},
+	} {
+		sb.Reset()
+		if tc.giveCtx == nil {
+			l.Info("test")
+		} else {
+			l.WithContext(tc.giveCtx).Info("test")
+		}
+		xtesting.Equal(t, strings.TrimSpace(sb.String()), tc.want)
+	}
+
+	// custom field names
+	hook = NewContextHook(&ContextHookConfig{
+		SpanContextFunc: func(ctx context.Context) SpanContext { return testSpanContext{"t2", "s2"} },
+		TraceIDField:    "tid",
+		SpanIDField:     "sid",
+	})
+	entry := logrus.NewEntry(l).WithContext(context.Background())
+	entry.Data = nil
+	xtesting.Nil(t, hook.Fire(entry))
+	xtesting.Equal(t, entry.Data, logrus.Fields{"tid": "t2", "sid": "s2"})
+}
+
+func TestEntryContext(t *testing.T) {
+	l := logrus.New()
+	entry := l.WithField("key", "value")
+
+	e, ok := EntryFromContext(nil)
+	xtesting.False(t, ok)
+	xtesting.Nil(t, e)
+	e, ok = EntryFromContext(context.Background())
+	xtesting.False(t, ok)
+	xtesting.Nil(t, e)
+
+	ctx := NewContextWithEntry(nil, entry)
+	e, ok = EntryFromContext(ctx)
+	xtesting.True(t, ok)
+	xtesting.Equal(t, e.Data, logrus.Fields{"key": "value"})
+	xtesting.Equal(t, e.Context, ctx)
+
+	type ctxKey string
+	child := context.WithValue(ctx, ctxKey("k"), "v")
+	e = EntryFromContextOr(child, nil)
+	xtesting.Equal(t, e.Data, logrus.Fields{"key": "value"})
+	xtesting.Equal(t, e.Context, child)
+
+	e = EntryFromContextOr(context.Background(), l)
+	xtesting.Equal(t, e.Logger, l)
+	xtesting.Equal(t, len(e.Data), 0)
+	e = EntryFromContextOr(nil, nil)
+	xtesting.Equal(t, e.Logger, logrus.StandardLogger())
+	xtesting.Nil(t, e.Context)
+}
+
+func TestStdLoggerAdapter(t *testing.T) {
+	l := logrus.New()
+	l.SetLevel(logrus.TraceLevel)
+	l.SetFormatter(&logrus.TextFormatter{DisableTimestamp: true, DisableQuote: true})
+	sb := &strings.Builder{}
+	l.SetOutput(sb)
+	exitCode := -1
+	l.ExitFunc = func(code int) { exitCode = code }
+
+	xtesting.Panic(t, func() { NewStdLoggerAdapter(nil, logrus.InfoLevel, logrus.PanicLevel, logrus.FatalLevel) })
+	xtesting.Panic(t, func() { NewStdLoggerAdapterWithEntry(nil, logrus.InfoLevel, logrus.PanicLevel, logrus.FatalLevel) })
+	xtesting.Panic(t, func() { NewStdLoggerAdapter(l, 20, logrus.PanicLevel, logrus.FatalLevel) })
+	xtesting.Panic(t, func() { NewStdLoggerAdapter(l, logrus.InfoLevel, 20, logrus.FatalLevel) })
+	xtesting.Panic(t, func() { NewStdLoggerAdapter(l, logrus.InfoLevel, logrus.PanicLevel, 20) })
+
+	adapter := NewStdLoggerAdapter(l, logrus.DebugLevel, logrus.ErrorLevel, logrus.WarnLevel)
+	for _, tc := range []struct {
+		giveFn    func()
+		want      string
+		wantPanic string
+		wantExit  bool
+	}{
+		{func() { adapter.Print("a", "b") }, "level=debug msg=ab\n", "", false},
+		{func() { adapter.Printf("%s-%d", "a", 1) }, "level=debug msg=a-1\n", "", false},
+		{func() { adapter.Println("a", "b") }, "level=debug msg=a b\n", "", false},
+		{func() { adapter.Panic("a", "b") }, "level=error msg=ab\n", "ab", false},
+		{func() { adapter.Panicf("%s-%d", "a", 1) }, "level=error msg=a-1\n", "a-1", false},
+		{func() { adapter.Panicln("a", "b") }, "level=error msg=a b\n", "a b\n", false},
+		{func() { adapter.Fatal("a", "b") }, "level=warning msg=ab\n", "", true},
+		{func() { adapter.Fatalf("%s-%d", "a", 1) }, "level=warning msg=a-1\n", "", true},
+		{func() { adapter.Fatalln("a", "b") }, "level=warning msg=a b\n", "", true},
+	} {
+		sb.Reset()
+		exitCode = -1
+		if tc.wantPanic != "" {
+			xtesting.PanicWithValue(t, tc.wantPanic, tc.giveFn)
+		} else {
+			tc.giveFn()
+		}
+		xtesting.Equal(t, sb.String(), tc.want)
+		if tc.wantExit {
+			xtesting.Equal(t, exitCode, 1)
+		} else {
+			xtesting.Equal(t, exitCode, -1)
+		}
+	}
+
+	adapter = NewStdLoggerAdapterWithEntry(l.WithField("k", "v"), logrus.InfoLevel, logrus.PanicLevel, logrus.FatalLevel)
+	sb.Reset()
+	adapter.Print("test")
+	xtesting.Equal(t, sb.String(), "level=info msg=test k=v\n")
+	sb.Reset()
+	xtesting.PanicWithValue(t, "test", func() { adapter.Panic("test") }) // string rather than *logrus.Entry
+	xtesting.Equal(t, sb.String(), "level=panic msg=test k=v\n")
+	sb.Reset()
+	xtesting.PanicWithValue(t, "a-1", func() { adapter.Panicf("%s-%d", "a", 1) })
+	xtesting.PanicWithValue(t, "a b\n", func() { adapter.Panicln("a", "b") })
+	xtesting.Equal(t, sb.String(), "level=panic msg=a-1 k=v\nlevel=panic msg=a b k=v\n")
+
+	// panics by hooks are kept
+	l.AddHook(&panicHook{})
+	xtesting.PanicWithValue(t, "hook", func() { adapter.Panic("test") })
+}
+
+type panicHook struct{}
+
+func (p *panicHook) Levels() []logrus.Level   { return []logrus.Level{logrus.PanicLevel} }
+func (p *panicHook) Fire(*logrus.Entry) error { panic("hook") }
+
+func TestParseStdLogLevel(t *testing.T) {
+	for _, tc := range []struct {
+		give      string
+		wantLevel logrus.Level
+		wantMsg   string
+	}{
+		{"", logrus.InfoLevel, ""},
+		{"test", logrus.InfoLevel, "test"},
+		{"[", logrus.InfoLevel, "["},
+		{"[WARN test", logrus.InfoLevel, "[WARN test"},
+		{"[UNKNOWN] test", logrus.InfoLevel, "[UNKNOWN] test"},
+		{"test [WARN]", logrus.InfoLevel, "test [WARN]"},
+		{"[TRACE] test", logrus.TraceLevel, "test"},
+		{"[DEBUG] test", logrus.DebugLevel, "test"},
+		{"[INFO] test", logrus.InfoLevel, "test"},
+		{"[WARN] test", logrus.WarnLevel, "test"},
+		{"[warning]test", logrus.WarnLevel, "test"},
+		{"  [Error]  test", logrus.ErrorLevel, "test"},
+		{"[FATAL] test", logrus.FatalLevel, "test"},
+		{"[PANIC] test", logrus.PanicLevel, "test"},
+	} {
+		level, msg := ParseStdLogLevel(tc.give, logrus.InfoLevel)
+		xtesting.Equal(t, level, tc.wantLevel)
+		xtesting.Equal(t, msg, tc.wantMsg)
+	}
+}
+
+func TestStdLogWriter(t *testing.T) {
+	l := logrus.New()
+	l.SetLevel(logrus.TraceLevel)
+	l.SetFormatter(&logrus.TextFormatter{DisableTimestamp: true, DisableQuote: true})
+	sb := &strings.Builder{}
+	l.SetOutput(sb)
+
+	xtesting.Panic(t, func() { NewStdLogWriter(nil, logrus.InfoLevel) })
+	xtesting.Panic(t, func() { NewStdLogWriter(logrus.NewEntry(l), 20) })
+	xtesting.Panic(t, func() { RedirectLogger(nil, logrus.NewEntry(l), logrus.InfoLevel) })
+
+	w := NewStdLogWriter(logrus.NewEntry(l), logrus.InfoLevel)
+	n, err := w.Write([]byte("[WARN] a\n[ERR"))
+	xtesting.Equal(t, n, 13)
+	xtesting.Nil(t, err)
+	xtesting.Equal(t, sb.String(), "level=warning msg=a\n")
+	sb.Reset()
+	_, _ = w.Write([]byte("OR] b\r\nc\n[PANIC] d\n"))
+	xtesting.Equal(t, sb.String(), "level=error msg=b\nlevel=info msg=c\nlevel=error msg=d\n") // panic is not mapped to fatal
+	sb.Reset()
+	w = NewStdLogWriter(logrus.NewEntry(l), logrus.PanicLevel)
+	_, _ = w.Write([]byte("e\n"))
+	xtesting.Equal(t, sb.String(), "level=error msg=e\n")
+
+	// log.Logger
+	sb.Reset()
+	logger := log.New(os.Stderr, "prefix: ", log.LstdFlags)
+	restore := RedirectLogger(logger, l.WithField("k", "v"), logrus.DebugLevel)
+	logger.Print("test")
+	logger.Printf("[WARN] %s", "test")
+	xtesting.Equal(t, sb.String(), "level=debug msg=test k=v\nlevel=warning msg=test k=v\n")
+	restore()
+	xtesting.Equal(t, logger.Writer(), os.Stderr)
+	xtesting.Equal(t, logger.Prefix(), "prefix: ")
+	xtesting.Equal(t, logger.Flags(), log.LstdFlags)
+
+	// standard logger
+	sb.Reset()
+	oldWriter, oldPrefix, oldFlags := log.Writer(), log.Prefix(), log.Flags()
+	restore = RedirectStdLog(logrus.NewEntry(l), logrus.InfoLevel)
+	log.Println("[debug] test")
+	log.Print("test")
+	xtesting.Equal(t, sb.String(), "level=debug msg=test\nlevel=info msg=test\n")
+	restore()
+	xtesting.Equal(t, log.Writer(), oldWriter)
+	xtesting.Equal(t, log.Prefix(), oldPrefix)
+	xtesting.Equal(t, log.Flags(), oldFlags)
+}
+
+func TestLeveledLoggerAdapter(t *testing.T) {
+	xtesting.Panic(t, func() { NewLeveledLoggerAdapter(nil) })
+
+	l := logrus.New()
+	l.SetLevel(logrus.DebugLevel)
+	l.SetFormatter(&logrus.TextFormatter{DisableTimestamp: true, DisableQuote: true})
+	sb := &strings.Builder{}
+	l.SetOutput(sb)
+
+	var adapter xlogger.LeveledLogger = NewLeveledLoggerAdapter(l)
+	for _, tc := range []struct {
+		giveFn func()
+		want   string
+	}{
+		{func() { adapter.Debugf("test %d", 1) }, "level=debug msg=test 1\n"},
+		{func() { adapter.Infof("test %d", 2) }, "level=info msg=test 2\n"},
+		{func() { adapter.Warnf("test %d", 3) }, "level=warning msg=test 3\n"},
+		{func() { adapter.Errorf("test %d", 4) }, "level=error msg=test 4\n"},
+		{func() { adapter.WithField("k", "v").Infof("test") }, "level=info msg=test k=v\n"},
+		{func() { adapter.WithFields(map[string]interface{}{"b": 2, "a": 1}).WithField("c", true).Infof("test") }, "level=info msg=test a=1 b=2 c=true\n"},
+	} {
+		sb.Reset()
+		tc.giveFn()
+		xtesting.Equal(t, sb.String(), tc.want)
+	}
+
+	adapter = NewLeveledLoggerAdapter(l.WithField("k", "v"))
+	sb.Reset()
+	adapter.Infof("test")
+	xtesting.Equal(t, sb.String(), "level=info msg=test k=v\n")
+}
@@ -0,0 +1,90 @@
+package xpaseto
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// RegisteredClaims represents the registered claims described in PASETO specification, which can be embedded in custom claims. Note
+// that time based claims are encoded in RFC 3339 format rather than numeric date used in JWT.
+type RegisteredClaims struct {
+	Issuer    string     `json:"iss,omitempty"`
+	Subject   string     `json:"sub,omitempty"`
+	Audience  string     `json:"aud,omitempty"`
+	ExpiresAt *time.Time `json:"exp,omitempty"`
+	NotBefore *time.Time `json:"nbf,omitempty"`
+	IssuedAt  *time.Time `json:"iat,omitempty"`
+	ID        string     `json:"jti,omitempty"`
+}
+
+// NewRegisteredClaims creates a RegisteredClaims with given subject and lifetime, "iat" and "nbf" are set to now, and "exp" is set to
+// now + lifetime, zero lifetime means never expire.
+func NewRegisteredClaims(subject string, lifetime time.Duration) *RegisteredClaims {
+	now := time.Now().UTC().Truncate(time.Second)
+	claims := &RegisteredClaims{Subject: subject, IssuedAt: &now, NotBefore: &now}
+	if lifetime != 0 {
+		exp := now.Add(lifetime)
+		claims.ExpiresAt = &exp
+	}
+	return claims
+}
+
+// validateClaims decodes the registered claims from given JSON payload, and validates them using parseOptions. The failures are reported
+// as ValidationError with corresponding flags, and the inner error is the first failure.
+func validateClaims(payload []byte, opt *parseOptions) error {
+	claims := &RegisteredClaims{}
+	if err := json.Unmarshal(payload, claims); err != nil {
+		return newValidationError(err, ValidationErrorClaimsInvalid)
+	}
+
+	now := time.Now()
+	if opt.timeFunc != nil {
+		now = opt.timeFunc()
+	}
+	leeway := opt.leeway
+	vErr := &ValidationError{}
+	addError := func(flag uint32, format string, a ...interface{}) {
+		if vErr.Inner == nil {
+			vErr.Inner = fmt.Errorf(format, a...)
+		}
+		vErr.Errors |= flag
+	}
+
+	// time based claims
+	if claims.ExpiresAt != nil && !now.Before(claims.ExpiresAt.Add(leeway)) {
+		addError(ValidationErrorExpired, "token is expired by %v", now.Sub(*claims.ExpiresAt))
+	}
+	if claims.NotBefore != nil && now.Add(leeway).Before(*claims.NotBefore) {
+		addError(ValidationErrorNotValidYet, "token is not valid yet")
+	}
+	if claims.IssuedAt != nil && now.Add(leeway).Before(*claims.IssuedAt) {
+		addError(ValidationErrorIssuedAt, "token used before issued")
+	}
+
+	// string claims
+	if opt.issuer != "" && claims.Issuer != opt.issuer {
+		addError(ValidationErrorIssuer, "token has invalid issuer %q", claims.Issuer)
+	}
+	if len(opt.audiences) > 0 && !containsString(opt.audiences, claims.Audience) {
+		addError(ValidationErrorAudience, "token has invalid audience %q", claims.Audience)
+	}
+	if opt.subject != "" && claims.Subject != opt.subject {
+		addError(ValidationErrorSubject, "token has invalid subject %q", claims.Subject)
+	}
+
+	if vErr.Errors == 0 {
+		return nil
+	}
+	return vErr
+}
+
+// containsString checks whether given string slice contains given string.
+func containsString(slice []string, s string) bool {
+	for _, item := range slice {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,74 @@
+package xpaseto
+
+import (
+	"github.com/Aoi-hosizora/ahlib/xtesting"
+	"testing"
+	"time"
+)
+
+func TestClaimsValidation(t *testing.T) {
+	key, _ := GenerateLocalKey()
+	now := time.Unix(1600000000, 0).UTC()
+	timeFunc := WithTimeFunc(func() time.Time { return now })
+	at := func(d time.Duration) *time.Time { t := now.Add(d); return &t }
+	claims := &RegisteredClaims{Issuer: "iss", Subject: "sub", Audience: "a1", IssuedAt: at(-time.Minute), ExpiresAt: at(time.Hour)}
+	token, err := GenerateLocalToken(claims, key)
+	xtesting.Nil(t, err)
+
+	// success
+	for _, opts := range [][]ParseOption{
+		{timeFunc},
+		{timeFunc, WithIssuer("iss"), WithAudience("a0", "a1"), WithSubject("sub")},
+		{timeFunc, WithIssuer(""), WithAudience(), WithSubject(""), WithLeeway(0)},
+		{WithTimeFunc(func() time.Time { return now.Add(time.Hour + time.Minute - time.Second) }), WithLeeway(time.Minute)},
+		{WithTimeFunc(func() time.Time { return now.Add(-2 * time.Minute) }), WithLeeway(time.Minute)},
+	} {
+		parsed := &RegisteredClaims{}
+		xtesting.Nil(t, ParseLocalToken(token, key, parsed, opts...))
+		xtesting.Equal(t, parsed.Subject, "sub")
+		xtesting.True(t, parsed.IssuedAt.Equal(now.Add(-time.Minute)))
+	}
+
+	// failure
+	for _, tc := range []struct {
+		giveClaims interface{}
+		giveOpts   []ParseOption
+		wantFn     func(error) bool
+	}{
+		{claims, []ParseOption{WithTimeFunc(func() time.Time { return now.Add(2 * time.Hour) })}, IsExpiredError},
+		{claims, []ParseOption{WithTimeFunc(func() time.Time { return now.Add(time.Hour + 2*time.Minute) }), WithLeeway(time.Minute)}, IsExpiredError},
+		{claims, []ParseOption{WithTimeFunc(func() time.Time { return now.Add(time.Hour) })}, IsExpiredError},                                        // exactly at exp
+		{claims, []ParseOption{WithTimeFunc(func() time.Time { return now.Add(time.Hour + time.Minute) }), WithLeeway(time.Minute)}, IsExpiredError}, // exactly at exp + leeway
+		{claims, []ParseOption{WithTimeFunc(func() time.Time { return now.Add(-2 * time.Minute) })}, IsIssuedAtError},
+		{&RegisteredClaims{NotBefore: at(time.Minute)}, []ParseOption{timeFunc}, IsNotValidYetError},
+		{&RegisteredClaims{NotBefore: at(2 * time.Minute)}, []ParseOption{timeFunc, WithLeeway(time.Minute)}, IsNotValidYetError},
+		{claims, []ParseOption{timeFunc, WithIssuer("other")}, IsIssuerError},
+		{&RegisteredClaims{}, []ParseOption{timeFunc, WithIssuer("iss")}, IsIssuerError},
+		{claims, []ParseOption{timeFunc, WithAudience("a2")}, IsAudienceError},
+		{claims, []ParseOption{timeFunc, WithSubject("other")}, IsSubjectError},
+		{map[string]interface{}{"exp": 1600000000}, []ParseOption{timeFunc}, IsClaimsInvalidError},
+		{map[string]interface{}{"iss": "iss", "exp": "2020/01/01"}, []ParseOption{timeFunc}, IsClaimsInvalidError},
+	} {
+		token, err := GenerateLocalToken(tc.giveClaims, key)
+		xtesting.Nil(t, err)
+		err = ParseLocalToken(token, key, nil, tc.giveOpts...)
+		xtesting.NotNil(t, err)
+		xtesting.True(t, tc.wantFn(err))
+		xtesting.False(t, IsTokenInvalidError(err))
+	}
+
+	// multiple failures
+	err = ParseLocalToken(token, key, nil, WithTimeFunc(func() time.Time { return now.Add(2 * time.Hour) }), WithIssuer("x"), WithAudience("x"), WithSubject("x"))
+	xtesting.True(t, IsExpiredError(err))
+	xtesting.True(t, IsIssuerError(err))
+	xtesting.True(t, IsAudienceError(err))
+	xtesting.True(t, IsSubjectError(err))
+	xtesting.Equal(t, err.Error(), "token is expired by 1h0m0s")
+
+	// registered claims
+	c := NewRegisteredClaims("sub", time.Hour)
+	xtesting.Equal(t, c.Subject, "sub")
+	xtesting.Equal(t, c.IssuedAt, c.NotBefore)
+	xtesting.Equal(t, c.ExpiresAt.Sub(*c.IssuedAt), time.Hour)
+	xtesting.Nil(t, NewRegisteredClaims("sub", 0).ExpiresAt)
+}
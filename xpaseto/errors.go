@@ -0,0 +1,99 @@
+package xpaseto
+
+import (
+	"errors"
+)
+
+// The errors that can be used in ValidationError.Errors, which is a bit-field of the flags, see CheckValidationError.
+const (
+	ValidationErrorMalformed        uint32 = 1 << iota // Token is malformed
+	ValidationErrorUnverifiable                        // Token could not be verified because of version, purpose, footer or key
+	ValidationErrorSignatureInvalid                    // Signature or authentication tag validation failed
+	ValidationErrorAudience                            // AUD validation failed
+	ValidationErrorExpired                             // EXP validation failed
+	ValidationErrorIssuedAt                            // IAT validation failed
+	ValidationErrorIssuer                              // ISS validation failed
+	ValidationErrorNotValidYet                         // NBF validation failed
+	ValidationErrorSubject                             // SUB validation failed
+	ValidationErrorClaimsInvalid                       // Generic claims validation error
+)
+
+// ValidationError represents an error of token validation, its Errors field is the bit-field of ValidationErrorXXX flags, and Inner
+// field is the first failure's cause.
+type ValidationError struct {
+	Inner  error
+	Errors uint32
+}
+
+// Error returns the message of the inner error.
+func (v *ValidationError) Error() string {
+	if v.Inner != nil {
+		return v.Inner.Error()
+	}
+	return "token is invalid"
+}
+
+// Unwrap returns the inner error.
+func (v *ValidationError) Unwrap() error {
+	return v.Inner
+}
+
+// newValidationError creates a ValidationError with given inner error and flag.
+func newValidationError(inner error, flag uint32) *ValidationError {
+	return &ValidationError{Inner: inner, Errors: flag}
+}
+
+var (
+	errInvalidKey           = errors.New("xpaseto: invalid key")
+	errInvalidHeader        = errors.New("xpaseto: token has invalid version or purpose")
+	errMalformedToken       = errors.New("xpaseto: token is malformed")
+	errFooterMismatch       = errors.New("xpaseto: token has unexpected footer")
+	errAuthenticationFailed = errors.New("xpaseto: token authentication failed")
+	errSignatureInvalid     = errors.New("xpaseto: token signature is invalid")
+)
+
+// CheckValidationError returns true if given error is ValidationError with given flag.
+func CheckValidationError(err error, flag uint32) bool {
+	var ve *ValidationError
+	return errors.As(err, &ve) && ve.Errors&flag != 0
+}
+
+// IsAudienceError checks error is an AUD (Audience) validation error.
+func IsAudienceError(err error) bool {
+	return CheckValidationError(err, ValidationErrorAudience)
+}
+
+// IsExpiredError checks error is an EXP (Expires at) validation error.
+func IsExpiredError(err error) bool {
+	return CheckValidationError(err, ValidationErrorExpired)
+}
+
+// IsIssuedAtError checks error is an IAT (Issued at) validation error.
+func IsIssuedAtError(err error) bool {
+	return CheckValidationError(err, ValidationErrorIssuedAt)
+}
+
+// IsIssuerError checks error is an ISS (Issuer) validation error.
+func IsIssuerError(err error) bool {
+	return CheckValidationError(err, ValidationErrorIssuer)
+}
+
+// IsNotValidYetError checks error is a NBF (Not before) validation error.
+func IsNotValidYetError(err error) bool {
+	return CheckValidationError(err, ValidationErrorNotValidYet)
+}
+
+// IsSubjectError checks error is a SUB (Subject) validation error.
+func IsSubjectError(err error) bool {
+	return CheckValidationError(err, ValidationErrorSubject)
+}
+
+// IsTokenInvalidError checks error is an invalid token (could not be decrypted or verified) error.
+func IsTokenInvalidError(err error) bool {
+	return CheckValidationError(err, ValidationErrorMalformed|ValidationErrorUnverifiable|ValidationErrorSignatureInvalid)
+}
+
+// IsClaimsInvalidError checks error is a generic claims validation error.
+func IsClaimsInvalidError(err error) bool {
+	return CheckValidationError(err, ValidationErrorClaimsInvalid)
+}
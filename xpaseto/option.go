@@ -0,0 +1,124 @@
+package xpaseto
+
+import (
+	"time"
+)
+
+// GenerateOption represents an option for GenerateToken series functions, can be created by WithXXX functions.
+type GenerateOption func(*generateOptions)
+
+// generateOptions is a type of GenerateToken series functions' options.
+type generateOptions struct {
+	footer            []byte
+	implicitAssertion []byte
+}
+
+// WithFooter creates a GenerateOption to specify the footer of token, which is authenticated but not encrypted, such as a JSON object
+// containing "kid". Note that never put any sensitive data in the footer.
+func WithFooter(footer []byte) GenerateOption {
+	return func(o *generateOptions) {
+		o.footer = footer
+	}
+}
+
+// WithImplicitAssertion creates a GenerateOption to specify the implicit assertion, which is authenticated but not stored in the token,
+// so the same assertion must be specified when parsing, see WithExpectedImplicitAssertion.
+func WithImplicitAssertion(assertion []byte) GenerateOption {
+	return func(o *generateOptions) {
+		o.implicitAssertion = assertion
+	}
+}
+
+// newGenerateOptions applies given GenerateOption-s and returns generateOptions.
+func newGenerateOptions(options []GenerateOption) *generateOptions {
+	opt := &generateOptions{}
+	for _, o := range options {
+		if o != nil {
+			o(opt)
+		}
+	}
+	return opt
+}
+
+// ParseOption represents an option for ParseToken series functions, can be created by WithXXX functions. Note that the time based
+// claims ("exp", "nbf" and "iat") are always validated if they exist.
+type ParseOption func(*parseOptions)
+
+// parseOptions is a type of ParseToken series functions' options.
+type parseOptions struct {
+	footer            []byte
+	checkFooter       bool
+	implicitAssertion []byte
+
+	issuer    string
+	audiences []string
+	subject   string
+	leeway    time.Duration
+	timeFunc  func() time.Time
+}
+
+// WithExpectedFooter creates a ParseOption to require the footer of token to be equal to given footer, otherwise IsTokenInvalidError
+// will be true. Defaults to accept any footer.
+func WithExpectedFooter(footer []byte) ParseOption {
+	return func(o *parseOptions) {
+		o.footer = footer
+		o.checkFooter = true
+	}
+}
+
+// WithExpectedImplicitAssertion creates a ParseOption to specify the implicit assertion used when generating, see WithImplicitAssertion.
+func WithExpectedImplicitAssertion(assertion []byte) ParseOption {
+	return func(o *parseOptions) {
+		o.implicitAssertion = assertion
+	}
+}
+
+// WithIssuer creates a ParseOption to require the "iss" (Issuer) claim to be equal to given issuer, otherwise IsIssuerError will be
+// true. Empty issuer means no requirement.
+func WithIssuer(issuer string) ParseOption {
+	return func(o *parseOptions) {
+		o.issuer = issuer
+	}
+}
+
+// WithAudience creates a ParseOption to require the "aud" (Audience) claim to be one of given audiences, otherwise IsAudienceError will
+// be true. Empty audiences means no requirement.
+func WithAudience(audiences ...string) ParseOption {
+	return func(o *parseOptions) {
+		o.audiences = audiences
+	}
+}
+
+// WithSubject creates a ParseOption to require the "sub" (Subject) claim to be equal to given subject, otherwise IsSubjectError will be
+// true. Empty subject means no requirement.
+func WithSubject(subject string) ParseOption {
+	return func(o *parseOptions) {
+		o.subject = subject
+	}
+}
+
+// WithLeeway creates a ParseOption to specify the leeway when validating time based claims, to account for clock skew. Defaults to 0.
+func WithLeeway(leeway time.Duration) ParseOption {
+	return func(o *parseOptions) {
+		o.leeway = leeway
+	}
+}
+
+// WithTimeFunc creates a ParseOption to specify the function to get current time when validating time based claims, defaults to
+// time.Now. This is useful for testing.
+func WithTimeFunc(timeFunc func() time.Time) ParseOption {
+	return func(o *parseOptions) {
+		o.timeFunc = timeFunc
+	}
+}
+
+// newParseOptions applies given ParseOption-s and returns parseOptions.
+func newParseOptions(options []ParseOption) *parseOptions {
+	opt := &parseOptions{}
+	for _, o := range options {
+		if o != nil {
+			o(opt)
+		}
+	}
+	return opt
+}
@@ -0,0 +1,277 @@
+// Package xpaseto implements PASETO v4.local and v4.public tokens with registered claims validation. Note that token ID ("jti")
+// generation and revocation checking are out of scope of this package, please set RegisteredClaims.ID and check it by yourself after
+// parsing, or use xjwt instead.
+package xpaseto
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"golang.org/x/crypto/blake2b"
+	"golang.org/x/crypto/chacha20"
+	"strings"
+)
+
+const (
+	// LocalHeader represents the header of v4.local tokens, which are encrypted by XChaCha20 and authenticated by BLAKE2b-MAC.
+	LocalHeader = "v4.local."
+
+	// PublicHeader represents the header of v4.public tokens, which are signed by Ed25519.
+	PublicHeader = "v4.public."
+
+	// LocalKeySize represents the key size of v4.local tokens.
+	LocalKeySize = 32
+)
+
+const (
+	localNonceSize = 32
+	localMACSize   = 32
+)
+
+// GenerateLocalKey generates a random key for v4.local tokens.
+func GenerateLocalKey() ([]byte, error) {
+	key := make([]byte, LocalKeySize)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// Encrypt encrypts given payload using given 32 bytes key, and returns a v4.local token. Supported GenerateOption-s are WithFooter and
+// WithImplicitAssertion.
+func Encrypt(payload, key []byte, options ...GenerateOption) (string, error) {
+	nonce := make([]byte, localNonceSize)
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	return encrypt(payload, key, nonce, newGenerateOptions(options))
+}
+
+// encrypt is the implementation of Encrypt with given nonce.
+func encrypt(payload, key, nonce []byte, opt *generateOptions) (string, error) {
+	if len(key) != LocalKeySize {
+		return "", errInvalidKey
+	}
+	encKey, counterNonce, authKey := splitLocalKey(key, nonce)
+	cipher, err := chacha20.NewUnauthenticatedCipher(encKey, counterNonce)
+	if err != nil {
+		return "", err
+	}
+	ciphertext := make([]byte, len(payload))
+	cipher.XORKeyStream(ciphertext, payload)
+	mac := localMAC(authKey, nonce, ciphertext, opt.footer, opt.implicitAssertion)
+
+	body := make([]byte, 0, len(nonce)+len(ciphertext)+len(mac))
+	body = append(append(append(body, nonce...), ciphertext...), mac...)
+	return encodeToken(LocalHeader, body, opt.footer), nil
+}
+
+// Decrypt decrypts given v4.local token using given 32 bytes key, and returns the payload and footer. Supported ParseOption-s are
+// WithExpectedFooter and WithExpectedImplicitAssertion. Returned errors are ValidationError, and IsTokenInvalidError will be true for them.
+func Decrypt(token string, key []byte, options ...ParseOption) (payload, footer []byte, err error) {
+	opt := newParseOptions(options)
+	if len(key) != LocalKeySize {
+		return nil, nil, newValidationError(errInvalidKey, ValidationErrorUnverifiable)
+	}
+	body, footer, err := decodeToken(token, LocalHeader, opt)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(body) < localNonceSize+localMACSize {
+		return nil, nil, newValidationError(errMalformedToken, ValidationErrorMalformed)
+	}
+	nonce, ciphertext, mac := body[:localNonceSize], body[localNonceSize:len(body)-localMACSize], body[len(body)-localMACSize:]
+
+	encKey, counterNonce, authKey := splitLocalKey(key, nonce)
+	if subtle.ConstantTimeCompare(mac, localMAC(authKey, nonce, ciphertext, footer, opt.implicitAssertion)) != 1 {
+		return nil, nil, newValidationError(errAuthenticationFailed, ValidationErrorSignatureInvalid)
+	}
+	cipher, err := chacha20.NewUnauthenticatedCipher(encKey, counterNonce)
+	if err != nil {
+		return nil, nil, newValidationError(err, ValidationErrorUnverifiable)
+	}
+	payload = make([]byte, len(ciphertext))
+	cipher.XORKeyStream(payload, ciphertext)
+	return payload, footer, nil
+}
+
+// splitLocalKey derives the encryption key, the XChaCha20 nonce and the authentication key from given key and nonce.
+func splitLocalKey(key, nonce []byte) (encKey, counterNonce, authKey []byte) {
+	h, _ := blake2b.New(56, key) // key size is checked
+	_, _ = h.Write([]byte("paseto-encryption-key"))
+	_, _ = h.Write(nonce)
+	tmp := h.Sum(nil)
+
+	h, _ = blake2b.New(32, key)
+	_, _ = h.Write([]byte("paseto-auth-key-for-aead"))
+	_, _ = h.Write(nonce)
+	return tmp[:32], tmp[32:], h.Sum(nil)
+}
+
+// localMAC calculates the BLAKE2b-MAC of v4.local token.
+func localMAC(authKey, nonce, ciphertext, footer, implicitAssertion []byte) []byte {
+	h, _ := blake2b.New(localMACSize, authKey)
+	_, _ = h.Write(preAuthEncode([]byte(LocalHeader), nonce, ciphertext, footer, implicitAssertion))
+	return h.Sum(nil)
+}
+
+// Sign signs given payload using given Ed25519 private key, and returns a v4.public token. Note that the payload is not encrypted.
+// Supported GenerateOption-s are WithFooter and WithImplicitAssertion.
+func Sign(payload []byte, key ed25519.PrivateKey, options ...GenerateOption) (string, error) {
+	if len(key) != ed25519.PrivateKeySize {
+		return "", errInvalidKey
+	}
+	opt := newGenerateOptions(options)
+	signature := ed25519.Sign(key, preAuthEncode([]byte(PublicHeader), payload, opt.footer, opt.implicitAssertion))
+
+	body := make([]byte, 0, len(payload)+len(signature))
+	body = append(append(body, payload...), signature...)
+	return encodeToken(PublicHeader, body, opt.footer), nil
+}
+
+// Verify verifies given v4.public token using given Ed25519 public key, and returns the payload and footer. Supported ParseOption-s are
+// WithExpectedFooter and WithExpectedImplicitAssertion. Returned errors are ValidationError, and IsTokenInvalidError will be true for them.
+func Verify(token string, key ed25519.PublicKey, options ...ParseOption) (payload, footer []byte, err error) {
+	opt := newParseOptions(options)
+	if len(key) != ed25519.PublicKeySize {
+		return nil, nil, newValidationError(errInvalidKey, ValidationErrorUnverifiable)
+	}
+	body, footer, err := decodeToken(token, PublicHeader, opt)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(body) < ed25519.SignatureSize {
+		return nil, nil, newValidationError(errMalformedToken, ValidationErrorMalformed)
+	}
+	payload, signature := body[:len(body)-ed25519.SignatureSize], body[len(body)-ed25519.SignatureSize:]
+	if !ed25519.Verify(key, preAuthEncode([]byte(PublicHeader), payload, footer, opt.implicitAssertion), signature) {
+		return nil, nil, newValidationError(errSignatureInvalid, ValidationErrorSignatureInvalid)
+	}
+	return payload, footer, nil
+}
+
+// ExtractFooter extracts the footer from given token without verification, this is useful to select key by the footer, such as "kid".
+// Note that please never trust the returned footer before the token is verified.
+func ExtractFooter(token string) ([]byte, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 && len(parts) != 4 {
+		return nil, newValidationError(errMalformedToken, ValidationErrorMalformed)
+	}
+	if len(parts) == 3 {
+		return nil, nil
+	}
+	footer, err := base64.RawURLEncoding.DecodeString(parts[3])
+	if err != nil {
+		return nil, newValidationError(err, ValidationErrorMalformed)
+	}
+	return footer, nil
+}
+
+// encodeToken encodes the token in the form of "header + base64url(body) [+ "." + base64url(footer)]".
+func encodeToken(header string, body, footer []byte) string {
+	token := header + base64.RawURLEncoding.EncodeToString(body)
+	if len(footer) > 0 {
+		token += "." + base64.RawURLEncoding.EncodeToString(footer)
+	}
+	return token
+}
+
+// decodeToken checks the header and footer of given token, and returns the decoded body and footer.
+func decodeToken(token, header string, opt *parseOptions) (body, footer []byte, err error) {
+	if !strings.HasPrefix(token, header) {
+		return nil, nil, newValidationError(errInvalidHeader, ValidationErrorUnverifiable)
+	}
+	parts := strings.Split(token[len(header):], ".")
+	if len(parts) > 2 {
+		return nil, nil, newValidationError(errMalformedToken, ValidationErrorMalformed)
+	}
+	if len(parts) == 2 {
+		if footer, err = base64.RawURLEncoding.DecodeString(parts[1]); err != nil {
+			return nil, nil, newValidationError(err, ValidationErrorMalformed)
+		}
+	}
+	if opt.checkFooter && subtle.ConstantTimeCompare(footer, opt.footer) != 1 {
+		return nil, nil, newValidationError(errFooterMismatch, ValidationErrorUnverifiable)
+	}
+	if body, err = base64.RawURLEncoding.DecodeString(parts[0]); err != nil {
+		return nil, nil, newValidationError(err, ValidationErrorMalformed)
+	}
+	return body, footer, nil
+}
+
+// preAuthEncode encodes given pieces using PAE (Pre-Authentication Encoding) described in PASETO specification.
+func preAuthEncode(pieces ...[]byte) []byte {
+	size := 8
+	for _, p := range pieces {
+		size += 8 + len(p)
+	}
+	out := make([]byte, 8, size)
+	binary.LittleEndian.PutUint64(out, uint64(len(pieces))&^(1<<63))
+	for _, p := range pieces {
+		var length [8]byte
+		binary.LittleEndian.PutUint64(length[:], uint64(len(p))&^(1<<63))
+		out = append(append(out, length[:]...), p...)
+	}
+	return out
+}
+
+// GenerateLocalToken marshals given claims to JSON and encrypts it using given 32 bytes key, and returns a v4.local token. See Encrypt
+// for supported GenerateOption-s.
+func GenerateLocalToken(claims interface{}, key []byte, options ...GenerateOption) (string, error) {
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	return Encrypt(payload, key, options...)
+}
+
+// ParseLocalToken decrypts given v4.local token using given 32 bytes key, unmarshals the payload to given claims, and validates the
+// registered claims using ParseOption-s. Returned errors are ValidationError, which can be checked by IsXXXError functions.
+// Example:
+// 	type Claims struct {
+// 		xpaseto.RegisteredClaims
+// 		UID uint64 `json:"uid"`
+// 	}
+// 	token, _ := xpaseto.GenerateLocalToken(&Claims{RegisteredClaims: *xpaseto.NewRegisteredClaims("1", time.Hour), UID: 1}, key)
+// 	claims := &Claims{}
+// 	err := xpaseto.ParseLocalToken(token, key, claims, xpaseto.WithSubject("1"))
+func ParseLocalToken(token string, key []byte, claims interface{}, options ...ParseOption) error {
+	payload, _, err := Decrypt(token, key, options...)
+	if err != nil {
+		return err
+	}
+	return unmarshalClaims(payload, claims, newParseOptions(options))
+}
+
+// GeneratePublicToken marshals given claims to JSON and signs it using given Ed25519 private key, and returns a v4.public token. See
+// Sign for supported GenerateOption-s.
+func GeneratePublicToken(claims interface{}, key ed25519.PrivateKey, options ...GenerateOption) (string, error) {
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	return Sign(payload, key, options...)
+}
+
+// ParsePublicToken verifies given v4.public token using given Ed25519 public key, unmarshals the payload to given claims, and validates
+// the registered claims using ParseOption-s. Returned errors are ValidationError, which can be checked by IsXXXError functions.
+func ParsePublicToken(token string, key ed25519.PublicKey, claims interface{}, options ...ParseOption) error {
+	payload, _, err := Verify(token, key, options...)
+	if err != nil {
+		return err
+	}
+	return unmarshalClaims(payload, claims, newParseOptions(options))
+}
+
+// unmarshalClaims unmarshals given payload to claims, and validates the registered claims.
+func unmarshalClaims(payload []byte, claims interface{}, opt *parseOptions) error {
+	if claims != nil {
+		if err := json.Unmarshal(payload, claims); err != nil {
+			return newValidationError(err, ValidationErrorMalformed)
+		}
+	}
+	return validateClaims(payload, opt)
+}
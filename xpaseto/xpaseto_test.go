@@ -0,0 +1,206 @@
+package xpaseto
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"github.com/Aoi-hosizora/ahlib/xtesting"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestVectors(t *testing.T) {
+	// https://github.com/paseto-standard/test-vectors/blob/master/v4.json
+	t.Run("v4.local", func(t *testing.T) {
+		key, _ := hex.DecodeString("707172737475767778797a7b7c7d7e7f808182838485868788898a8b8c8d8e8f")
+		payload := []byte(`{"data":"this is a secret message","exp":"2022-01-01T00:00:00+00:00"}`)
+		token, err := encrypt(payload, key, make([]byte, localNonceSize), &generateOptions{})
+		xtesting.Nil(t, err)
+		xtesting.Equal(t, token, "v4.local.AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAQAr68PS4AXe7If_ZgesdkUMvSwscFlAl1pk5HC0e8kApeaqMfGo_7OpBnwJOAbY9V7WU6abu74MmcUE8YWAiaArVI8XJ5hOb_4v9RmDkneN0S92dx0OW4pgy7omxgf3S8c3LlQg")
+		decrypted, footer, err := Decrypt(token, key)
+		xtesting.Nil(t, err)
+		xtesting.Equal(t, decrypted, payload)
+		xtesting.Nil(t, footer)
+	})
+
+	t.Run("v4.public", func(t *testing.T) {
+		sk, _ := hex.DecodeString("b4cbfb43df4ce210727d953e4a713307fa19bb7d9f85041438d9e11b942a37741eb9dbbbbc047c03fd70604e0071f0987e16b28b757225c11f00415d0e20b1a2")
+		pk, _ := hex.DecodeString("1eb9dbbbbc047c03fd70604e0071f0987e16b28b757225c11f00415d0e20b1a2")
+		payload := []byte(`{"data":"this is a signed message","exp":"2022-01-01T00:00:00+00:00"}`)
+		token, err := Sign(payload, sk)
+		xtesting.Nil(t, err)
+		xtesting.Equal(t, token, "v4.public.eyJkYXRhIjoidGhpcyBpcyBhIHNpZ25lZCBtZXNzYWdlIiwiZXhwIjoiMjAyMi0wMS0wMVQwMDowMDowMCswMDowMCJ9bg_XBBzds8lTZShVlwwKSgeKpLT3yukTw6JUz3W4h_ExsQV-P0V54zemZDcAxFaSeef1QlXEFtkqxT1ciiQEDA")
+		verified, footer, err := Verify(token, pk)
+		xtesting.Nil(t, err)
+		xtesting.Equal(t, verified, payload)
+		xtesting.Nil(t, footer)
+	})
+
+	// PAE
+	xtesting.Equal(t, hex.EncodeToString(preAuthEncode()), "0000000000000000")
+	xtesting.Equal(t, hex.EncodeToString(preAuthEncode([]byte{})), "01000000000000000000000000000000")
+	xtesting.Equal(t, hex.EncodeToString(preAuthEncode([]byte("test"))), "0100000000000000040000000000000074657374")
+}
+
+func TestLocal(t *testing.T) {
+	key, err := GenerateLocalKey()
+	xtesting.Nil(t, err)
+	xtesting.Equal(t, len(key), LocalKeySize)
+	otherKey, _ := GenerateLocalKey()
+	payload := []byte("hello world")
+
+	// footer and implicit assertion
+	token, err := Encrypt(payload, key, WithFooter([]byte(`{"kid":"k1"}`)), WithImplicitAssertion([]byte("user-1")), nil)
+	xtesting.Nil(t, err)
+	xtesting.True(t, strings.HasPrefix(token, LocalHeader))
+	xtesting.Equal(t, strings.Count(token, "."), 3)
+	footer, err := ExtractFooter(token)
+	xtesting.Nil(t, err)
+	xtesting.Equal(t, footer, []byte(`{"kid":"k1"}`))
+	decrypted, footer, err := Decrypt(token, key, WithExpectedImplicitAssertion([]byte("user-1")), WithExpectedFooter([]byte(`{"kid":"k1"}`)))
+	xtesting.Nil(t, err)
+	xtesting.Equal(t, decrypted, payload)
+	xtesting.Equal(t, footer, []byte(`{"kid":"k1"}`))
+	token2, _ := Encrypt(payload, key)
+	xtesting.NotEqual(t, token, token2)
+
+	// failures
+	for _, tc := range []struct {
+		giveToken string
+		giveKey   []byte
+		giveOpts  []ParseOption
+		wantFlag  uint32
+	}{
+		{token, key[:16], nil, ValidationErrorUnverifiable},
+		{token, otherKey, []ParseOption{WithExpectedImplicitAssertion([]byte("user-1"))}, ValidationErrorSignatureInvalid},
+		{token, key, nil, ValidationErrorSignatureInvalid},
+		{token, key, []ParseOption{WithExpectedImplicitAssertion([]byte("user-2"))}, ValidationErrorSignatureInvalid},
+		{token, key, []ParseOption{WithExpectedFooter(nil)}, ValidationErrorUnverifiable},
+		{strings.Replace(token, "v4.local.", "v3.local.", 1), key, nil, ValidationErrorUnverifiable},
+		{strings.Replace(token, "v4.local.", "v4.public.", 1), key, nil, ValidationErrorUnverifiable},
+		{token + ".x", key, nil, ValidationErrorMalformed},
+		{token[:len(token)-1] + "!", key, nil, ValidationErrorMalformed},
+		{"v4.local.!", key, nil, ValidationErrorMalformed},
+		{"v4.local.AAAA", key, nil, ValidationErrorMalformed},
+	} {
+		_, _, err := Decrypt(tc.giveToken, tc.giveKey, tc.giveOpts...)
+		xtesting.NotNil(t, err)
+		xtesting.True(t, CheckValidationError(err, tc.wantFlag))
+		xtesting.True(t, IsTokenInvalidError(err))
+	}
+
+	// tampered ciphertext
+	tampered := []byte(token2)
+	tampered[len(LocalHeader)+50] ^= 1
+	_, _, err = Decrypt(string(tampered), key)
+	xtesting.True(t, CheckValidationError(err, ValidationErrorSignatureInvalid) || CheckValidationError(err, ValidationErrorMalformed))
+
+	_, err = Encrypt(payload, key[:31])
+	xtesting.Equal(t, err, errInvalidKey)
+}
+
+func TestPublic(t *testing.T) {
+	pk, sk, _ := ed25519.GenerateKey(rand.Reader)
+	otherPK, _, _ := ed25519.GenerateKey(rand.Reader)
+	payload := []byte("hello world")
+
+	token, err := Sign(payload, sk, WithFooter([]byte("footer")), WithImplicitAssertion([]byte("ia")))
+	xtesting.Nil(t, err)
+	xtesting.True(t, strings.HasPrefix(token, PublicHeader))
+	verified, footer, err := Verify(token, pk, WithExpectedImplicitAssertion([]byte("ia")), WithExpectedFooter([]byte("footer")))
+	xtesting.Nil(t, err)
+	xtesting.Equal(t, verified, payload)
+	xtesting.Equal(t, footer, []byte("footer"))
+
+	for _, tc := range []struct {
+		giveToken string
+		giveKey   ed25519.PublicKey
+		giveOpts  []ParseOption
+		wantFlag  uint32
+	}{
+		{token, pk[:16], nil, ValidationErrorUnverifiable},
+		{token, otherPK, []ParseOption{WithExpectedImplicitAssertion([]byte("ia"))}, ValidationErrorSignatureInvalid},
+		{token, pk, nil, ValidationErrorSignatureInvalid},
+		{token, pk, []ParseOption{WithExpectedImplicitAssertion([]byte("ia")), WithExpectedFooter([]byte("other"))}, ValidationErrorUnverifiable},
+		{strings.Replace(token, "v4.public.", "v4.local.", 1), pk, nil, ValidationErrorUnverifiable},
+		{"v4.public.AAAA", pk, nil, ValidationErrorMalformed},
+	} {
+		_, _, err := Verify(tc.giveToken, tc.giveKey, tc.giveOpts...)
+		xtesting.NotNil(t, err)
+		xtesting.True(t, CheckValidationError(err, tc.wantFlag))
+	}
+
+	_, err = Sign(payload, sk[:32])
+	xtesting.Equal(t, err, errInvalidKey)
+
+	// footer
+	for _, tc := range []struct {
+		giveToken  string
+		wantFooter []byte
+		wantErr    bool
+	}{
+		{"v4.public.AAAA", nil, false},
+		{"v4.public.AAAA.Zm9vdGVy", []byte("footer"), false},
+		{"v4.public", nil, true},
+		{"v4.public.AAAA.!", nil, true},
+	} {
+		footer, err := ExtractFooter(tc.giveToken)
+		xtesting.Equal(t, footer, tc.wantFooter)
+		xtesting.Equal(t, err != nil, tc.wantErr)
+	}
+}
+
+func TestTokens(t *testing.T) {
+	type Claims struct {
+		RegisteredClaims
+		UID uint64 `json:"uid"`
+	}
+	localKey, _ := GenerateLocalKey()
+	pk, sk, _ := ed25519.GenerateKey(rand.Reader)
+	claims := &Claims{RegisteredClaims: *NewRegisteredClaims("sub", time.Hour), UID: 1}
+	claims.Issuer, claims.Audience = "iss", "aud"
+
+	local, err := GenerateLocalToken(claims, localKey)
+	xtesting.Nil(t, err)
+	public, err := GeneratePublicToken(claims, sk, WithFooter([]byte("kid")))
+	xtesting.Nil(t, err)
+	_, err = GenerateLocalToken(make(chan int), localKey)
+	xtesting.NotNil(t, err)
+	_, err = GeneratePublicToken(make(chan int), sk)
+	xtesting.NotNil(t, err)
+
+	parsers := []func(*Claims, ...ParseOption) error{
+		func(c *Claims, options ...ParseOption) error { return ParseLocalToken(local, localKey, c, options...) },
+		func(c *Claims, options ...ParseOption) error { return ParsePublicToken(public, pk, c, options...) },
+	}
+	for _, parse := range parsers {
+		parsed := &Claims{}
+		xtesting.Nil(t, parse(parsed, WithIssuer("iss"), WithAudience("x", "aud"), WithSubject("sub")))
+		xtesting.Equal(t, parsed.UID, uint64(1))
+		xtesting.Equal(t, parsed.Subject, "sub")
+		xtesting.True(t, parsed.ExpiresAt.Equal(*claims.ExpiresAt))
+
+		err := parse(&Claims{}, WithIssuer("other"), WithTimeFunc(func() time.Time { return time.Now().Add(2 * time.Hour) }))
+		xtesting.True(t, IsIssuerError(err))
+		xtesting.True(t, IsExpiredError(err))
+		xtesting.False(t, IsTokenInvalidError(err))
+	}
+
+	err = ParseLocalToken(local, pk, &Claims{})
+	xtesting.True(t, IsTokenInvalidError(err))
+	err = ParsePublicToken(local, pk, &Claims{})
+	xtesting.True(t, IsTokenInvalidError(err))
+	notObject, _ := Encrypt([]byte(`"string"`), localKey)
+	err = ParseLocalToken(notObject, localKey, &Claims{})
+	xtesting.True(t, CheckValidationError(err, ValidationErrorMalformed))
+
+	// errors
+	var ve *ValidationError
+	xtesting.True(t, errors.As(err, &ve))
+	xtesting.NotNil(t, errors.Unwrap(err))
+	xtesting.Equal(t, (&ValidationError{}).Error(), "token is invalid")
+	xtesting.False(t, CheckValidationError(nil, ValidationErrorMalformed))
+	xtesting.False(t, CheckValidationError(errors.New("x"), ValidationErrorMalformed))
+}